@@ -0,0 +1,72 @@
+package accesslog
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sjek/internal/env"
+)
+
+// Config mengatur perekaman access log
+type Config struct {
+	Enabled bool
+	// SampleRate is the fraction (0..1) of successful requests that are recorded.
+	// Request dengan status >= 400 selalu dicatat
+	SampleRate float64
+	// Exclude berisi route pattern yang tidak dicatat; akhiran * berarti prefix
+	Exclude       []string
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// ConfigFromEnv reads ACCESS_LOG_ENABLED, ACCESS_LOG_SAMPLE_RATE, ACCESS_LOG_EXCLUDE
+// (comma separated), ACCESS_LOG_BUFFER, ACCESS_LOG_BATCH_SIZE and ACCESS_LOG_FLUSH_INTERVAL
+func ConfigFromEnv() Config {
+	config := Config{
+		Enabled:       os.Getenv("ACCESS_LOG_ENABLED") != "false",
+		SampleRate:    1,
+		Exclude:       []string{"/swagger/*"},
+		BufferSize:    10000,
+		BatchSize:     500,
+		FlushInterval: 2 * time.Second,
+	}
+
+	if value := os.Getenv("ACCESS_LOG_SAMPLE_RATE"); value != "" {
+		if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 && rate <= 1 {
+			config.SampleRate = rate
+		} else {
+			slog.Warn("Invalid access log sample rate, using default", "value", value, "default", config.SampleRate)
+		}
+	}
+	if value, ok := os.LookupEnv("ACCESS_LOG_EXCLUDE"); ok {
+		config.Exclude = nil
+		for _, route := range strings.Split(value, ",") {
+			if route = strings.TrimSpace(route); route != "" {
+				config.Exclude = append(config.Exclude, route)
+			}
+		}
+	}
+	config.BufferSize = env.Int("ACCESS_LOG_BUFFER", config.BufferSize)
+	config.BatchSize = env.Int("ACCESS_LOG_BATCH_SIZE", config.BatchSize)
+	config.FlushInterval = env.Duration("ACCESS_LOG_FLUSH_INTERVAL", config.FlushInterval)
+
+	return config
+}
+
+// Excluded reports whether route matches one of the exclusion patterns
+func (c Config) Excluded(route string) bool {
+	for _, pattern := range c.Exclude {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(route, prefix) {
+				return true
+			}
+		} else if route == pattern {
+			return true
+		}
+	}
+	return false
+}
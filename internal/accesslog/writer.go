@@ -0,0 +1,139 @@
+package accesslog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sjek/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Writer menulis access log ke database secara asynchronous dalam batch.
+// Record tidak pernah memblokir request; jika buffer penuh entry dibuang dan dihitung
+type Writer struct {
+	db      *gorm.DB
+	config  Config
+	entries chan models.AccessLog
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWriter starts the background goroutine that flushes entries to db
+func NewWriter(db *gorm.DB, config Config) *Writer {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 10000
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 2 * time.Second
+	}
+
+	w := &Writer{
+		db:      db,
+		config:  config,
+		entries: make(chan models.AccessLog, config.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Config returns the configuration the writer was created with
+func (w *Writer) Config() Config {
+	return w.config
+}
+
+// Dropped returns the number of entries discarded because the buffer was full
+func (w *Writer) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// ShouldRecord applies route exclusions and sampling. Error responses are always recorded
+func (w *Writer) ShouldRecord(route string, status int) bool {
+	if !w.config.Enabled || w.config.Excluded(route) {
+		return false
+	}
+	if status >= 400 || w.config.SampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < w.config.SampleRate
+}
+
+// Record queues an entry without blocking
+func (w *Writer) Record(entry models.AccessLog) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.entries <- entry:
+	default:
+		if w.dropped.Add(1)%1000 == 1 {
+			slog.Warn("Access log buffer full, dropping entries", "dropped_total", w.dropped.Load())
+		}
+	}
+}
+
+// Close stops accepting entries and waits until the buffered entries are flushed or ctx is done
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AccessLog, 0, w.config.BatchSize)
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= w.config.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *Writer) flush(batch []models.AccessLog) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := w.db.CreateInBatches(batch, w.config.BatchSize).Error; err != nil {
+		slog.Error("Failed to write access logs", "count", len(batch), "error", err)
+	}
+}
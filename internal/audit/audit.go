@@ -0,0 +1,212 @@
+// Package audit records administrative mutations together with the change itself.
+//
+// Record harus dipanggil dengan *gorm.DB transaksi yang sama dengan perubahan data,
+// sehingga entry audit hanya ada jika perubahan benar-benar tersimpan. Setiap entry
+// dirangkai ke hash chain audit_logs (lihat package hashchain).
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"sjek/internal/hashchain"
+	"sjek/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Action string
+
+const (
+	ActionCreate   Action = "CREATE"
+	ActionUpdate   Action = "UPDATE"
+	ActionDelete   Action = "DELETE"
+	ActionRestore  Action = "RESTORE"
+	ActionAssign   Action = "ASSIGN"
+	ActionUnassign Action = "UNASSIGN"
+	ActionDeny     Action = "DENY"
+	ActionUndeny   Action = "UNDENY"
+	ActionImport   Action = "IMPORT"
+	ActionRevoke   Action = "REVOKE"
+)
+
+// Resource types
+const (
+	ResourceUser          = "user"
+	ResourceRole          = "role"
+	ResourceAPI           = "api"
+	ResourceMenu          = "menu"
+	ResourceGroup         = "group"
+	ResourceUserRole      = "user_role"
+	ResourceRoleAPI       = "role_api"
+	ResourceRoleMenu      = "role_menu"
+	ResourceRoleAPIDeny   = "role_api_deny"
+	ResourceRoleMenuDeny  = "role_menu_deny"
+	ResourceGroupMember   = "group_member"
+	ResourceGroupRole     = "group_role"
+	ResourceDriverProfile = "driver_profile"
+	ResourceDocument      = "document"
+	ResourceRBAC          = "rbac"
+	ResourceWebhook       = "webhook"
+	ResourceToken         = "token"
+)
+
+// Actor identifies who made the change and from where
+type Actor struct {
+	UserID    *uuid.UUID
+	Username  string
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
+
+// Entry describes one mutation. Before nil untuk create, After nil untuk delete
+type Entry struct {
+	Action       Action
+	ResourceType string
+	ResourceID   string
+	Before       interface{}
+	After        interface{}
+}
+
+// Change is the before and after value of one field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+const redacted = "[REDACTED]"
+
+// redactedFields never appear in audit snapshots, hanya tercatat bahwa nilainya berubah
+var redactedFields = map[string]bool{
+	"password":    true,
+	"token":       true,
+	"secret":      true,
+	"storage_key": true,
+}
+
+// ignoredChanges are bookkeeping fields left out of the diff
+var ignoredChanges = map[string]bool{
+	"updated_at": true,
+}
+
+// Record writes one audit entry using tx
+func Record(tx *gorm.DB, actor Actor, entry Entry) error {
+	before, err := snapshot(entry.Before)
+	if err != nil {
+		return fmt.Errorf("audit: encode before: %v", err)
+	}
+	after, err := snapshot(entry.After)
+	if err != nil {
+		return fmt.Errorf("audit: encode after: %v", err)
+	}
+
+	log := models.AuditLog{
+		ID:            uuid.New(),
+		ActorID:       actor.UserID,
+		ActorUsername: actor.Username,
+		Action:        string(entry.Action),
+		ResourceType:  entry.ResourceType,
+		ResourceID:    entry.ResourceID,
+		IPAddress:     actor.IPAddress,
+		UserAgent:     actor.UserAgent,
+		RequestID:     actor.RequestID,
+	}
+
+	// Diff dihitung sebelum redaksi supaya perubahan password tetap tercatat
+	changes := Diff(before, after)
+	for key, change := range changes {
+		if redactedFields[key] {
+			changes[key] = Change{From: redactValue(change.From), To: redactValue(change.To)}
+		}
+	}
+	redact(before)
+	redact(after)
+
+	if log.Before, err = encode(before); err != nil {
+		return fmt.Errorf("audit: encode before: %v", err)
+	}
+	if log.After, err = encode(after); err != nil {
+		return fmt.Errorf("audit: encode after: %v", err)
+	}
+	if len(changes) > 0 {
+		if log.Changes, err = encode(changes); err != nil {
+			return fmt.Errorf("audit: encode changes: %v", err)
+		}
+	}
+
+	return hashchain.AuditLogs.Append(tx, &log)
+}
+
+// snapshot converts a value into its JSON object form
+func snapshot(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func redact(fields map[string]interface{}) {
+	for key, value := range fields {
+		if redactedFields[key] {
+			fields[key] = redactValue(value)
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			redact(nested)
+		}
+	}
+}
+
+// redactValue hides a sensitive value but keeps empty values visible
+func redactValue(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return redacted
+}
+
+// Diff returns the fields that differ between two snapshots
+func Diff(before, after map[string]interface{}) map[string]Change {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	changes := make(map[string]Change)
+	for key, to := range after {
+		if ignoredChanges[key] {
+			continue
+		}
+		if from := before[key]; !reflect.DeepEqual(from, to) {
+			changes[key] = Change{From: from, To: to}
+		}
+	}
+	for key, from := range before {
+		if _, ok := after[key]; !ok && !ignoredChanges[key] {
+			changes[key] = Change{From: from, To: nil}
+		}
+	}
+	return changes
+}
+
+func encode(value interface{}) (models.JSON, error) {
+	if value == nil || reflect.ValueOf(value).IsNil() {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// accessLogMigrations membuat tabel access_logs yang dipartisi per hari (RANGE created_at).
+// Partisi DEFAULT menampung row di luar partisi harian yang sudah dibuat. Semua statement idempotent.
+var accessLogMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS access_logs (
+		id uuid NOT NULL,
+		created_at timestamptz NOT NULL,
+		method varchar(10) NOT NULL,
+		route text NOT NULL DEFAULT '',
+		status smallint NOT NULL,
+		latency_ms double precision NOT NULL,
+		user_id uuid,
+		token_id uuid,
+		ip_address varchar(45) NOT NULL DEFAULT '',
+		request_id varchar(128) NOT NULL DEFAULT '',
+		PRIMARY KEY (created_at, id)
+	) PARTITION BY RANGE (created_at)`,
+
+	`CREATE TABLE IF NOT EXISTS access_logs_default PARTITION OF access_logs DEFAULT`,
+
+	`CREATE INDEX IF NOT EXISTS idx_access_logs_user_id ON access_logs (user_id, created_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_access_logs_route ON access_logs (route, created_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_access_logs_status ON access_logs (status, created_at)`,
+}
+
+// accessLogPartitionDays is how many daily partitions are created ahead of today
+const accessLogPartitionDays = 7
+
+// MigrateAccessLogs creates the partitioned access log table and the upcoming daily partitions
+func MigrateAccessLogs(db *gorm.DB) error {
+	for _, statement := range accessLogMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("access log migration failed: %v", err)
+		}
+	}
+	return EnsureAccessLogPartitions(db, time.Now(), accessLogPartitionDays)
+}
+
+func accessLogPartition(day time.Time) string {
+	return "access_logs_" + day.Format("20060102")
+}
+
+// EnsureAccessLogPartitions creates the daily partitions (UTC) from the day of from for the given number of days
+func EnsureAccessLogPartitions(db *gorm.DB, from time.Time, days int) error {
+	day := from.UTC().Truncate(24 * time.Hour)
+	for i := 0; i <= days; i++ {
+		start := day.AddDate(0, 0, i)
+		end := start.AddDate(0, 0, 1)
+		statement := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF access_logs FOR VALUES FROM ('%s') TO ('%s')`,
+			accessLogPartition(start), start.Format(time.RFC3339), end.Format(time.RFC3339))
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create partition %s: %v", accessLogPartition(start), err)
+		}
+	}
+	return nil
+}
+
+// DropAccessLogPartitions drops daily partitions that end before cutoff and deletes old rows
+// from the default partition. Returns the dropped partition names
+func DropAccessLogPartitions(db *gorm.DB, cutoff time.Time) ([]string, error) {
+	var partitions []string
+	err := db.Raw(`SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'access_logs'`).Scan(&partitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, partition := range partitions {
+		day, err := time.Parse("access_logs_20060102", partition)
+		if err != nil {
+			continue // partisi default
+		}
+		if day.AddDate(0, 0, 1).After(cutoff) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", partition)).Error; err != nil {
+			return dropped, fmt.Errorf("failed to drop partition %s: %v", partition, err)
+		}
+		dropped = append(dropped, partition)
+	}
+
+	if err := db.Exec("DELETE FROM access_logs_default WHERE created_at < ?", cutoff).Error; err != nil {
+		return dropped, err
+	}
+	return dropped, nil
+}
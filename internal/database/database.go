@@ -1,11 +1,15 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"sjek/internal/logger"
 	"sjek/internal/models"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -28,23 +32,41 @@ func InitDB() (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		dbHost, dbUser, dbPassword, dbName, dbPort)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.GormFromEnv()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
 	DB = db
-	log.Println("Database connection established")
+	slog.Info("Database connection established", "host", dbHost, "database", dbName)
+
+	// Custom join table untuk time-bound role assignment
+	if err := DB.SetupJoinTable(&models.User{}, "Roles", &models.UserRole{}); err != nil {
+		return nil, fmt.Errorf("failed to setup user role join table: %v", err)
+	}
+	if err := DB.SetupJoinTable(&models.Role{}, "Users", &models.UserRole{}); err != nil {
+		return nil, fmt.Errorf("failed to setup role user join table: %v", err)
+	}
 
 	// Auto Migrate the schemas
-	err = DB.AutoMigrate(&models.User{}, &models.Role{}, &models.API{}, &models.LoginLog{}, &models.UserToken{}, &models.Menu{})
+	err = DB.AutoMigrate(&models.User{}, &models.Role{}, &models.API{}, &models.LoginLog{}, &models.UserToken{}, &models.Menu{}, &models.RoleAPIDeny{}, &models.RoleMenuDeny{}, &models.UserRole{}, &models.UserStatusHistory{}, &models.PasswordToken{}, &models.DriverProfile{}, &models.Document{}, &models.Group{}, &models.GroupMember{}, &models.GroupRole{}, &models.AuditLog{}, &models.ChainCheckpoint{}, &models.WebhookSubscription{}, &models.WebhookEvent{}, &models.WebhookDelivery{}, &models.WebhookAttempt{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
 
+	// Index full-text dan trigram untuk pencarian user
+	if err := MigrateSearch(DB); err != nil {
+		return nil, err
+	}
+
+	// Tabel access log dipartisi per hari, dibuat dengan SQL
+	if err := MigrateAccessLogs(DB); err != nil {
+		return nil, err
+	}
+
 	// Seed default menus
 	// if err := SeedDefaultMenus(db); err != nil {
-	// 	log.Printf("Warning: failed to seed default menus: %v", err)
+	// 	slog.Warn("Failed to seed default menus", "error", err)
 	// }
 
 	return db, nil
@@ -53,3 +75,15 @@ func InitDB() (*gorm.DB, error) {
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// WithContext returns DB bound to ctx. Handler memakai context request supaya query log
+// GORM membawa request_id dan query dibatalkan saat client memutus koneksi
+func WithContext(ctx context.Context) *gorm.DB {
+	return DB.WithContext(ctx)
+}
+
+// IsUniqueViolation reports whether err is a Postgres unique constraint violation
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -1,7 +1,7 @@
 package database
 
 import (
-	"log"
+	"log/slog"
 	"sjek/internal/models"
 
 	"github.com/google/uuid"
@@ -14,7 +14,7 @@ func SeedDefaultMenus(db *gorm.DB) error {
 	var count int64
 	db.Model(&models.Menu{}).Count(&count)
 	if count > 0 {
-		log.Println("Menus already exist, skipping seeding")
+		slog.Info("Menus already exist, skipping seeding")
 		return nil
 	}
 
@@ -127,7 +127,7 @@ func SeedDefaultMenus(db *gorm.DB) error {
 	// Assign all menus to super_admin role
 	var superAdminRole models.Role
 	if err := db.Where("name = ?", "super_admin").First(&superAdminRole).Error; err != nil {
-		log.Printf("Warning: super_admin role not found, skipping menu assignment")
+		slog.Warn("super_admin role not found, skipping menu assignment")
 		return nil
 	}
 
@@ -138,10 +138,10 @@ func SeedDefaultMenus(db *gorm.DB) error {
 	// Assign all menus to super_admin
 	for _, menu := range allMenus {
 		if err := db.Model(&menu).Association("Roles").Append(&superAdminRole); err != nil {
-			log.Printf("Warning: failed to assign menu %s to super_admin: %v", menu.Name, err)
+			slog.Warn("Failed to assign menu to super_admin", "menu", menu.Name, "error", err)
 		}
 	}
 
-	log.Println("Default menus seeded successfully")
+	slog.Info("Default menus seeded successfully")
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// directAssignments returns rows of map_user_role that are within their validity window at the given time
+func directAssignments(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Table("map_user_role").
+		Where("map_user_role.valid_from IS NULL OR map_user_role.valid_from <= ?", at).
+		Where("map_user_role.valid_until IS NULL OR map_user_role.valid_until > ?", at)
+}
+
+// groupAssignments joins roles of every group with the members of that group
+func groupAssignments(db *gorm.DB) *gorm.DB {
+	return db.Table("map_group_role").
+		Joins("JOIN map_group_user ON map_group_user.group_id = map_group_role.group_id")
+}
+
+// ActiveRoles restricts a query on roles to the roles a user has at the given time: roles
+// assigned directly that are within their validity window, plus roles of every group the
+// user is a member of. userID boleh berupa uuid atau gorm.Expr kolom (misalnya users.id)
+// untuk subquery berkorelasi.
+func ActiveRoles(db *gorm.DB, userID interface{}, at time.Time) func(*gorm.DB) *gorm.DB {
+	direct := directAssignments(db, at).
+		Select("map_user_role.role_id").
+		Where("map_user_role.user_id = ?", userID)
+
+	viaGroup := groupAssignments(db).
+		Select("map_group_role.role_id").
+		Where("map_group_user.user_id = ?", userID)
+
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where("roles.id IN (?) OR roles.id IN (?)", direct, viaGroup)
+	}
+}
+
+// RoleHolders restricts a query on users to the users that have the role at the given time,
+// dengan aturan yang sama seperti ActiveRoles
+func RoleHolders(db *gorm.DB, roleID uuid.UUID, at time.Time) func(*gorm.DB) *gorm.DB {
+	direct := directAssignments(db, at).
+		Select("map_user_role.user_id").
+		Where("map_user_role.role_id = ?", roleID)
+
+	viaGroup := groupAssignments(db).
+		Select("map_group_user.user_id").
+		Where("map_group_role.role_id = ?", roleID)
+
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where("users.id IN (?) OR users.id IN (?)", direct, viaGroup)
+	}
+}
+
+// UserRoleID is a (user, role) pair returned by ActiveRoleIDs
+type UserRoleID struct {
+	UserID uuid.UUID
+	RoleID uuid.UUID
+}
+
+// ActiveRoleIDs returns the roles every given user has at the given time, see ActiveRoles.
+// Pasangan yang sama bisa muncul lebih dari sekali jika role didapat dari beberapa jalur
+func ActiveRoleIDs(db *gorm.DB, userIDs []uuid.UUID, at time.Time) ([]UserRoleID, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var direct, viaGroup []UserRoleID
+	err := directAssignments(db, at).
+		Select("map_user_role.user_id, map_user_role.role_id").
+		Where("map_user_role.user_id IN ?", userIDs).
+		Scan(&direct).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = groupAssignments(db).
+		Select("map_group_user.user_id, map_group_role.role_id").
+		Where("map_group_user.user_id IN ?", userIDs).
+		Scan(&viaGroup).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return append(direct, viaGroup...), nil
+}
+
+// ActiveRoleNames returns names of roles the user has right now, see ActiveRoles.
+// Assignment yang belum mulai atau sudah kedaluwarsa diabaikan.
+func ActiveRoleNames(db *gorm.DB, userID uuid.UUID) ([]string, error) {
+	var names []string
+	err := db.Table("roles").
+		Scopes(ActiveRoles(db, userID, time.Now())).
+		Order("roles.name ASC").
+		Pluck("roles.name", &names).Error
+
+	return names, err
+}
+
+// ActiveRoleNamesByUser returns, for every given user, the names of the roles the user has
+// right now (see ActiveRoles), diurutkan berdasarkan nama
+func ActiveRoleNamesByUser(db *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	pairs, err := ActiveRoleIDs(db, userIDs, time.Now())
+	if err != nil || len(pairs) == 0 {
+		return map[uuid.UUID][]string{}, err
+	}
+
+	roleIDs := make([]uuid.UUID, 0, len(pairs))
+	for _, pair := range pairs {
+		roleIDs = append(roleIDs, pair.RoleID)
+	}
+	var roles []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	if err := db.Table("roles").Select("id, name").Where("id IN ?", roleIDs).Scan(&roles).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		names[role.ID] = role.Name
+	}
+
+	result := make(map[uuid.UUID][]string, len(userIDs))
+	seen := make(map[UserRoleID]bool, len(pairs))
+	for _, pair := range pairs {
+		name, ok := names[pair.RoleID]
+		if !ok || seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		result[pair.UserID] = append(result[pair.UserID], name)
+	}
+	for _, userRoles := range result {
+		sort.Strings(userRoles)
+	}
+	return result, nil
+}
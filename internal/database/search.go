@@ -0,0 +1,86 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// likeEscaper escapes the LIKE wildcards with the escape character used by ContainsPattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ContainsPattern returns a LIKE pattern that matches s as a literal substring. Query yang
+// memakainya harus menulis ESCAPE '\' setelah placeholder, misalnya "email ILIKE ? ESCAPE '\'"
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// searchMigrations membuat kolom search_vector di users beserta trigger dan index
+// untuk full-text (tsvector) dan fuzzy search (pg_trgm). Semua statement idempotent.
+var searchMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+
+	// Dokumen pencarian: username (bobot A), email dan bagian lokalnya (B), data driver (C)
+	`CREATE OR REPLACE FUNCTION users_search_document(p_user_id uuid, p_username text, p_email text)
+	RETURNS tsvector AS $$
+		SELECT setweight(to_tsvector('simple', coalesce(p_username, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(p_email, '') || ' ' ||
+				translate(split_part(coalesce(p_email, ''), '@', 1), '._-', '   ')), 'B') ||
+			setweight(to_tsvector('simple', coalesce((
+				SELECT dp.vehicle_plate || ' ' || replace(dp.vehicle_plate, ' ', '') || ' ' ||
+					dp.license_number || ' ' || coalesce(dp.vehicle_brand, '')
+				FROM driver_profiles dp WHERE dp.user_id = p_user_id
+			), '')), 'C')
+	$$ LANGUAGE sql STABLE`,
+
+	`CREATE OR REPLACE FUNCTION users_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		NEW.search_vector := users_search_document(NEW.id, NEW.username, NEW.email);
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql`,
+
+	`DROP TRIGGER IF EXISTS users_search_vector_update ON users`,
+	`CREATE TRIGGER users_search_vector_update BEFORE INSERT OR UPDATE OF username, email ON users
+	FOR EACH ROW EXECUTE FUNCTION users_search_vector_trigger()`,
+
+	// Perubahan driver profile ikut memperbarui search_vector user pemiliknya
+	`CREATE OR REPLACE FUNCTION driver_profiles_search_vector_trigger() RETURNS trigger AS $$
+	DECLARE
+		target uuid;
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			target := OLD.user_id;
+		ELSE
+			target := NEW.user_id;
+		END IF;
+		UPDATE users SET search_vector = users_search_document(id, username, email) WHERE id = target;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+
+	`DROP TRIGGER IF EXISTS driver_profiles_search_vector_update ON driver_profiles`,
+	`CREATE TRIGGER driver_profiles_search_vector_update AFTER INSERT OR UPDATE OR DELETE ON driver_profiles
+	FOR EACH ROW EXECUTE FUNCTION driver_profiles_search_vector_trigger()`,
+
+	`UPDATE users SET search_vector = users_search_document(id, username, email) WHERE search_vector IS NULL`,
+
+	`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_driver_profiles_vehicle_plate_trgm ON driver_profiles USING GIN (vehicle_plate gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_driver_profiles_license_number_trgm ON driver_profiles USING GIN (license_number gin_trgm_ops)`,
+}
+
+// MigrateSearch creates the full-text and trigram search objects
+func MigrateSearch(db *gorm.DB) error {
+	for _, statement := range searchMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("search migration failed: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package env reads optional configuration values from environment variables.
+//
+// Nilai yang kosong memakai default; nilai yang tidak valid dicatat di log lalu juga
+// memakai default, sehingga salah ketik konfigurasi tidak menghentikan server.
+package env
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Duration reads a positive duration such as "15m" from key
+func Duration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		slog.Warn("Invalid duration, using default", "key", key, "value", value, "default", fallback.String())
+		return fallback
+	}
+	return duration
+}
+
+// Int reads a positive integer from key
+func Int(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		slog.Warn("Invalid number, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// subscriberBuffer is the number of events queued per subscriber before events are dropped
+const subscriberBuffer = 64
+
+// Bus fans events out to in-process subscribers. Subscriber yang lambat tidak memblokir
+// bus; event untuk subscriber itu dibuang
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// Subscription receives the events matching its type filter on C until it is closed
+type Subscription struct {
+	C     <-chan Event
+	ch    chan Event
+	types map[Type]bool
+	bus   *Bus
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber for the given types; no types means all events.
+// Jika bus sudah ditutup, C langsung tertutup
+func (b *Bus) Subscribe(types ...Type) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes and closes C
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subscribers[s]; ok {
+		delete(s.bus.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// Publish delivers the event to the local subscribers whose filter matches
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			slog.Warn("Security event subscriber is too slow, dropping event", "event_id", event.ID, "type", event.Type)
+		}
+	}
+}
+
+// Close closes every subscription so that open streams end, misalnya saat server shutdown
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,73 @@
+// Package events streams security events to dashboards.
+//
+// Event dipublikasikan lewat Postgres NOTIFY (Publish), diterima setiap replica oleh
+// Listen lalu diteruskan ke Bus in-process yang dibaca oleh subscriber SSE. Karena NOTIFY
+// transaksional, event yang dipublikasikan di dalam transaksi hanya terkirim setelah commit.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Channel is the Postgres NOTIFY channel of security events
+const Channel = "security_events"
+
+// Type is the kind of security event
+type Type string
+
+const (
+	TypeLoginFailed  Type = "login.failed"
+	TypeUserLocked   Type = "user.locked"
+	TypeTokenRevoked Type = "token.revoked"
+	TypeRoleChanged  Type = "role.changed"
+	TypeAccessDenied Type = "access.denied"
+)
+
+// Types lists every event type, urutan ini dipakai di dokumentasi dan validasi filter
+var Types = []Type{TypeLoginFailed, TypeUserLocked, TypeTokenRevoked, TypeRoleChanged, TypeAccessDenied}
+
+// Valid reports whether t is a known event type
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is one security event. UserID dan Username adalah user yang menjadi subjek event,
+// ActorID user yang memicunya jika berbeda (misalnya admin). Data berisi detail per type
+// dan harus tetap kecil karena payload NOTIFY dibatasi 8000 byte
+type Event struct {
+	ID        uuid.UUID              `json:"id"`
+	Type      Type                   `json:"type"`
+	Time      time.Time              `json:"time"`
+	UserID    *uuid.UUID             `json:"user_id,omitempty"`
+	Username  string                 `json:"username,omitempty"`
+	ActorID   *uuid.UUID             `json:"actor_id,omitempty"`
+	IPAddress string                 `json:"ip_address,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Publish sends the event to every replica through pg_notify on db. Jika db adalah
+// transaksi, event baru terkirim saat commit dan hilang jika rollback
+func Publish(db *gorm.DB, event Event) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return db.Exec("SELECT pg_notify(?, ?)", Channel, string(payload)).Error
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Listen receives security events with LISTEN on a dedicated connection and publishes
+// them to bus until ctx is cancelled. Koneksi yang putus dibuka ulang dengan backoff;
+// event yang dikirim selama koneksi putus tidak diterima replica ini
+func Listen(ctx context.Context, db *gorm.DB, bus *Bus) {
+	delay := minReconnectDelay
+	for {
+		started := time.Now()
+		err := listen(ctx, db, bus)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		slog.Error("Security event listener stopped, reconnecting", "error", err, "retry_in", delay.String())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+func listen(ctx context.Context, db *gorm.DB, bus *Bus) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unsupported database driver %T", driverConn)
+		}
+		// Koneksi masih LISTEN sehingga tidak boleh kembali ke pool; driver.ErrBadConn membuatnya ditutup
+		return errors.Join(driver.ErrBadConn, receive(ctx, stdlibConn.Conn(), bus))
+	})
+}
+
+// receive listens on pgConn and publishes notifications to bus until an error occurs
+func receive(ctx context.Context, pgConn *pgx.Conn, bus *Bus) error {
+	if _, err := pgConn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	slog.Info("Listening for security events", "channel", Channel)
+
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			slog.Warn("Invalid security event payload", "error", err)
+			continue
+		}
+		bus.Publish(event)
+	}
+}
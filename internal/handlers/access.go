@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"sjek/internal/middleware"
+	"sjek/internal/models"
+	"sjek/internal/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccessExplainUser struct {
+	ID       uuid.UUID         `json:"id"`
+	Username string            `json:"username"`
+	Type     models.UserType   `json:"type"`
+	Status   models.UserStatus `json:"status"`
+}
+
+type AccessExplainRole struct {
+	Name       string     `json:"name"`
+	Active     bool       `json:"active"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	Grants     bool       `json:"grants"`
+	Denies     bool       `json:"denies"`
+	Group      string     `json:"group,omitempty"` // diisi jika role berasal dari group
+}
+
+type AccessExplainResponse struct {
+	User     AccessExplainUser    `json:"user"`
+	Method   string               `json:"method"`
+	Path     string               `json:"path"`
+	Route    string               `json:"route,omitempty"`
+	Access   middleware.RouteKind `json:"access,omitempty" enums:"public,authenticated,protected"`
+	API      *models.API          `json:"api,omitempty"`
+	Policies []string             `json:"policies,omitempty"`
+	Roles    []AccessExplainRole  `json:"roles"`
+	Allowed  bool                 `json:"allowed"`
+	Reasons  []string             `json:"reasons"`
+	Problems []string             `json:"problems"`
+}
+
+// ExplainAccess explains why a user can or cannot call an API
+// @Summary      Explain API access
+// @Description  Resolve method and path to a registered route and evaluate the same checks the router applies:
+// @Description  public and /me routes need no grant, other routes check driver verification, API grants and deny
+// @Description  rules of the user's roles and, for row-level routes, the policies against the addressed resource.
+// @Tags         access
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  query     string  true  "User ID"
+// @Param        method   query     string  true  "HTTP method"
+// @Param        path     query     string  true  "Request path, e.g. /users/6f1c..."
+// @Success      200  {object}  AccessExplainResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /access/explain [get]
+func ExplainAccess(db *gorm.DB, router *gin.Engine, routeAccess *middleware.RouteAccess) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, err := uuid.Parse(c.Query("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		method := strings.ToUpper(c.Query("method"))
+		path := c.Query("path")
+		if method == "" || path == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "method and path are required"})
+			return
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		// Abaikan query string jika ikut dikirim
+		if idx := strings.Index(path, "?"); idx >= 0 {
+			path = path[:idx]
+		}
+
+		var user models.User
+		if err := db.First(&user, "id = ?", userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			}
+			return
+		}
+
+		response := AccessExplainResponse{
+			User: AccessExplainUser{
+				ID:       user.ID,
+				Username: user.Username,
+				Type:     user.Type,
+				Status:   user.Status,
+			},
+			Method:   method,
+			Path:     path,
+			Roles:    []AccessExplainRole{},
+			Reasons:  []string{},
+			Problems: []string{},
+		}
+
+		if user.Status != models.UserStatusActive {
+			response.Problems = append(response.Problems, "User status is "+string(user.Status))
+		}
+
+		// Semua assignment, termasuk yang kedaluwarsa atau belum berlaku
+		var assignments []models.UserRole
+		if err := db.Preload("Role").Where("user_id = ?", user.ID).Find(&assignments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role assignments"})
+			return
+		}
+
+		now := time.Now()
+		var activeRoles []string
+		for _, assignment := range assignments {
+			active := assignment.IsActiveAt(now)
+			if active {
+				activeRoles = append(activeRoles, assignment.Role.Name)
+			} else if assignment.ValidUntil != nil && !assignment.ValidUntil.After(now) {
+				response.Problems = append(response.Problems, "Role "+assignment.Role.Name+" expired at "+assignment.ValidUntil.Format(time.RFC3339))
+			} else if assignment.ValidFrom != nil {
+				response.Problems = append(response.Problems, "Role "+assignment.Role.Name+" is not valid before "+assignment.ValidFrom.Format(time.RFC3339))
+			}
+			response.Roles = append(response.Roles, AccessExplainRole{
+				Name:       assignment.Role.Name,
+				Active:     active,
+				ValidFrom:  assignment.ValidFrom,
+				ValidUntil: assignment.ValidUntil,
+			})
+		}
+		// Role dari group selalu aktif selama user masih anggota
+		groupRoles, err := loadUserGroupRoles(db, user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group roles"})
+			return
+		}
+		for _, groupRole := range groupRoles {
+			if !containsString(activeRoles, groupRole.RoleName) {
+				activeRoles = append(activeRoles, groupRole.RoleName)
+			}
+			response.Roles = append(response.Roles, AccessExplainRole{
+				Name:   groupRole.RoleName,
+				Active: true,
+				Group:  groupRole.GroupName,
+			})
+		}
+
+		if len(activeRoles) == 0 {
+			response.Problems = append(response.Problems, "User has no active roles")
+		}
+
+		route, found := matchRoute(router.Routes(), method, path)
+		if !found {
+			response.Problems = append(response.Problems, "No route matches "+method+" "+path)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		response.Route = route
+
+		// Route yang tidak dicatat diperlakukan sebagai protected, rantai paling ketat
+		kind, ok := routeAccess.Kind(method, route)
+		if !ok {
+			kind = middleware.RouteProtected
+		}
+		response.Access = kind
+		switch kind {
+		case middleware.RoutePublic:
+			response.Allowed = true
+			response.Reasons = append(response.Reasons, "Public route, no authentication required")
+			c.JSON(http.StatusOK, response)
+			return
+		case middleware.RouteAuthenticated:
+			response.Allowed = true
+			response.Reasons = append(response.Reasons, "Route only requires authentication, API grants are not checked")
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		denial, err := middleware.DriverAccessDenial(db, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check driver profile"})
+			return
+		}
+		if denial != "" {
+			response.Problems = append(response.Problems, denial+", only /me endpoints are available")
+		}
+
+		access, err := middleware.ResolveAPIAccess(db, activeRoles, method, route)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				response.Problems = append(response.Problems, "API "+method+" "+route+" is not registered in apis table")
+				c.JSON(http.StatusOK, response)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve API access"})
+			return
+		}
+		response.API = &access.API
+
+		for i := range response.Roles {
+			response.Roles[i].Grants = containsString(access.GrantedBy, response.Roles[i].Name)
+			response.Roles[i].Denies = containsString(access.DeniedBy, response.Roles[i].Name)
+		}
+
+		for _, role := range access.DeniedBy {
+			response.Reasons = append(response.Reasons, "Denied by deny rule on role "+role)
+		}
+		for _, role := range access.GrantedBy {
+			response.Reasons = append(response.Reasons, "Granted by role "+role)
+		}
+		if len(access.GrantedBy) == 0 {
+			response.Reasons = append(response.Reasons, "No active role is granted this API")
+		}
+
+		response.Allowed = access.Allowed() && denial == ""
+
+		if routePolicy, ok := routeAccess.Policy(method, route); ok {
+			for _, p := range routePolicy.Policies {
+				response.Policies = append(response.Policies, p.Name)
+			}
+
+			// Loader membaca :id dari path yang dijelaskan, bukan dari request ini
+			policyCtx := c.Copy()
+			policyCtx.Params = routeParams(route, path)
+			resource, err := routePolicy.Loader(policyCtx)
+			switch {
+			case err == gorm.ErrRecordNotFound:
+				response.Problems = append(response.Problems, "Resource addressed by "+path+" not found")
+				response.Allowed = false
+			case err == middleware.ErrInvalidResourceID:
+				response.Problems = append(response.Problems, "Invalid resource ID in "+path)
+				response.Allowed = false
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load resource"})
+				return
+			default:
+				subject := policy.Subject{UserID: user.ID.String(), Type: user.Type, Roles: activeRoles}
+				decision := policy.Evaluate(routePolicy.Policies, subject, resource)
+				if decision.Allowed {
+					response.Reasons = append(response.Reasons, "Allowed by policies "+strings.Join(response.Policies, ", "))
+				} else {
+					response.Reasons = append(response.Reasons, "Denied by policy "+decision.Policy+": "+decision.Reason)
+					response.Allowed = false
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// routeParams extracts the :param and *catch-all values of path for a route pattern found by matchRoute
+func routeParams(route, path string) gin.Params {
+	routeSegments := strings.Split(strings.Trim(route, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var params gin.Params
+	for i, segment := range routeSegments {
+		switch {
+		case strings.HasPrefix(segment, ":") && i < len(pathSegments):
+			params = append(params, gin.Param{Key: segment[1:], Value: pathSegments[i]})
+		case strings.HasPrefix(segment, "*"):
+			params = append(params, gin.Param{Key: segment[1:], Value: "/" + strings.Join(pathSegments[i:], "/")})
+			return params
+		}
+	}
+	return params
+}
+
+// matchRoute finds the registered route pattern for a concrete path the same
+// way gin does: static segments win over :params, which win over *catch-all
+func matchRoute(routes gin.RoutesInfo, method, path string) (string, bool) {
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	trailingSlash := strings.HasSuffix(path, "/") && path != "/"
+
+	best := ""
+	var bestScore []int
+	for _, route := range routes {
+		if route.Method != method {
+			continue
+		}
+		if (strings.HasSuffix(route.Path, "/") && route.Path != "/") != trailingSlash && !strings.Contains(route.Path, "*") {
+			continue
+		}
+
+		score, ok := routeScore(strings.Split(strings.Trim(route.Path, "/"), "/"), pathSegments)
+		if !ok {
+			continue
+		}
+		if bestScore == nil || higherScore(score, bestScore) {
+			best = route.Path
+			bestScore = score
+		}
+	}
+
+	return best, bestScore != nil
+}
+
+func routeScore(routeSegments, pathSegments []string) ([]int, bool) {
+	var score []int
+	for i, segment := range routeSegments {
+		if strings.HasPrefix(segment, "*") {
+			return append(score, 0), true
+		}
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			score = append(score, 1)
+		case segment == pathSegments[i]:
+			score = append(score, 2)
+		default:
+			return nil, false
+		}
+	}
+
+	if len(routeSegments) != len(pathSegments) {
+		return nil, false
+	}
+	return score, true
+}
+
+func higherScore(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sjek/internal/accesslog"
+	"sjek/internal/database"
+	"sjek/internal/listquery"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// accessLogListFields is the sort/filter whitelist for access log list endpoint
+var accessLogListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":         {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"method":     {Column: "method", Type: listquery.String, Filter: true, Sortable: true},
+	"route":      {Column: "route", Type: listquery.String, Filter: true, Sortable: true},
+	"status":     {Column: "status", Type: listquery.Int, Filter: true, Sortable: true},
+	"user_id":    {Column: "user_id", Type: listquery.UUID, Filter: true, Nullable: true},
+	"token_id":   {Column: "token_id", Type: listquery.UUID, Filter: true, Nullable: true},
+	"ip_address": {Column: "ip_address", Type: listquery.String, Filter: true},
+	"request_id": {Column: "request_id", Type: listquery.String, Filter: true},
+	"created_at": {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
+// AccessLogStat is one group of the access log aggregation. Hanya kolom sesuai group_by yang terisi
+type AccessLogStat struct {
+	Method       *string    `json:"method,omitempty"`
+	Route        *string    `json:"route,omitempty"`
+	Status       *int       `json:"status,omitempty"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	IPAddress    *string    `json:"ip_address,omitempty"`
+	Requests     int64      `json:"requests"`
+	ClientErrors int64      `json:"client_errors"`
+	ServerErrors int64      `json:"server_errors"`
+	AvgLatencyMs float64    `json:"avg_latency_ms"`
+	P95LatencyMs float64    `json:"p95_latency_ms"`
+	MaxLatencyMs float64    `json:"max_latency_ms"`
+	UniqueUsers  int64      `json:"unique_users"`
+}
+
+// AccessLogStatsResponse represents the access log aggregation
+type AccessLogStatsResponse struct {
+	GroupBy    string          `json:"group_by"`
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	SampleRate float64         `json:"sample_rate" example:"1"`
+	Dropped    int64           `json:"dropped"`
+	Data       []AccessLogStat `json:"data"`
+}
+
+// AccessLogBucket is one time bucket of the access log timeseries
+type AccessLogBucket struct {
+	Bucket       time.Time `json:"bucket"`
+	Requests     int64     `json:"requests"`
+	ClientErrors int64     `json:"client_errors"`
+	ServerErrors int64     `json:"server_errors"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+}
+
+// AccessLogTimeseriesResponse represents the access log timeseries
+type AccessLogTimeseriesResponse struct {
+	Interval string            `json:"interval"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Data     []AccessLogBucket `json:"data"`
+}
+
+// accessLogMetrics are the aggregate columns shared by stats and timeseries
+const accessLogMetrics = `COUNT(*) AS requests,
+	COUNT(*) FILTER (WHERE status >= 400 AND status < 500) AS client_errors,
+	COUNT(*) FILTER (WHERE status >= 500) AS server_errors,
+	COALESCE(AVG(latency_ms), 0) AS avg_latency_ms,
+	COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms), 0) AS p95_latency_ms`
+
+// accessLogGroups maps group_by to the grouped columns
+var accessLogGroups = map[string]string{
+	"route":      "method, route",
+	"method":     "method",
+	"status":     "status",
+	"user_id":    "user_id",
+	"ip_address": "ip_address",
+}
+
+// accessLogStatSorts maps sort to the ORDER BY of the aggregation
+var accessLogStatSorts = map[string]string{
+	"requests":    "requests DESC",
+	"errors":      "client_errors + server_errors DESC",
+	"avg_latency": "avg_latency_ms DESC",
+	"p95_latency": "p95_latency_ms DESC",
+	"max_latency": "max_latency_ms DESC",
+}
+
+// accessLogIntervals are the allowed timeseries intervals with the longest range each may cover
+var accessLogIntervals = map[string]time.Duration{
+	"minute": 2 * 24 * time.Hour,
+	"hour":   31 * 24 * time.Hour,
+	"day":    366 * 24 * time.Hour,
+}
+
+// applyAccessLogFilters applies the simple query parameter filters of the list endpoint
+func applyAccessLogFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	query, ok := applyAccessLogFieldFilters(c, query)
+	if !ok {
+		return nil, false
+	}
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		query = query.Where("created_at >= ?", fromDate+" 00:00:00")
+	}
+	if toDate := c.Query("to_date"); toDate != "" {
+		query = query.Where("created_at <= ?", toDate+" 23:59:59")
+	}
+	return query, true
+}
+
+// applyAccessLogFieldFilters applies the filters other than the date range, shared by list, stats and timeseries
+func applyAccessLogFieldFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	for _, param := range []string{"user_id", "token_id"} {
+		if value := c.Query(param); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return nil, false
+			}
+			query = query.Where(param+" = ?", id)
+		}
+	}
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method = ?", method)
+	}
+	if route := c.Query("route"); route != "" {
+		query = query.Where("route = ?", route)
+	}
+	for _, filter := range []struct{ param, condition string }{
+		{"status", "status = ?"}, {"min_status", "status >= ?"}, {"max_status", "status <= ?"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			status, err := strconv.Atoi(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + filter.param})
+				return nil, false
+			}
+			query = query.Where(filter.condition, status)
+		}
+	}
+	if ipAddress := c.Query("ip_address"); ipAddress != "" {
+		query = query.Where("ip_address = ?", ipAddress)
+	}
+	if requestID := c.Query("request_id"); requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+	return query, true
+}
+
+// accessLogRange returns the time range for aggregation: from_date/to_date (YYYY-MM-DD, UTC)
+// dengan default 24 jam terakhir
+func accessLogRange(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if toDate := c.Query("to_date"); toDate != "" {
+		day, err := time.Parse("2006-01-02", toDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_date, use YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		day, err := time.Parse("2006-01-02", fromDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_date, use YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = day
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_date must be before to_date"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// accessLogRangeQuery applies the field filters and limits the query to the aggregation range [from, to)
+func accessLogRangeQuery(c *gin.Context) (*gorm.DB, time.Time, time.Time, bool) {
+	from, to, ok := accessLogRange(c)
+	if !ok {
+		return nil, from, to, false
+	}
+
+	query, ok := applyAccessLogFieldFilters(c, database.WithContext(c.Request.Context()).Table("access_logs"))
+	if !ok {
+		return nil, from, to, false
+	}
+	return query.Where("created_at >= ? AND created_at < ?", from, to), from, to, true
+}
+
+// @Summary      Get access logs
+// @Description  Get recorded HTTP requests with pagination, newest first. Successful requests may be sampled (ACCESS_LOG_SAMPLE_RATE).
+// @Description  Supports sort=-created_at and filters like filter[status][gte]=500 or route[eq]=/users/:id.
+// @Description  Send pagination=cursor, after or before for keyset pagination; the response is then models.CursorPaginatedResponse.
+// @Tags         access-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page        query     int     false  "Page number (default: 1)"
+// @Param        limit       query     int     false  "Items per page (default: 10, max: 100)"
+// @Param        sort        query     string  false  "Sort fields, prefix - for descending (default: -created_at)"
+// @Param        user_id     query     string  false  "Filter by user ID"
+// @Param        token_id    query     string  false  "Filter by token ID"
+// @Param        method      query     string  false  "Filter by HTTP method"
+// @Param        route       query     string  false  "Filter by route pattern, e.g. /users/:id"
+// @Param        status      query     int     false  "Filter by status code"
+// @Param        min_status  query     int     false  "Filter by minimum status code"
+// @Param        max_status  query     int     false  "Filter by maximum status code"
+// @Param        ip_address  query     string  false  "Filter by IP address"
+// @Param        request_id  query     string  false  "Filter by request ID"
+// @Param        from_date   query     string  false  "Filter from date (YYYY-MM-DD)"
+// @Param        to_date     query     string  false  "Filter to date (YYYY-MM-DD)"
+// @Param        pagination  query     string  false  "Set to cursor for keyset pagination"
+// @Param        after       query     string  false  "Cursor for the next page"
+// @Param        before      query     string  false  "Cursor for the previous page"
+// @Param        with_total  query     bool    false  "Count total rows in cursor mode"
+// @Success      200  {object}  models.PaginatedResponse{data=[]models.AccessLog}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /access-logs [get]
+func GetAccessLogs(c *gin.Context) {
+	var pagination models.Pagination
+	pagination.Page = 1
+	pagination.Limit = 10
+
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.Limit < 1 {
+		pagination.Limit = 10
+	}
+	if pagination.Limit > 100 {
+		pagination.Limit = 100
+	}
+	pagination.Offset = (pagination.Page - 1) * pagination.Limit
+
+	listQuery, ok := bindListQuery(c, accessLogListFields)
+	if !ok {
+		return
+	}
+
+	query, ok := applyAccessLogFilters(c, database.WithContext(c.Request.Context()).Model(&models.AccessLog{}))
+	if !ok {
+		return
+	}
+	query = listQuery.ApplyFilters(query)
+
+	keyset, ok := bindKeyset(c, accessLogListFields, listQuery, "-created_at", pagination.Limit)
+	if !ok {
+		return
+	}
+	if keyset != nil {
+		logs := []models.AccessLog{}
+		cursorPagination, err := findCursorPage(c, keyset, query, &logs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access logs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+			Data:       logs,
+			Pagination: cursorPagination,
+		})
+		return
+	}
+
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count access logs"})
+		return
+	}
+
+	logs := []models.AccessLog{}
+	err := listQuery.ApplySort(query, "created_at DESC").Order("id DESC").
+		Offset(pagination.Offset).Limit(pagination.Limit).Find(&logs).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       logs,
+		Pagination: pagination,
+	})
+}
+
+// @Summary      Get access log statistics
+// @Description  Aggregate recorded requests per route, method, status, user or IP: request count, client/server errors,
+// @Description  average, p95 and max latency and unique users. Default range is the last 24 hours.
+// @Description  Successful requests may be sampled; counts then cover only the sample (see sample_rate).
+// @Tags         access-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        group_by    query     string  false  "route (default), method, status, user_id or ip_address"
+// @Param        sort        query     string  false  "requests (default), errors, avg_latency, p95_latency or max_latency; always descending"
+// @Param        limit       query     int     false  "Number of groups (default: 20, max: 100)"
+// @Param        from_date   query     string  false  "From date (YYYY-MM-DD, UTC)"
+// @Param        to_date     query     string  false  "To date inclusive (YYYY-MM-DD, UTC)"
+// @Param        user_id     query     string  false  "Filter by user ID"
+// @Param        token_id    query     string  false  "Filter by token ID"
+// @Param        method      query     string  false  "Filter by HTTP method"
+// @Param        route       query     string  false  "Filter by route pattern"
+// @Param        status      query     int     false  "Filter by status code"
+// @Param        min_status  query     int     false  "Filter by minimum status code"
+// @Param        max_status  query     int     false  "Filter by maximum status code"
+// @Param        ip_address  query     string  false  "Filter by IP address"
+// @Success      200  {object}  AccessLogStatsResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /access-logs/stats [get]
+func GetAccessLogStats(writer *accesslog.Writer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupBy := c.DefaultQuery("group_by", "route")
+		columns, ok := accessLogGroups[groupBy]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_by, use route, method, status, user_id or ip_address"})
+			return
+		}
+
+		order, ok := accessLogStatSorts[c.DefaultQuery("sort", "requests")]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use requests, errors, avg_latency, p95_latency or max_latency"})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > 100 {
+			limit = 100
+		}
+
+		query, from, to, ok := accessLogRangeQuery(c)
+		if !ok {
+			return
+		}
+
+		stats := []AccessLogStat{}
+		err = query.Select(columns + ", " + accessLogMetrics + `,
+			COALESCE(MAX(latency_ms), 0) AS max_latency_ms,
+			COUNT(DISTINCT user_id) AS unique_users`).
+			Group(columns).Order(order).Limit(limit).Scan(&stats).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate access logs"})
+			return
+		}
+
+		response := AccessLogStatsResponse{GroupBy: groupBy, From: from, To: to, Data: stats}
+		if writer != nil {
+			response.SampleRate = writer.Config().SampleRate
+			response.Dropped = writer.Dropped()
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// @Summary      Get access log timeseries
+// @Description  Request count, errors and latency per minute, hour or day (UTC buckets). Default range is the last 24 hours.
+// @Description  Interval minute covers at most 2 days, hour 31 days and day 366 days.
+// @Tags         access-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        interval    query     string  false  "minute, hour (default) or day"
+// @Param        from_date   query     string  false  "From date (YYYY-MM-DD, UTC)"
+// @Param        to_date     query     string  false  "To date inclusive (YYYY-MM-DD, UTC)"
+// @Param        user_id     query     string  false  "Filter by user ID"
+// @Param        token_id    query     string  false  "Filter by token ID"
+// @Param        method      query     string  false  "Filter by HTTP method"
+// @Param        route       query     string  false  "Filter by route pattern"
+// @Param        status      query     int     false  "Filter by status code"
+// @Param        min_status  query     int     false  "Filter by minimum status code"
+// @Param        max_status  query     int     false  "Filter by maximum status code"
+// @Param        ip_address  query     string  false  "Filter by IP address"
+// @Success      200  {object}  AccessLogTimeseriesResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /access-logs/timeseries [get]
+func GetAccessLogTimeseries(c *gin.Context) {
+	interval := c.DefaultQuery("interval", "hour")
+	maxRange, ok := accessLogIntervals[interval]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval, use minute, hour or day"})
+		return
+	}
+
+	query, from, to, ok := accessLogRangeQuery(c)
+	if !ok {
+		return
+	}
+	if to.Sub(from) > maxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range is too long for interval " + interval})
+		return
+	}
+
+	// interval sudah divalidasi terhadap whitelist sehingga aman disisipkan ke SQL
+	buckets := []AccessLogBucket{}
+	err := query.Select("date_trunc('" + interval + "', created_at AT TIME ZONE 'UTC') AS bucket, " + accessLogMetrics).
+		Group("1").Order("1").Scan(&buckets).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate access logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AccessLogTimeseriesResponse{Interval: interval, From: from, To: to, Data: buckets})
+}
@@ -2,25 +2,34 @@ package handlers
 
 import (
 	"net/http"
+	"sjek/internal/audit"
+	"sjek/internal/listquery"
 	"sjek/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateAPI creates a new API endpoint
 func CreateAPI(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		var api models.API
 		if err := c.ShouldBindJSON(&api); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		result := db.Create(&api)
-		if result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&api).Error; err != nil {
+				return err
+			}
+			return recordAudit(tx, c, audit.ActionCreate, audit.ResourceAPI, api.ID, nil, api)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -28,20 +37,38 @@ func CreateAPI(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// apiListFields is the sort/filter whitelist for API list endpoint
+var apiListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":          {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"method":      {Column: "method", Type: listquery.String, Filter: true, Sortable: true},
+	"path":        {Column: "path", Type: listquery.String, Filter: true, Sortable: true},
+	"description": {Column: "description", Type: listquery.String, Filter: true, Sortable: true},
+	"created_at":  {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"updated_at":  {Column: "updated_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
 // GetAPIs returns all API endpoints with pagination
 // @Summary      Get all APIs
-// @Description  Get list of all APIs with pagination
+// @Description  Get list of all APIs with pagination.
+// @Description  Supports sort=path,method and filters like filter[method][in]=POST,PUT or path[like]=/users.
+// @Description  Send pagination=cursor, after or before for keyset pagination; the response is then models.CursorPaginatedResponse.
 // @Tags         apis
 // @Produce      json
 // @Security     BearerAuth
-// @Param        page   query     int  false  "Page number"
-// @Param        limit  query     int  false  "Items per page"
+// @Param        page   query     int     false  "Page number"
+// @Param        limit  query     int     false  "Items per page"
+// @Param        sort   query     string  false  "Sort fields, prefix - for descending (default: path,method)"
+// @Param        pagination  query  string  false  "Set to cursor for keyset pagination"
+// @Param        after       query  string  false  "Cursor for the next page"
+// @Param        before      query  string  false  "Cursor for the previous page"
+// @Param        with_total  query  bool    false  "Count total rows in cursor mode"
 // @Success      200    {object}  models.PaginatedResponse
 // @Failure      400    {object}  ErrorResponse
 // @Failure      500    {object}  ErrorResponse
 // @Router       /apis [get]
 func GetAPIs(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		var pagination models.Pagination
 		// Set default values
 		pagination.Page = 1
@@ -67,19 +94,44 @@ func GetAPIs(db *gorm.DB) gin.HandlerFunc {
 		// Hitung offset
 		pagination.Offset = (pagination.Page - 1) * pagination.Limit
 
+		listQuery, ok := bindListQuery(c, apiListFields)
+		if !ok {
+			return
+		}
+		query := listQuery.ApplyFilters(db.Model(&models.API{}))
+
+		keyset, ok := bindKeyset(c, apiListFields, listQuery, "path,method", pagination.Limit)
+		if !ok {
+			return
+		}
+		if keyset != nil {
+			var apis []models.API
+			cursorPagination, err := findCursorPage(c, keyset, query.Preload("Roles"), &apis)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch APIs"})
+				return
+			}
+
+			c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+				Data:       apis,
+				Pagination: cursorPagination,
+			})
+			return
+		}
+
 		// Query dengan preload dan count total
 		var apis []models.API
 		var total int64
 
 		// Hitung total records
-		if err := db.Model(&models.API{}).Count(&total).Error; err != nil {
+		if err := query.Count(&total).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count APIs"})
 			return
 		}
 		pagination.Total = total
 
 		// Ambil data dengan pagination
-		result := db.Preload("Roles").Offset(pagination.Offset).Limit(pagination.Limit).Find(&apis)
+		result := listQuery.ApplySort(query, "path ASC, method ASC").Preload("Roles").Offset(pagination.Offset).Limit(pagination.Limit).Find(&apis)
 		if result.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch APIs"})
 			return
@@ -96,6 +148,7 @@ func GetAPIs(db *gorm.DB) gin.HandlerFunc {
 // GetAPI returns a specific API endpoint by ID
 func GetAPI(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
@@ -109,6 +162,7 @@ func GetAPI(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		setETag(c, api.UpdatedAt)
 		c.JSON(http.StatusOK, api)
 	}
 }
@@ -116,6 +170,7 @@ func GetAPI(db *gorm.DB) gin.HandlerFunc {
 // UpdateAPI updates an API endpoint
 func UpdateAPI(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
@@ -128,17 +183,102 @@ func UpdateAPI(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !checkIfMatch(c, api.UpdatedAt) {
+			return
+		}
+		// Disimpan sebelum bind karena body bisa menimpa updated_at
+		since := api.UpdatedAt
+		before := api
+
 		if err := c.ShouldBindJSON(&api); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		api.ID = id // Ensure ID remains unchanged
-		if err := db.Save(&api).Error; err != nil {
+		var saved bool
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if saved, err = saveIfUnmodified(tx, &api, since); err != nil || !saved {
+				return err
+			}
+			return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceAPI, api.ID, before, api)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !saved {
+			respondPreconditionFailed(c, api.UpdatedAt)
+			return
+		}
+
+		setETag(c, api.UpdatedAt)
+		c.JSON(http.StatusOK, api)
+	}
+}
+
+// APIPatchRequest is the patchable shape of an API endpoint
+type APIPatchRequest struct {
+	Path        string `json:"path" binding:"required"`
+	Method      string `json:"method" binding:"required"`
+	Description string `json:"description"`
+}
+
+// PatchAPI partially updates an API endpoint with a JSON Merge Patch or JSON Patch body
+func PatchAPI(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
+			return
+		}
+
+		var api models.API
+		if err := db.First(&api, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+			return
+		}
+
+		if !checkIfMatch(c, api.UpdatedAt) {
+			return
+		}
+		since := api.UpdatedAt
+		before := api
+
+		var req APIPatchRequest
+		columns, ok := bindPatch(c, APIPatchRequest{Path: api.Path, Method: api.Method, Description: api.Description}, &req)
+		if !ok {
+			return
+		}
+		if len(columns) == 0 {
+			setETag(c, api.UpdatedAt)
+			c.JSON(http.StatusOK, api)
+			return
+		}
+
+		api.Path = req.Path
+		api.Method = req.Method
+		api.Description = req.Description
+		var saved bool
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if saved, err = saveIfUnmodified(tx, &api, since, columns...); err != nil || !saved {
+				return err
+			}
+			return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceAPI, api.ID, before, api)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if !saved {
+			respondPreconditionFailed(c, api.UpdatedAt)
+			return
+		}
 
+		setETag(c, api.UpdatedAt)
 		c.JSON(http.StatusOK, api)
 	}
 }
@@ -146,20 +286,39 @@ func UpdateAPI(db *gorm.DB) gin.HandlerFunc {
 // DeleteAPI deletes an API endpoint
 func DeleteAPI(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
 			return
 		}
 
-		result := db.Delete(&models.API{}, "id = ?", id)
-		if result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		var api models.API
+		if err := db.First(&api, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
 			return
 		}
 
-		if result.RowsAffected == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+		if !checkIfMatch(c, api.UpdatedAt) {
+			return
+		}
+
+		var rowsAffected int64
+		err = db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("updated_at = ?", api.UpdatedAt).Delete(&models.API{}, "id = ?", id)
+			if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+				return result.Error
+			}
+			return recordAudit(tx, c, audit.ActionDelete, audit.ResourceAPI, api.ID, api, nil)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if rowsAffected == 0 {
+			db.Select("updated_at").First(&api)
+			respondPreconditionFailed(c, api.UpdatedAt)
 			return
 		}
 
@@ -170,6 +329,7 @@ func DeleteAPI(db *gorm.DB) gin.HandlerFunc {
 // AssignAPIToRole assigns an API endpoint to a role
 func AssignAPIToRole(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		apiID, err := uuid.Parse(c.Param("api_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
@@ -197,7 +357,18 @@ func AssignAPIToRole(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// Associate API with Role
-		if err := db.Model(&api).Association("Roles").Append(&role); err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var existing int64
+			if err := tx.Table("map_role_api").Where("api_id = ? AND role_id = ?", api.ID, role.ID).Count(&existing).Error; err != nil || existing > 0 {
+				return err
+			}
+
+			if err := tx.Model(&api).Association("Roles").Append(&role); err != nil {
+				return err
+			}
+			return recordAudit(tx, c, audit.ActionAssign, audit.ResourceRoleAPI, api.ID, nil, roleAPIAudit(api, role))
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -209,6 +380,7 @@ func AssignAPIToRole(db *gorm.DB) gin.HandlerFunc {
 // RemoveAPIFromRole removes an API endpoint from a role
 func RemoveAPIFromRole(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		apiID, err := uuid.Parse(c.Param("api_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
@@ -236,7 +408,14 @@ func RemoveAPIFromRole(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// Remove association between API and Role
-		if err := db.Model(&api).Association("Roles").Delete(&role); err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Exec("DELETE FROM map_role_api WHERE api_id = ? AND role_id = ?", api.ID, role.ID)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return recordAudit(tx, c, audit.ActionUnassign, audit.ResourceRoleAPI, api.ID, roleAPIAudit(api, role), nil)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -244,3 +423,173 @@ func RemoveAPIFromRole(db *gorm.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "API removed from role successfully"})
 	}
 }
+
+// DenyRuleRequest represents optional payload for creating a deny rule
+type DenyRuleRequest struct {
+	Reason string `json:"reason"`
+}
+
+// GetAPIDenyRules returns all API deny rules, optionally filtered by role
+// @Summary      Get API deny rules
+// @Description  Get list of API deny rules, optionally filtered by role
+// @Tags         api-assignments
+// @Produce      json
+// @Security     BearerAuth
+// @Param        role_id  query     string  false  "Filter by role ID"
+// @Success      200  {array}   models.RoleAPIDeny
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api-assignments/denies [get]
+func GetAPIDenyRules(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		query := db.Preload("Role").Preload("API").Order("created_at DESC")
+
+		if roleIDParam := c.Query("role_id"); roleIDParam != "" {
+			roleID, err := uuid.Parse(roleIDParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Role ID"})
+				return
+			}
+			query = query.Where("role_id = ?", roleID)
+		}
+
+		var rules []models.RoleAPIDeny
+		if err := query.Find(&rules).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API deny rules"})
+			return
+		}
+
+		c.JSON(http.StatusOK, rules)
+	}
+}
+
+// DenyAPIForRole creates a deny rule that overrides any grant of the API to the role
+// @Summary      Deny API for role
+// @Description  Explicitly deny a role access to an API. Deny rules always override grants.
+// @Tags         api-assignments
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        api_id   path  string           true   "API ID"
+// @Param        role_id  path  string           true   "Role ID"
+// @Param        request  body  DenyRuleRequest  false  "Deny reason"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api-assignments/apis/{api_id}/roles/{role_id}/deny [post]
+func DenyAPIForRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		apiID, err := uuid.Parse(c.Param("api_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
+			return
+		}
+
+		roleID, err := uuid.Parse(c.Param("role_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Role ID"})
+			return
+		}
+
+		var req DenyRuleRequest
+		if !bindOptionalJSON(c, &req) {
+			return
+		}
+
+		// Check if API exists
+		var api models.API
+		if err := db.First(&api, "id = ?", apiID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+			return
+		}
+
+		// Check if Role exists
+		var role models.Role
+		if err := db.First(&role, "id = ?", roleID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+			return
+		}
+
+		rule := models.RoleAPIDeny{
+			RoleID: role.ID,
+			APIID:  api.ID,
+			Reason: req.Reason,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rule)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return recordAudit(tx, c, audit.ActionDeny, audit.ResourceRoleAPIDeny, api.ID, nil, roleAPIDenyAudit(rule))
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deny API for role"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API denied for role successfully"})
+	}
+}
+
+// RemoveAPIDenyFromRole removes a deny rule between an API and a role
+// @Summary      Remove API deny rule
+// @Description  Remove an explicit deny rule between an API and a role
+// @Tags         api-assignments
+// @Security     BearerAuth
+// @Param        api_id   path  string  true  "API ID"
+// @Param        role_id  path  string  true  "Role ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api-assignments/apis/{api_id}/roles/{role_id}/deny [delete]
+func RemoveAPIDenyFromRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		apiID, err := uuid.Parse(c.Param("api_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID"})
+			return
+		}
+
+		roleID, err := uuid.Parse(c.Param("role_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Role ID"})
+			return
+		}
+
+		var rule models.RoleAPIDeny
+		var rowsAffected int64
+		err = db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.Returning{}).Delete(&rule, "api_id = ? AND role_id = ?", apiID, roleID)
+			if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+				return result.Error
+			}
+			return recordAudit(tx, c, audit.ActionUndeny, audit.ResourceRoleAPIDeny, apiID, roleAPIDenyAudit(rule), nil)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove API deny rule"})
+			return
+		}
+
+		if rowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deny rule not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API deny rule removed successfully"})
+	}
+}
+
+// roleAPIAudit is the audit snapshot of a map_role_api row
+func roleAPIAudit(api models.API, role models.Role) gin.H {
+	return gin.H{"api_id": api.ID, "method": api.Method, "path": api.Path, "role_id": role.ID, "role_name": role.Name}
+}
+
+// roleAPIDenyAudit is the audit snapshot of a deny rule without its relations
+func roleAPIDenyAudit(rule models.RoleAPIDeny) gin.H {
+	return gin.H{"api_id": rule.APIID, "role_id": rule.RoleID, "reason": rule.Reason, "created_at": rule.CreatedAt}
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/events"
+	"sjek/internal/listquery"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// auditActor builds the audit actor from the authenticated request
+func auditActor(c *gin.Context) audit.Actor {
+	actor := audit.Actor{
+		Username:  c.GetString("username"),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString("request_id"),
+	}
+	if userID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		actor.UserID = &userID
+	}
+	return actor
+}
+
+// recordAudit writes an audit entry in tx, the same transaction as the change.
+// Perubahan role dan permission juga dipublikasikan sebagai security event role.changed
+func recordAudit(tx *gorm.DB, c *gin.Context, action audit.Action, resourceType string, resourceID interface{}, before, after interface{}) error {
+	err := audit.Record(tx, auditActor(c), audit.Entry{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   fmt.Sprint(resourceID),
+		Before:       before,
+		After:        after,
+	})
+	if err != nil || !roleChangeResources[resourceType] {
+		return err
+	}
+
+	return publishSecurityEvent(tx, c, events.Event{
+		Type: events.TypeRoleChanged,
+		Data: map[string]interface{}{
+			"action":        action,
+			"resource_type": resourceType,
+			"resource_id":   fmt.Sprint(resourceID),
+		},
+	})
+}
+
+// auditLogListFields is the sort/filter whitelist for audit log list endpoint
+var auditLogListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":             {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"actor_id":       {Column: "actor_id", Type: listquery.UUID, Filter: true, Nullable: true},
+	"actor_username": {Column: "actor_username", Type: listquery.String, Filter: true, Sortable: true},
+	"action":         {Column: "action", Type: listquery.String, Filter: true, Sortable: true},
+	"resource_type":  {Column: "resource_type", Type: listquery.String, Filter: true, Sortable: true},
+	"resource_id":    {Column: "resource_id", Type: listquery.String, Filter: true},
+	"ip_address":     {Column: "ip_address", Type: listquery.String, Filter: true},
+	"request_id":     {Column: "request_id", Type: listquery.String, Filter: true},
+	"created_at":     {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
+// applyAuditLogFilters applies the simple query parameter filters shared by list and export
+func applyAuditLogFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	if actorID := c.Query("actor_id"); actorID != "" {
+		id, err := uuid.Parse(actorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actor ID"})
+			return nil, false
+		}
+		query = query.Where("actor_id = ?", id)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if resourceType := c.Query("resource_type"); resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+	if resourceID := c.Query("resource_id"); resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+	if requestID := c.Query("request_id"); requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		query = query.Where("created_at >= ?", fromDate+" 00:00:00")
+	}
+	if toDate := c.Query("to_date"); toDate != "" {
+		query = query.Where("created_at <= ?", toDate+" 23:59:59")
+	}
+	return query, true
+}
+
+// @Summary      Get audit logs
+// @Description  Get audit trail of administrative changes with pagination, newest first.
+// @Description  Supports sort=-created_at and filters like filter[action][in]=CREATE,DELETE or created_at[gte]=2024-01-01.
+// @Description  Send pagination=cursor, after or before for keyset pagination; the response is then models.CursorPaginatedResponse.
+// @Tags         audit-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page           query     int     false  "Page number (default: 1)"
+// @Param        limit          query     int     false  "Items per page (default: 10, max: 100)"
+// @Param        sort           query     string  false  "Sort fields, prefix - for descending (default: -created_at)"
+// @Param        actor_id       query     string  false  "Filter by actor user ID"
+// @Param        action         query     string  false  "Filter by action (CREATE/UPDATE/DELETE/RESTORE/ASSIGN/UNASSIGN/DENY/UNDENY/IMPORT/REVOKE)"
+// @Param        resource_type  query     string  false  "Filter by resource type, e.g. user, role, user_role"
+// @Param        resource_id    query     string  false  "Filter by resource ID"
+// @Param        request_id     query     string  false  "Filter by request ID"
+// @Param        from_date      query     string  false  "Filter from date (YYYY-MM-DD)"
+// @Param        to_date        query     string  false  "Filter to date (YYYY-MM-DD)"
+// @Param        pagination     query     string  false  "Set to cursor for keyset pagination"
+// @Param        after          query     string  false  "Cursor for the next page"
+// @Param        before         query     string  false  "Cursor for the previous page"
+// @Param        with_total     query     bool    false  "Count total rows in cursor mode"
+// @Success      200  {object}  models.PaginatedResponse{data=[]models.AuditLog}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /audit-logs [get]
+func GetAuditLogs(c *gin.Context) {
+	var pagination models.Pagination
+	pagination.Page = 1
+	pagination.Limit = 10
+
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.Limit < 1 {
+		pagination.Limit = 10
+	}
+	if pagination.Limit > 100 {
+		pagination.Limit = 100
+	}
+	pagination.Offset = (pagination.Page - 1) * pagination.Limit
+
+	listQuery, ok := bindListQuery(c, auditLogListFields)
+	if !ok {
+		return
+	}
+
+	query, ok := applyAuditLogFilters(c, database.WithContext(c.Request.Context()).Model(&models.AuditLog{}))
+	if !ok {
+		return
+	}
+	query = listQuery.ApplyFilters(query)
+
+	keyset, ok := bindKeyset(c, auditLogListFields, listQuery, "-created_at", pagination.Limit)
+	if !ok {
+		return
+	}
+	if keyset != nil {
+		logs := []models.AuditLog{}
+		cursorPagination, err := findCursorPage(c, keyset, query, &logs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+			Data:       logs,
+			Pagination: cursorPagination,
+		})
+		return
+	}
+
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count audit logs"})
+		return
+	}
+
+	logs := []models.AuditLog{}
+	err := listQuery.ApplySort(query, "created_at DESC").Order("id DESC").
+		Offset(pagination.Offset).Limit(pagination.Limit).Find(&logs).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       logs,
+		Pagination: pagination,
+	})
+}
+
+// @Summary      Get audit log by ID
+// @Description  Get one audit entry with before/after snapshots and field changes
+// @Tags         audit-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Audit log ID"
+// @Success      200  {object}  models.AuditLog
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /audit-logs/{id} [get]
+func GetAuditLog(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audit log ID"})
+		return
+	}
+
+	var entry models.AuditLog
+	if err := database.WithContext(c.Request.Context()).First(&entry, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audit log not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// @Summary      Export audit logs
+// @Description  Stream audit logs matching the list filters as CSV or XLSX
+// @Tags         audit-logs
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security     BearerAuth
+// @Param        format         query     string  false  "Export format (csv/xlsx, default: csv)"
+// @Param        sort           query     string  false  "Sort fields, prefix - for descending (e.g. -created_at)"
+// @Param        actor_id       query     string  false  "Filter by actor user ID"
+// @Param        action         query     string  false  "Filter by action"
+// @Param        resource_type  query     string  false  "Filter by resource type"
+// @Param        resource_id    query     string  false  "Filter by resource ID"
+// @Param        request_id     query     string  false  "Filter by request ID"
+// @Param        from_date      query     string  false  "Filter from date (YYYY-MM-DD)"
+// @Param        to_date        query     string  false  "Filter to date (YYYY-MM-DD)"
+// @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /audit-logs/export [get]
+func ExportAuditLogs(c *gin.Context) {
+	listQuery, ok := bindListQuery(c, auditLogListFields)
+	if !ok {
+		return
+	}
+
+	query, ok := applyAuditLogFilters(c, database.WithContext(c.Request.Context()).Model(&models.AuditLog{}))
+	if !ok {
+		return
+	}
+	query = listQuery.ApplySort(listQuery.ApplyFilters(query), "created_at ASC").Order("id ASC")
+
+	header := []interface{}{"id", "created_at", "actor_id", "actor_username", "action", "resource_type", "resource_id",
+		"changes", "before", "after", "ip_address", "user_agent", "request_id"}
+	streamExport(c, "audit-logs", header, query, func(rows *sql.Rows) ([]interface{}, error) {
+		var entry models.AuditLog
+		if err := query.ScanRows(rows, &entry); err != nil {
+			return nil, err
+		}
+
+		actorID := ""
+		if entry.ActorID != nil {
+			actorID = entry.ActorID.String()
+		}
+		return []interface{}{entry.ID.String(), entry.CreatedAt, actorID, entry.ActorUsername, entry.Action,
+			entry.ResourceType, entry.ResourceID, string(entry.Changes), string(entry.Before), string(entry.After),
+			entry.IPAddress, entry.UserAgent, entry.RequestID}, nil
+	})
+}
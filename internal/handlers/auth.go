@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
+	"sjek/internal/audit"
 	"sjek/internal/database"
+	"sjek/internal/events"
+	"sjek/internal/hashchain"
 	"sjek/internal/middleware"
 	"sjek/internal/models"
+	"sjek/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -27,7 +34,8 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token         string `json:"token"`
+	LimitedAccess string `json:"limited_access,omitempty"` // diisi untuk driver yang belum diverifikasi
 }
 
 // ErrorResponse represents error response
@@ -40,32 +48,47 @@ type SuccessResponse struct {
 	Message string `json:"message" example:"operation successful"`
 }
 
-// Fungsi helper untuk register user
-func registerUser(c *gin.Context, userType models.UserType) {
-	var req RegisterUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+var (
+	errUsernameExists = errors.New("Username already exists")
+	errEmailExists    = errors.New("Email already exists")
+)
 
-	// Cek apakah username sudah ada
+// checkUserUnique returns errUsernameExists or errEmailExists if taken.
+// User yang sudah di-soft delete tetap dihitung karena constraint unique di database.
+func checkUserUnique(db *gorm.DB, username, email string) error {
 	var existingUser models.User
-	result := database.DB.Where("username = ?", req.Username).First(&existingUser)
+	result := db.Unscoped().Where("username = ?", username).First(&existingUser)
 	if result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-		return
+		return errUsernameExists
 	} else if result.Error != gorm.ErrRecordNotFound {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check username"})
-		return
+		return errors.New("Failed to check username")
 	}
 
-	// Cek apakah email sudah ada
-	result = database.DB.Where("email = ?", req.Email).First(&existingUser)
+	result = db.Unscoped().Where("email = ?", email).First(&existingUser)
 	if result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
-		return
+		return errEmailExists
 	} else if result.Error != gorm.ErrRecordNotFound {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email"})
+		return errors.New("Failed to check email")
+	}
+
+	return nil
+}
+
+// Fungsi helper untuk register user
+func registerUser(c *gin.Context, userType models.UserType) {
+	var req RegisterUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Cek apakah username dan email sudah ada
+	if err := checkUserUnique(database.WithContext(c.Request.Context()), req.Username, req.Email); err != nil {
+		if err == errUsernameExists || err == errEmailExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -91,7 +114,7 @@ func registerUser(c *gin.Context, userType models.UserType) {
 	}
 
 	// Save to database dalam transaction
-	tx := database.DB.Begin()
+	tx := database.WithContext(c.Request.Context()).Begin()
 	if tx.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
@@ -103,6 +126,18 @@ func registerUser(c *gin.Context, userType models.UserType) {
 		return
 	}
 
+	if err := recordAudit(tx, c, audit.ActionCreate, audit.ResourceUser, user.ID, nil, user); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	if err := webhook.Enqueue(tx, webhook.UserCreated, webhook.UserData(user)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
@@ -149,7 +184,8 @@ func RegisterDriver(c *gin.Context) {
 }
 
 // @Summary      Login user
-// @Description  Login with username/email and password to get JWT token
+// @Description  Login with username/email and password to get JWT token.
+// @Description  With DRIVER_VERIFICATION_REQUIRED=true, drivers that are not verified yet can only use /me endpoints; limited_access explains why.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -157,6 +193,7 @@ func RegisterDriver(c *gin.Context) {
 // @Success      200  {object}  AuthResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /login [post]
 func Login(c *gin.Context) {
@@ -168,9 +205,9 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Find user dengan roles - cari berdasarkan username ATAU email
+	// Find user - cari berdasarkan username ATAU email
 	var user models.User
-	result := database.DB.Preload("Roles").Where("username = ? OR email = ?", req.Login, req.Login).First(&user)
+	result := database.WithContext(c.Request.Context()).Where("username = ? OR email = ?", req.Login, req.Login).First(&user)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			// Log failed login attempt - user not found
@@ -193,10 +230,45 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Get user roles
-	var roleNames []string
-	for _, role := range user.Roles {
-		roleNames = append(roleNames, role.Name)
+	// Masa suspend sudah lewat, aktifkan kembali secara otomatis
+	if user.Status == models.UserStatusSuspended && user.SuspendedUntil != nil && !user.SuspendedUntil.After(time.Now()) {
+		err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			return transitionUserStatus(tx, &user, models.UserStatusActive, "Suspension period ended", nil, nil)
+		})
+		if err != nil {
+			saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusFailed, "Failed to lift suspension")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
+			return
+		}
+	}
+
+	// Check user status
+	switch user.Status {
+	case models.UserStatusInactive:
+		saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusFailed, "User is inactive")
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is inactive"})
+		return
+	case models.UserStatusSuspended:
+		saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusFailed, "User is suspended")
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is suspended"})
+		return
+	}
+
+	// Driver yang belum diverifikasi tetap bisa login, tapi hanya boleh memakai /me
+	// jika DRIVER_VERIFICATION_REQUIRED=true (lihat DriverVerificationMiddleware)
+	denial, err := middleware.DriverAccessDenial(database.WithContext(c.Request.Context()), user)
+	if err != nil {
+		saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusFailed, "Failed to check driver profile")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check driver profile"})
+		return
+	}
+
+	// Get user roles, hanya assignment yang masih berlaku
+	roleNames, err := database.ActiveRoleNames(database.WithContext(c.Request.Context()), user.ID)
+	if err != nil {
+		saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusFailed, "Failed to resolve roles")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve user roles"})
+		return
 	}
 
 	// Generate token
@@ -211,7 +283,7 @@ func Login(c *gin.Context) {
 	// Save token to database
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
-	if err := middleware.SaveTokenToDB(user.ID.String(), token, ipAddress, userAgent); err != nil {
+	if err := middleware.SaveTokenToDB(c.Request.Context(), user.ID.String(), token, ipAddress, userAgent); err != nil {
 		// Log failed login attempt - token save error
 		saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusFailed, "Failed to save token")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save token"})
@@ -221,7 +293,11 @@ func Login(c *gin.Context) {
 	// Log successful login
 	saveLoginLog(c, user.ID.String(), user.Username, user.Email, models.LoginStatusSuccess, "Login successful")
 
-	c.JSON(http.StatusOK, AuthResponse{Token: token})
+	response := AuthResponse{Token: token}
+	if denial != "" {
+		response.LimitedAccess = denial + ", only /me endpoints are available until the profile is verified"
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // Helper function untuk save login log
@@ -251,10 +327,39 @@ func saveLoginLog(c *gin.Context, userID, username, email, status, message strin
 		}
 	}
 
-	// Save to database (non-blocking, jangan sampai mengganggu login process)
+	// Save to database (non-blocking, jangan sampai mengganggu login process).
+	// Query tidak ikut dibatalkan saat request selesai, tapi log-nya tetap membawa request_id
+	ctx := context.WithoutCancel(c.Request.Context())
+	requestID := c.GetString("request_id")
 	go func() {
-		if err := database.DB.Create(&loginLog).Error; err != nil {
-			fmt.Printf("Failed to save login log: %v\n", err)
+		err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := hashchain.LoginLogs.Append(tx, &loginLog); err != nil {
+				return err
+			}
+			if status == models.LoginStatusSuccess {
+				return webhook.Enqueue(tx, webhook.SessionStarted, webhook.Session{
+					UserID:    loginLog.UserID,
+					Username:  loginLog.Username,
+					IPAddress: loginLog.IPAddress,
+					UserAgent: loginLog.UserAgent,
+				})
+			}
+
+			event := events.Event{
+				Type:      events.TypeLoginFailed,
+				Time:      loginLog.LoginTime,
+				Username:  loginLog.Username,
+				IPAddress: loginLog.IPAddress,
+				RequestID: requestID,
+				Data:      map[string]interface{}{"message": message, "login_log_id": loginLog.ID},
+			}
+			if loginLog.UserID != uuid.Nil {
+				event.UserID = &loginLog.UserID
+			}
+			return events.Publish(tx, event)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to save login log", "username", loginLog.Username, "error", err)
 		}
 	}()
 }
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/models"
+	"sjek/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DocumentResponse struct {
+	models.Document
+	DownloadURL       string    `json:"download_url"`
+	DownloadExpiresAt time.Time `json:"download_expires_at"`
+}
+
+type ReviewDocumentRequest struct {
+	Status models.DocumentStatus `json:"status" binding:"required,oneof=APPROVED REJECTED"`
+	Note   string                `json:"note,omitempty"`
+}
+
+// MIME type yang diterima, dideteksi dari isi file bukan dari header request
+var allowedDocumentMIME = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+// documentMaxSize returns the upload limit in bytes from DOCUMENT_MAX_SIZE, default 5 MB
+func documentMaxSize() int64 {
+	if value := os.Getenv("DOCUMENT_MAX_SIZE"); value != "" {
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+			return size
+		}
+	}
+	return 5 << 20
+}
+
+func buildDocumentResponse(document models.Document, signer storage.URLSigner) DocumentResponse {
+	expires, signature := signer.Sign(document.ID.String())
+	return DocumentResponse{
+		Document:          document,
+		DownloadURL:       fmt.Sprintf("/documents/%s/download?expires=%d&signature=%s", document.ID, expires, signature),
+		DownloadExpiresAt: time.Unix(expires, 0),
+	}
+}
+
+// storeDocument validates the multipart upload, writes it to storage and saves the metadata
+func storeDocument(c *gin.Context, store storage.Storage, signer storage.URLSigner, userID uuid.UUID) {
+	maxSize := documentMaxSize()
+	// Sisakan ruang untuk field multipart lain selain file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	// Form di-parse lebih dulu agar body yang melebihi batas dilaporkan sebagai 413, bukan
+	// sebagai field type atau file yang hilang
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds maximum size of %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+
+	docType := models.DocumentType(strings.ToUpper(c.PostForm("type")))
+	if !models.IsValidDocumentType(docType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document type, use KTP, SIM or STNK"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds maximum size of %d bytes", maxSize)})
+		return
+	}
+
+	var user models.User
+	if err := database.WithContext(c.Request.Context()).Select("id").First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !allowedDocumentMIME[contentType] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("File type %s is not allowed, use JPEG, PNG or PDF", contentType)})
+		return
+	}
+
+	document := models.Document{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Type:        docType,
+		FileName:    filepath.Base(fileHeader.Filename),
+		ContentType: contentType,
+		Size:        fileHeader.Size,
+		Status:      models.DocumentStatusPending,
+	}
+	document.StorageKey = fmt.Sprintf("documents/%s/%s", userID, document.ID)
+	if uploader, exists := c.Get("user_id"); exists {
+		if uploaderID, err := uuid.Parse(uploader.(string)); err == nil {
+			document.UploadedBy = &uploaderID
+		}
+	}
+
+	hasher := sha256.New()
+	reader := io.TeeReader(io.MultiReader(bytes.NewReader(head), file), hasher)
+	if err := store.Put(c.Request.Context(), document.StorageKey, reader, fileHeader.Size, contentType); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to store document", "document_id", document.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+	document.Checksum = hex.EncodeToString(hasher.Sum(nil))
+
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&document).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionCreate, audit.ResourceDocument, document.ID, nil, document)
+	})
+	if err != nil {
+		// Metadata gagal disimpan, jangan tinggalkan file yatim di storage
+		if delErr := store.Delete(c.Request.Context(), document.StorageKey); delErr != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to remove orphan document", "storage_key", document.StorageKey, "error", delErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildDocumentResponse(document, signer))
+}
+
+func respondUserDocuments(c *gin.Context, signer storage.URLSigner, userID uuid.UUID) {
+	query := database.WithContext(c.Request.Context()).Where("user_id = ?", userID)
+	if docType := c.Query("type"); docType != "" {
+		query = query.Where("type = ?", strings.ToUpper(docType))
+	}
+
+	var documents []models.Document
+	if err := query.Order("created_at DESC").Find(&documents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+		return
+	}
+
+	response := []DocumentResponse{}
+	for _, document := range documents {
+		response = append(response, buildDocumentResponse(document, signer))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Upload user document
+// @Description  Upload a KYC document (KTP, SIM or STNK) for a user. Only JPEG, PNG and PDF up to DOCUMENT_MAX_SIZE bytes are accepted.
+// @Tags         documents
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string  true  "User ID"
+// @Param        type  formData  string  true  "Document type (KTP/SIM/STNK)"
+// @Param        file  formData  file    true  "Document file"
+// @Success      201  {object}  DocumentResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      413  {object}  ErrorResponse
+// @Failure      415  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/documents [post]
+func UploadDocument(store storage.Storage, signer storage.URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		storeDocument(c, store, signer, userID)
+	}
+}
+
+// @Summary      Get user documents
+// @Description  List documents of a user with signed download URLs
+// @Tags         documents
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path   string  true   "User ID"
+// @Param        type  query  string  false  "Filter by document type (KTP/SIM/STNK)"
+// @Success      200  {array}   DocumentResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/documents [get]
+func GetUserDocuments(signer storage.URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		respondUserDocuments(c, signer, userID)
+	}
+}
+
+// @Summary      Upload current user document
+// @Description  Upload a KYC document (KTP, SIM or STNK) for the authenticated user
+// @Tags         me
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        type  formData  string  true  "Document type (KTP/SIM/STNK)"
+// @Param        file  formData  file    true  "Document file"
+// @Success      201  {object}  DocumentResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      413  {object}  ErrorResponse
+// @Failure      415  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /me/documents [post]
+func UploadMyDocument(store storage.Storage, signer storage.URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		storeDocument(c, store, signer, userID)
+	}
+}
+
+// @Summary      Get current user documents
+// @Description  List documents of the authenticated user with signed download URLs
+// @Tags         me
+// @Produce      json
+// @Security     BearerAuth
+// @Param        type  query  string  false  "Filter by document type (KTP/SIM/STNK)"
+// @Success      200  {array}   DocumentResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /me/documents [get]
+func GetMyDocuments(signer storage.URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		respondUserDocuments(c, signer, userID)
+	}
+}
+
+// @Summary      Get document
+// @Description  Get document metadata with a fresh signed download URL
+// @Tags         documents
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {object}  DocumentResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /documents/{id} [get]
+func GetDocument(signer storage.URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+			return
+		}
+
+		var document models.Document
+		if err := database.WithContext(c.Request.Context()).First(&document, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, buildDocumentResponse(document, signer))
+	}
+}
+
+// @Summary      Review document
+// @Description  Approve or reject a document. A note is required when rejecting.
+// @Tags         documents
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string                 true  "Document ID"
+// @Param        request body  ReviewDocumentRequest  true  "Review result"
+// @Success      200  {object}  models.Document
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /documents/{id}/review [post]
+func ReviewDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req ReviewDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status == models.DocumentStatusRejected && req.Note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Note is required when rejecting a document"})
+		return
+	}
+
+	var document models.Document
+	if err := database.WithContext(c.Request.Context()).First(&document, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	reviewerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	before := document
+	now := time.Now()
+	document.Status = req.Status
+	document.ReviewNote = req.Note
+	document.ReviewedBy = &reviewerID
+	document.ReviewedAt = &now
+
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&document).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceDocument, document.ID, before, document)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// @Summary      Delete document
+// @Description  Delete document metadata and the stored file
+// @Tags         documents
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /documents/{id} [delete]
+func DeleteDocument(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+			return
+		}
+
+		var document models.Document
+		if err := database.WithContext(c.Request.Context()).First(&document, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+
+		err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&document).Error; err != nil {
+				return err
+			}
+			return recordAudit(tx, c, audit.ActionDelete, audit.ResourceDocument, document.ID, document, nil)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+			return
+		}
+
+		// Metadata sudah terhapus, file yang gagal dihapus cukup dicatat
+		if err := store.Delete(c.Request.Context(), document.StorageKey); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to delete stored document", "storage_key", document.StorageKey, "error", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
+	}
+}
+
+// @Summary      Download document
+// @Description  Download document content using a signed URL from the document endpoints. The URL expires after DOCUMENT_URL_TTL.
+// @Tags         documents
+// @Produce      application/octet-stream
+// @Param        id         path   string  true  "Document ID"
+// @Param        expires    query  int     true  "Expiry unix timestamp"
+// @Param        signature  query  string  true  "URL signature"
+// @Success      200  {file}    file
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /documents/{id}/download [get]
+func DownloadDocument(store storage.Storage, signer storage.URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !signer.Verify(c.Param("id"), c.Query("expires"), c.Query("signature")) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired download URL"})
+			return
+		}
+
+		var document models.Document
+		if err := database.WithContext(c.Request.Context()).First(&document, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+
+		reader, err := store.Get(c.Request.Context(), document.StorageKey)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Document file not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document"})
+			}
+			return
+		}
+		defer reader.Close()
+
+		c.Header("Cache-Control", "private, no-store")
+		c.DataFromReader(http.StatusOK, document.Size, document.ContentType, reader, map[string]string{
+			"Content-Disposition": fmt.Sprintf("inline; filename=%q", document.FileName),
+		})
+	}
+}
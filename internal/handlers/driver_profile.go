@@ -0,0 +1,547 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/listquery"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DriverProfileRequest struct {
+	LicenseNumber string `json:"license_number" binding:"required"`
+	LicenseExpiry string `json:"license_expiry" binding:"required"` // YYYY-MM-DD
+	VehiclePlate  string `json:"vehicle_plate" binding:"required"`
+	VehicleType   string `json:"vehicle_type" binding:"required"`
+	VehicleBrand  string `json:"vehicle_brand,omitempty"`
+}
+
+type RejectDriverProfileRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+var (
+	errNotDriver             = errors.New("user is not a driver")
+	errDriverProfileExists   = errors.New("driver profile already exists")
+	errDriverProfileNotFound = errors.New("driver profile not found")
+	errDriverProfileChanged  = errors.New("driver profile was changed while it was reviewed, reload and review again")
+	errPlateExists           = errors.New("vehicle plate already registered to another driver")
+	errInvalidLicenseExpiry  = errors.New("invalid license_expiry, expected YYYY-MM-DD")
+	errLicenseExpired        = errors.New("driver license has expired")
+)
+
+// applyDriverProfileRequest validates the request and copies normalized values to the profile.
+// Perubahan data SIM atau kendaraan membuat profile harus diverifikasi ulang
+func applyDriverProfileRequest(profile *models.DriverProfile, req DriverProfileRequest) error {
+	licenseNumber, err := models.NormalizeLicenseNumber(req.LicenseNumber)
+	if err != nil {
+		return err
+	}
+	plate, err := models.NormalizePlate(req.VehiclePlate)
+	if err != nil {
+		return err
+	}
+	vehicleType, err := models.ParseVehicleType(req.VehicleType)
+	if err != nil {
+		return err
+	}
+	expiry, err := time.Parse("2006-01-02", req.LicenseExpiry)
+	if err != nil {
+		return errInvalidLicenseExpiry
+	}
+
+	changed := profile.LicenseNumber != licenseNumber ||
+		!profile.LicenseExpiry.Equal(expiry) ||
+		profile.VehiclePlate != plate ||
+		profile.VehicleType != vehicleType ||
+		profile.VehicleBrand != req.VehicleBrand
+
+	profile.LicenseNumber = licenseNumber
+	profile.LicenseExpiry = expiry
+	profile.VehiclePlate = plate
+	profile.VehicleType = vehicleType
+	profile.VehicleBrand = req.VehicleBrand
+
+	if !profile.IsLicenseValidAt(time.Now()) {
+		return errLicenseExpired
+	}
+
+	if changed && profile.VerificationStatus != "" && profile.VerificationStatus != models.VerificationPending {
+		profile.VerificationStatus = models.VerificationPending
+		profile.VerifiedBy = nil
+		profile.VerifiedAt = nil
+		profile.RejectionReason = ""
+	}
+	return nil
+}
+
+// saveDriverProfile creates or updates the driver profile of a user.
+// create=true gagal jika profile sudah ada, create=false gagal jika belum ada
+func saveDriverProfile(c *gin.Context, userID uuid.UUID, req DriverProfileRequest, create, upsert bool) (models.DriverProfile, int, error) {
+	var profile models.DriverProfile
+
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if user.Type != models.UserTypeDriver {
+			return errNotDriver
+		}
+
+		var before *models.DriverProfile
+		err := tx.Where("user_id = ?", userID).First(&profile).Error
+		switch {
+		case err == nil:
+			if create && !upsert {
+				return errDriverProfileExists
+			}
+			existing := profile
+			before = &existing
+		case err == gorm.ErrRecordNotFound:
+			if !create && !upsert {
+				return errDriverProfileNotFound
+			}
+			profile = models.DriverProfile{
+				ID:                 uuid.New(),
+				UserID:             userID,
+				VerificationStatus: models.VerificationPending,
+			}
+		default:
+			return err
+		}
+
+		if err := applyDriverProfileRequest(&profile, req); err != nil {
+			return err
+		}
+
+		var plateCount int64
+		if err := tx.Model(&models.DriverProfile{}).
+			Where("vehicle_plate = ? AND user_id <> ?", profile.VehiclePlate, userID).
+			Count(&plateCount).Error; err != nil {
+			return err
+		}
+		if plateCount > 0 {
+			return errPlateExists
+		}
+
+		if err := tx.Save(&profile).Error; err != nil {
+			return err
+		}
+
+		action := audit.ActionUpdate
+		if before == nil {
+			action = audit.ActionCreate
+		}
+		return recordAudit(tx, c, action, audit.ResourceDriverProfile, userID, before, profile)
+	})
+
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			return profile, http.StatusNotFound, errors.New("user not found")
+		case errDriverProfileNotFound:
+			return profile, http.StatusNotFound, err
+		case errDriverProfileExists, errPlateExists:
+			return profile, http.StatusConflict, err
+		case errNotDriver, errInvalidLicenseExpiry, errLicenseExpired, models.ErrInvalidPlate, models.ErrInvalidLicenseNumber, models.ErrInvalidVehicleType:
+			return profile, http.StatusBadRequest, err
+		}
+		return profile, http.StatusInternalServerError, errors.New("failed to save driver profile")
+	}
+
+	return profile, http.StatusOK, nil
+}
+
+// driverProfileListFields is the sort/filter whitelist for driver profile list endpoint
+var driverProfileListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":                  {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"user_id":             {Column: "user_id", Type: listquery.UUID, Filter: true},
+	"vehicle_plate":       {Column: "vehicle_plate", Type: listquery.String, Filter: true, Sortable: true},
+	"vehicle_type":        {Column: "vehicle_type", Type: listquery.String, Filter: true, Sortable: true},
+	"vehicle_brand":       {Column: "vehicle_brand", Type: listquery.String, Filter: true, Sortable: true},
+	"license_expiry":      {Column: "license_expiry", Type: listquery.Time, Filter: true, Sortable: true},
+	"verification_status": {Column: "verification_status", Type: listquery.String, Filter: true, Sortable: true},
+	"verified_at":         {Column: "verified_at", Type: listquery.Time, Filter: true, Sortable: true, Nullable: true},
+	"created_at":          {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"updated_at":          {Column: "updated_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
+// @Summary      Get driver profiles
+// @Description  Get driver profiles with pagination, filter by verification status for the review queue.
+// @Description  Supports sort=license_expiry and filters like license_expiry[lte]=2025-12-31.
+// @Description  Send pagination=cursor, after or before for keyset pagination; the response is then models.CursorPaginatedResponse.
+// @Tags         driver-profiles
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page                 query     int     false  "Page number (default: 1)"
+// @Param        limit                query     int     false  "Items per page (default: 10, max: 100)"
+// @Param        verification_status  query     string  false  "Filter by verification status (PENDING/VERIFIED/REJECTED)"
+// @Param        vehicle_type         query     string  false  "Filter by vehicle type (MOTORCYCLE/CAR)"
+// @Param        sort                 query     string  false  "Sort fields, prefix - for descending (default: created_at)"
+// @Param        pagination           query     string  false  "Set to cursor for keyset pagination"
+// @Param        after                query     string  false  "Cursor for the next page"
+// @Param        before               query     string  false  "Cursor for the previous page"
+// @Param        with_total           query     bool    false  "Count total rows in cursor mode"
+// @Success      200  {object}  models.PaginatedResponse{data=[]models.DriverProfile}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /driver-profiles [get]
+func GetDriverProfiles(c *gin.Context) {
+	var pagination models.Pagination
+	pagination.Page = 1
+	pagination.Limit = 10
+
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.Limit < 1 {
+		pagination.Limit = 10
+	}
+	if pagination.Limit > 100 {
+		pagination.Limit = 100
+	}
+	pagination.Offset = (pagination.Page - 1) * pagination.Limit
+
+	listQuery, ok := bindListQuery(c, driverProfileListFields)
+	if !ok {
+		return
+	}
+
+	query := listQuery.ApplyFilters(database.WithContext(c.Request.Context()).Model(&models.DriverProfile{}))
+	if status := c.Query("verification_status"); status != "" {
+		query = query.Where("verification_status = ?", status)
+	}
+	if vehicleType := c.Query("vehicle_type"); vehicleType != "" {
+		query = query.Where("vehicle_type = ?", vehicleType)
+	}
+
+	keyset, ok := bindKeyset(c, driverProfileListFields, listQuery, "created_at", pagination.Limit)
+	if !ok {
+		return
+	}
+	if keyset != nil {
+		var profiles []models.DriverProfile
+		cursorPagination, err := findCursorPage(c, keyset, query, &profiles)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch driver profiles"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+			Data:       profiles,
+			Pagination: cursorPagination,
+		})
+		return
+	}
+
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count driver profiles"})
+		return
+	}
+
+	var profiles []models.DriverProfile
+	if err := listQuery.ApplySort(query, "created_at ASC").Offset(pagination.Offset).Limit(pagination.Limit).Find(&profiles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch driver profiles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       profiles,
+		Pagination: pagination,
+	})
+}
+
+// @Summary      Get driver profile
+// @Description  Get driver profile of a user
+// @Tags         driver-profiles
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.DriverProfile
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /users/{id}/driver-profile [get]
+func GetDriverProfile(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	respondDriverProfile(c, userID)
+}
+
+func respondDriverProfile(c *gin.Context, userID uuid.UUID) {
+	var profile models.DriverProfile
+	if err := database.WithContext(c.Request.Context()).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver profile not found"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary      Create driver profile
+// @Description  Create driver profile with license and vehicle data. The profile starts as PENDING verification.
+// @Tags         driver-profiles
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string                true  "User ID"
+// @Param        request body  DriverProfileRequest  true  "Driver profile"
+// @Success      201  {object}  models.DriverProfile
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /users/{id}/driver-profile [post]
+func CreateDriverProfile(c *gin.Context) {
+	writeDriverProfile(c, true)
+}
+
+// @Summary      Update driver profile
+// @Description  Update driver profile. Changing license or vehicle data resets verification to PENDING.
+// @Tags         driver-profiles
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string                true  "User ID"
+// @Param        request body  DriverProfileRequest  true  "Driver profile"
+// @Success      200  {object}  models.DriverProfile
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /users/{id}/driver-profile [put]
+func UpdateDriverProfile(c *gin.Context) {
+	writeDriverProfile(c, false)
+}
+
+func writeDriverProfile(c *gin.Context, create bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req DriverProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, status, err := saveDriverProfile(c, userID, req, create, false)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if create {
+		status = http.StatusCreated
+	}
+	c.JSON(status, profile)
+}
+
+// @Summary      Delete driver profile
+// @Description  Delete driver profile of a user
+// @Tags         driver-profiles
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/driver-profile [delete]
+func DeleteDriverProfile(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var profile models.DriverProfile
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Returning{}).Where("user_id = ?", userID).Delete(&profile)
+		if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		return recordAudit(tx, c, audit.ActionDelete, audit.ResourceDriverProfile, userID, profile, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete driver profile"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver profile not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Driver profile deleted successfully"})
+}
+
+// changeVerificationStatus moves a driver profile through the verification state machine
+func changeVerificationStatus(c *gin.Context, to models.VerificationStatus, reason string) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var reviewer *uuid.UUID
+	if actorID, exists := c.Get("user_id"); exists {
+		if actorUUID, err := uuid.Parse(actorID.(string)); err == nil {
+			reviewer = &actorUUID
+		}
+	}
+
+	var profile models.DriverProfile
+	if err := database.WithContext(c.Request.Context()).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver profile not found"})
+		return
+	}
+
+	if !profile.VerificationStatus.CanTransition(to) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot change verification status from %s to %s", profile.VerificationStatus, to)})
+		return
+	}
+
+	if to == models.VerificationVerified && !profile.IsLicenseValidAt(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Driver license has expired"})
+		return
+	}
+
+	// Hanya kolom review yang diubah, dan hanya jika profile belum diedit sejak dibaca. Edit plat
+	// atau SIM oleh driver mengembalikan status ke PENDING dan tidak boleh ikut tertandai VERIFIED
+	before := profile
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.DriverProfile{}).
+			Where("user_id = ? AND verification_status = ? AND updated_at = ?", userID, before.VerificationStatus, before.UpdatedAt).
+			Updates(map[string]interface{}{
+				"verification_status": to,
+				"verified_by":         reviewer,
+				"verified_at":         time.Now(),
+				"rejection_reason":    reason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errDriverProfileChanged
+		}
+		if err := tx.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceDriverProfile, userID, before, profile)
+	})
+	if err != nil {
+		if err == errDriverProfileChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update driver profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary      Verify driver profile
+// @Description  Mark a PENDING driver profile as VERIFIED
+// @Tags         driver-profiles
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.DriverProfile
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /users/{id}/driver-profile/verify [post]
+func VerifyDriverProfile(c *gin.Context) {
+	changeVerificationStatus(c, models.VerificationVerified, "")
+}
+
+// @Summary      Reject driver profile
+// @Description  Reject a driver profile with a reason
+// @Tags         driver-profiles
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string                      true  "User ID"
+// @Param        request body  RejectDriverProfileRequest  true  "Rejection reason"
+// @Success      200  {object}  models.DriverProfile
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /users/{id}/driver-profile/reject [post]
+func RejectDriverProfile(c *gin.Context) {
+	var req RejectDriverProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changeVerificationStatus(c, models.VerificationRejected, req.Reason)
+}
+
+// @Summary      Get current driver profile
+// @Description  Get driver profile of the authenticated driver
+// @Tags         me
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.DriverProfile
+// @Failure      401  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /me/driver-profile [get]
+func GetMyDriverProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	respondDriverProfile(c, userID)
+}
+
+// @Summary      Save current driver profile
+// @Description  Create or update driver profile of the authenticated driver. Changes reset verification to PENDING.
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body  DriverProfileRequest  true  "Driver profile"
+// @Success      200  {object}  models.DriverProfile
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /me/driver-profile [put]
+func SaveMyDriverProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req DriverProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, status, err := saveDriverProfile(c, userID, req, false, true)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// resourceETag builds a strong ETag from updated_at (presisi mikrodetik seperti di Postgres)
+func resourceETag(updatedAt time.Time) string {
+	return fmt.Sprintf(`"%x"`, updatedAt.UnixMicro())
+}
+
+func setETag(c *gin.Context, updatedAt time.Time) {
+	c.Header("ETag", resourceETag(updatedAt))
+}
+
+// checkIfMatch validates the If-Match header against the current resource version.
+// Tanpa header request tetap diterima, kecuali REQUIRE_IF_MATCH=true (428)
+func checkIfMatch(c *gin.Context, updatedAt time.Time) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if os.Getenv("REQUIRE_IF_MATCH") == "true" {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+			return false
+		}
+		return true
+	}
+
+	current := resourceETag(updatedAt)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+
+	respondPreconditionFailed(c, updatedAt)
+	return false
+}
+
+func respondPreconditionFailed(c *gin.Context, updatedAt time.Time) {
+	setETag(c, updatedAt)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Resource has been modified by another request, reload and retry"})
+}
+
+// versionedDB truncates auto updated_at to microseconds so the ETag returned after
+// a write equals the value Postgres stores
+func versionedDB(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NowFunc: func() time.Time {
+		return time.Now().Truncate(time.Microsecond)
+	}})
+}
+
+// saveIfUnmodified writes model only when updated_at still equals since. Tanpa columns
+// semua kolom ditulis (PUT), dengan columns hanya kolom tersebut dan updated_at (PATCH).
+// False tanpa error berarti ada request lain yang sudah mengubah resource,
+// updated_at di model diisi ulang dengan versi terbaru untuk ETag di response 412
+func saveIfUnmodified(db *gorm.DB, model interface{}, since time.Time, columns ...string) (bool, error) {
+	query := versionedDB(db).Model(model).Where("updated_at = ?", since)
+	if len(columns) > 0 {
+		query = query.Select(columns)
+	} else {
+		query = query.Select("*").Omit(clause.Associations, "created_at")
+	}
+
+	result := query.Updates(model)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		db.Select("updated_at").First(model)
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"sjek/internal/database"
+	"sjek/internal/models"
+	"sjek/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type userExportRow struct {
+	ID            uuid.UUID
+	Username      string
+	Email         string
+	Type          string
+	Status        string
+	ActivatedDate *time.Time
+	InactiveDate  *time.Time
+	CreatedAt     time.Time
+	DeletedAt     *time.Time
+	RoleNames     string
+}
+
+// streamExport runs the query with a database cursor and writes every row
+// to the response, sehingga hasil tidak pernah dimuat seluruhnya ke memory
+func streamExport(c *gin.Context, name string, header []interface{}, query *gorm.DB, scan func(rows *sql.Rows) ([]interface{}, error)) {
+	format := c.DefaultQuery("format", spreadsheet.FormatCSV)
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, use csv or xlsx"})
+		return
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export " + name})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Type", spreadsheet.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	writer, err := spreadsheet.NewWriter(format, c.Writer, name)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to create export writer", "export", name, "error", err)
+		return
+	}
+
+	if err := writer.WriteRow(header); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to write export", "export", name, "error", err)
+		return
+	}
+
+	for rows.Next() {
+		values, err := scan(rows)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to scan export row", "export", name, "error", err)
+			return
+		}
+		if err := writer.WriteRow(values); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to write export", "export", name, "error", err)
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to finish export", "export", name, "error", err)
+	}
+}
+
+// @Summary      Export users
+// @Description  Stream all users matching the list filters as CSV or XLSX. The roles column lists roles active right now,
+// @Description  assigned directly or through a group. Text cells starting with =, +, - or @ are prefixed with a single quote.
+// @Tags         users
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security     BearerAuth
+// @Param        format    query     string  false  "Export format (csv/xlsx, default: csv)"
+// @Param        q         query     string  false  "Search username, email, vehicle plate and license number"
+// @Param        sort      query     string  false  "Sort fields, prefix - for descending (e.g. -created_at,username)"
+// @Param        type      query     string  false  "Filter by user type (ADMIN/DRIVER)"
+// @Param        status    query     string  false  "Filter by status (ACTIVE/INACTIVE/SUSPENDED)"
+// @Param        email     query     string  false  "Filter by email contains"
+// @Param        username  query     string  false  "Filter by username contains"
+// @Param        from_date query     string  false  "Filter created from date (YYYY-MM-DD)"
+// @Param        to_date   query     string  false  "Filter created to date (YYYY-MM-DD)"
+// @Param        deleted   query     bool    false  "Export soft deleted users instead of active ones"
+// @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/export [get]
+func ExportUsers(c *gin.Context) {
+	listQuery, ok := bindListQuery(c, userListFields)
+	if !ok {
+		return
+	}
+
+	// Kolom roles memakai aturan yang sama dengan ActiveRoleNames: assignment langsung yang
+	// masih berlaku ditambah role dari group
+	roleNames := database.WithContext(c.Request.Context()).Table("roles").
+		Select("string_agg(roles.name, ';' ORDER BY roles.name)").
+		Scopes(database.ActiveRoles(database.WithContext(c.Request.Context()), gorm.Expr("users.id"), time.Now()))
+	query := listQuery.ApplyFilters(applyUserFilters(c, database.WithContext(c.Request.Context()).Model(&models.User{}))).
+		Select("users.id, users.username, users.email, users.type, users.status, users.activated_date, users.inactive_date, users.created_at, users.deleted_at, "+
+			"(?) AS role_names", roleNames)
+	query = listQuery.ApplySort(query, "users.created_at ASC").Order("users.id ASC")
+
+	header := []interface{}{"id", "username", "email", "type", "status", "roles", "activated_date", "inactive_date", "created_at", "deleted_at"}
+	streamExport(c, "users", header, query, func(rows *sql.Rows) ([]interface{}, error) {
+		var row userExportRow
+		if err := query.ScanRows(rows, &row); err != nil {
+			return nil, err
+		}
+		return []interface{}{row.ID.String(), row.Username, row.Email, row.Type, row.Status, row.RoleNames,
+			row.ActivatedDate, row.InactiveDate, row.CreatedAt, row.DeletedAt}, nil
+	})
+}
+
+// @Summary      Export login logs
+// @Description  Stream all login logs matching the list filters as CSV or XLSX
+// @Tags         login-logs
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security     BearerAuth
+// @Param        format    query     string  false  "Export format (csv/xlsx, default: csv)"
+// @Param        sort      query     string  false  "Sort fields, prefix - for descending (e.g. -login_time)"
+// @Param        status    query     string  false  "Filter by status (SUCCESS/FAILED)"
+// @Param        username  query     string  false  "Filter by username contains"
+// @Param        from_date query     string  false  "Filter from date (YYYY-MM-DD)"
+// @Param        to_date   query     string  false  "Filter to date (YYYY-MM-DD)"
+// @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /login-logs/export [get]
+func ExportLoginLogs(c *gin.Context) {
+	listQuery, ok := bindListQuery(c, loginLogListFields)
+	if !ok {
+		return
+	}
+
+	query := listQuery.ApplyFilters(applyLoginLogFilters(c, database.WithContext(c.Request.Context()).Model(&models.LoginLog{})))
+	query = listQuery.ApplySort(query, "login_time ASC").Order("id ASC")
+
+	header := []interface{}{"id", "user_id", "username", "email", "ip_address", "user_agent", "login_time", "status", "message"}
+	streamExport(c, "login-logs", header, query, func(rows *sql.Rows) ([]interface{}, error) {
+		var entry models.LoginLog
+		if err := query.ScanRows(rows, &entry); err != nil {
+			return nil, err
+		}
+		return []interface{}{entry.ID.String(), entry.UserID.String(), entry.Username, entry.Email, entry.IPAddress,
+			entry.UserAgent, entry.LoginTime, entry.Status, entry.Message}, nil
+	})
+}
+
+// @Summary      Export tokens
+// @Description  Stream token metadata (without token values) as CSV or XLSX
+// @Tags         tokens
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security     BearerAuth
+// @Param        format    query     string  false  "Export format (csv/xlsx, default: csv)"
+// @Param        sort      query     string  false  "Sort fields, prefix - for descending (e.g. -created_at)"
+// @Param        user_id   query     string  false  "Filter by user ID"
+// @Param        is_active query     bool    false  "Filter by active flag"
+// @Param        from_date query     string  false  "Filter created from date (YYYY-MM-DD)"
+// @Param        to_date   query     string  false  "Filter created to date (YYYY-MM-DD)"
+// @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tokens/export [get]
+func ExportTokens(c *gin.Context) {
+	listQuery, ok := bindListQuery(c, tokenListFields)
+	if !ok {
+		return
+	}
+
+	query := database.WithContext(c.Request.Context()).Model(&models.UserToken{}).
+		Select("id", "user_id", "ip_address", "user_agent", "expires_at", "is_active", "created_at")
+
+	if userIDParam := c.Query("user_id"); userIDParam != "" {
+		userID, err := uuid.Parse(userIDParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if isActive := c.Query("is_active"); isActive != "" {
+		query = query.Where("is_active = ?", isActive == "true")
+	}
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		query = query.Where("created_at >= ?", fromDate+" 00:00:00")
+	}
+	if toDate := c.Query("to_date"); toDate != "" {
+		query = query.Where("created_at <= ?", toDate+" 23:59:59")
+	}
+	query = listQuery.ApplySort(listQuery.ApplyFilters(query), "created_at ASC").Order("id ASC")
+
+	header := []interface{}{"id", "user_id", "ip_address", "user_agent", "expires_at", "is_active", "created_at"}
+	streamExport(c, "tokens", header, query, func(rows *sql.Rows) ([]interface{}, error) {
+		var token models.UserToken
+		if err := query.ScanRows(rows, &token); err != nil {
+			return nil, err
+		}
+		return []interface{}{token.ID.String(), token.UserID.String(), token.IPAddress, token.UserAgent,
+			token.ExpiresAt, token.IsActive, token.CreatedAt}, nil
+	})
+}
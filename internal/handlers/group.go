@@ -0,0 +1,721 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/listquery"
+	"sjek/internal/models"
+	"sjek/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type GroupResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MemberCount int64     `json:"member_count"`
+	Roles       []string  `json:"roles"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AddGroupMembersRequest menambahkan banyak user sekaligus, user yang sudah anggota diabaikan
+type AddGroupMembersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1"`
+}
+
+type GroupMemberResponse struct {
+	UserID   uuid.UUID         `json:"user_id"`
+	Username string            `json:"username"`
+	Email    string            `json:"email"`
+	Type     models.UserType   `json:"type"`
+	Status   models.UserStatus `json:"status"`
+	AddedBy  *uuid.UUID        `json:"added_by,omitempty"`
+	AddedAt  time.Time         `json:"added_at"`
+}
+
+// groupListFields is the sort/filter whitelist for group list endpoint
+var groupListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":         {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"name":       {Column: "name", Type: listquery.String, Filter: true, Sortable: true},
+	"created_at": {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
+// @Summary      Create group
+// @Description  Create a group (team) of users, roles assigned to the group apply to all members
+// @Tags         groups
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body GroupRequest true "Group details"
+// @Success      201  {object}  GroupResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups [post]
+func CreateGroup(c *gin.Context) {
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if groupNameTaken(database.WithContext(c.Request.Context()), req.Name, uuid.Nil) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group name already exists"})
+		return
+	}
+
+	group := models.Group{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionCreate, audit.ResourceGroup, group.ID, nil, group)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildGroupResponse(group, 0, nil))
+}
+
+// @Summary      Get all groups
+// @Description  Get list of groups with member count and roles. Supports sort=-created_at and filters like name[like]=jakarta.
+// @Tags         groups
+// @Produce      json
+// @Security     BearerAuth
+// @Param        sort  query     string  false  "Sort fields, prefix - for descending (default: name)"
+// @Success      200  {array}   GroupResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups [get]
+func GetGroups(c *gin.Context) {
+	listQuery, ok := bindListQuery(c, groupListFields)
+	if !ok {
+		return
+	}
+
+	var groups []models.Group
+	if err := listQuery.ApplySort(listQuery.ApplyFilters(database.WithContext(c.Request.Context())), "name ASC").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+
+	ids := make([]uuid.UUID, len(groups))
+	for i, group := range groups {
+		ids[i] = group.ID
+	}
+
+	counts, roles, err := loadGroupDetails(database.WithContext(c.Request.Context()), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+
+	response := make([]GroupResponse, 0, len(groups))
+	for _, group := range groups {
+		response = append(response, buildGroupResponse(group, counts[group.ID], roles[group.ID]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Get group by ID
+// @Description  Get group details with member count and roles
+// @Tags         groups
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Group ID"
+// @Success      200  {object}  GroupResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id} [get]
+func GetGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	counts, roles, err := loadGroupDetails(database.WithContext(c.Request.Context()), []uuid.UUID{group.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildGroupResponse(group, counts[group.ID], roles[group.ID]))
+}
+
+// @Summary      Update group
+// @Description  Update group name and description
+// @Tags         groups
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path    string       true  "Group ID"
+// @Param        request body    GroupRequest true  "Group details"
+// @Success      200  {object}  GroupResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id} [put]
+func UpdateGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if groupNameTaken(database.WithContext(c.Request.Context()), req.Name, group.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group name already exists"})
+		return
+	}
+
+	before := group
+	group.Name = req.Name
+	group.Description = req.Description
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&group).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceGroup, group.ID, before, group)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+		return
+	}
+
+	counts, roles, err := loadGroupDetails(database.WithContext(c.Request.Context()), []uuid.UUID{group.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildGroupResponse(group, counts[group.ID], roles[group.ID]))
+}
+
+// @Summary      Delete group
+// @Description  Delete group, its memberships and role assignments. Members lose the roles granted through the group.
+// @Tags         groups
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Group ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id} [delete]
+func DeleteGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		roles, err := snapshotGroupMemberRoles(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.GroupMember{}, "group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.GroupRole{}, "group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&group).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, audit.ActionDelete, audit.ResourceGroup, group.ID, group, nil); err != nil {
+			return err
+		}
+		return webhook.EnqueueRoleChanges(tx, roles, fmt.Sprintf("Group %s deleted", group.Name))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
+}
+
+// @Summary      Get group members
+// @Description  Get members of a group with pagination
+// @Tags         groups
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      string  true   "Group ID"
+// @Param        page   query     int     false  "Page number (default: 1)"
+// @Param        limit  query     int     false  "Items per page (default: 10, max: 100)"
+// @Success      200  {object}  models.PaginatedResponse{data=[]GroupMemberResponse}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id}/members [get]
+func GetGroupMembers(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	var pagination models.Pagination
+	pagination.Page = 1
+	pagination.Limit = 10
+
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.Limit < 1 {
+		pagination.Limit = 10
+	}
+	if pagination.Limit > 100 {
+		pagination.Limit = 100
+	}
+	pagination.Offset = (pagination.Page - 1) * pagination.Limit
+
+	// User yang sudah dihapus (soft delete) tidak ditampilkan
+	query := database.WithContext(c.Request.Context()).Table("map_group_user").
+		Joins("JOIN users ON users.id = map_group_user.user_id AND users.deleted_at IS NULL").
+		Where("map_group_user.group_id = ?", group.ID)
+
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count group members"})
+		return
+	}
+
+	members := []GroupMemberResponse{}
+	err := query.
+		Select("users.id AS user_id, users.username, users.email, users.type, users.status, map_group_user.added_by, map_group_user.created_at AS added_at").
+		Order("users.username ASC").
+		Offset(pagination.Offset).Limit(pagination.Limit).
+		Scan(&members).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       members,
+		Pagination: pagination,
+	})
+}
+
+// @Summary      Add group members
+// @Description  Add users to a group. Users that are already members are skipped.
+// @Tags         groups
+// @Accept       json
+// @Security     BearerAuth
+// @Param        id      path    string                  true  "Group ID"
+// @Param        request body    AddGroupMembersRequest  true  "User IDs"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id}/members [post]
+func AddGroupMembers(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	var req AddGroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var found []uuid.UUID
+	if err := database.WithContext(c.Request.Context()).Model(&models.User{}).Where("id IN ?", req.UserIDs).Pluck("id", &found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	if len(found) != len(uniqueUUIDs(req.UserIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "One or more users not found"})
+		return
+	}
+
+	var addedBy *uuid.UUID
+	if actor, exists := c.Get("user_id"); exists {
+		if actorUUID, err := uuid.Parse(actor.(string)); err == nil {
+			addedBy = &actorUUID
+		}
+	}
+
+	var added int64
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Anggota yang sudah ada dilewati, hanya anggota baru yang dicatat di audit
+		var existing []uuid.UUID
+		if err := tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id IN ?", group.ID, found).
+			Pluck("user_id", &existing).Error; err != nil {
+			return err
+		}
+		skip := make(map[uuid.UUID]bool, len(existing))
+		for _, userID := range existing {
+			skip[userID] = true
+		}
+
+		members := make([]models.GroupMember, 0, len(found))
+		for _, userID := range found {
+			if !skip[userID] {
+				members = append(members, models.GroupMember{GroupID: group.ID, UserID: userID, AddedBy: addedBy})
+			}
+		}
+		if len(members) == 0 {
+			return nil
+		}
+
+		memberIDs := make([]uuid.UUID, len(members))
+		for i, member := range members {
+			memberIDs[i] = member.UserID
+		}
+		roles, err := webhook.SnapshotRoles(tx, memberIDs)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members)
+		if result.Error != nil {
+			return result.Error
+		}
+		added = result.RowsAffected
+
+		for _, member := range members {
+			if err := recordAudit(tx, c, audit.ActionAssign, audit.ResourceGroupMember, group.ID, nil, member); err != nil {
+				return err
+			}
+		}
+		return webhook.EnqueueRoleChanges(tx, roles, fmt.Sprintf("Added to group %s", group.Name))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add group members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group members added successfully", "added": added})
+}
+
+// @Summary      Remove group member
+// @Description  Remove a user from a group
+// @Tags         groups
+// @Security     BearerAuth
+// @Param        id       path    string  true  "Group ID"
+// @Param        user_id  path    string  true  "User ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id}/members/{user_id} [delete]
+func RemoveGroupMember(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var member models.GroupMember
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		roles, err := webhook.SnapshotRoles(tx, []uuid.UUID{userID})
+		if err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.Returning{}).Delete(&member, "group_id = ? AND user_id = ?", group.ID, userID)
+		if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		if err := recordAudit(tx, c, audit.ActionUnassign, audit.ResourceGroupMember, group.ID, member, nil); err != nil {
+			return err
+		}
+		return webhook.EnqueueRoleChanges(tx, roles, fmt.Sprintf("Removed from group %s", group.Name))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove group member"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group member removed successfully"})
+}
+
+// @Summary      Assign role to group
+// @Description  Assign a role to a group, all members get the role in addition to their direct roles
+// @Tags         groups
+// @Security     BearerAuth
+// @Param        id       path    string  true  "Group ID"
+// @Param        role_id  path    string  true  "Role ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id}/roles/{role_id} [post]
+func AssignRoleToGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	roleID, err := uuid.Parse(c.Param("role_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var role models.Role
+	if err := database.WithContext(c.Request.Context()).First(&role, "id = ?", roleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	assignment := models.GroupRole{GroupID: group.ID, RoleID: role.ID}
+
+	// Catat user yang memberikan role
+	if grantedBy, exists := c.Get("user_id"); exists {
+		if grantedByUUID, err := uuid.Parse(grantedBy.(string)); err == nil {
+			assignment.GrantedBy = &grantedByUUID
+		}
+	}
+
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		roles, err := snapshotGroupMemberRoles(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&assignment)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := recordAudit(tx, c, audit.ActionAssign, audit.ResourceGroupRole, group.ID, nil, assignment); err != nil {
+			return err
+		}
+		return webhook.EnqueueRoleChanges(tx, roles, fmt.Sprintf("Role assigned to group %s", group.Name))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign role to group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role assigned to group successfully"})
+}
+
+// @Summary      Remove role from group
+// @Description  Remove a role from a group, members keep the role only if it is also assigned directly or through another group
+// @Tags         groups
+// @Security     BearerAuth
+// @Param        id       path    string  true  "Group ID"
+// @Param        role_id  path    string  true  "Role ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /groups/{id}/roles/{role_id} [delete]
+func RemoveRoleFromGroup(c *gin.Context) {
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	roleID, err := uuid.Parse(c.Param("role_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var assignment models.GroupRole
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		roles, err := snapshotGroupMemberRoles(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.Returning{}).Delete(&assignment, "group_id = ? AND role_id = ?", group.ID, roleID)
+		if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		if err := recordAudit(tx, c, audit.ActionUnassign, audit.ResourceGroupRole, group.ID, assignment, nil); err != nil {
+			return err
+		}
+		return webhook.EnqueueRoleChanges(tx, roles, fmt.Sprintf("Role removed from group %s", group.Name))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove role from group"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role is not assigned to this group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role removed from group successfully"})
+}
+
+// snapshotGroupMemberRoles takes the role snapshot of every member of the group, supaya perubahan
+// role group atau penghapusan group menghasilkan event webhook untuk role yang berubah
+func snapshotGroupMemberRoles(tx *gorm.DB, groupID uuid.UUID) (*webhook.RoleSnapshot, error) {
+	var memberIDs []uuid.UUID
+	if err := tx.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Pluck("user_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+	return webhook.SnapshotRoles(tx, memberIDs)
+}
+
+// findGroup loads the group from the :id path parameter and responds 400/404 on failure
+func findGroup(c *gin.Context) (models.Group, bool) {
+	var group models.Group
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return group, false
+	}
+
+	if err := database.WithContext(c.Request.Context()).First(&group, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		}
+		return group, false
+	}
+
+	return group, true
+}
+
+func groupNameTaken(db *gorm.DB, name string, exceptID uuid.UUID) bool {
+	var count int64
+	db.Model(&models.Group{}).Where("name = ? AND id <> ?", name, exceptID).Count(&count)
+	return count > 0
+}
+
+// loadGroupDetails returns member count and role names per group
+func loadGroupDetails(db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]int64, map[uuid.UUID][]string, error) {
+	counts := make(map[uuid.UUID]int64)
+	roles := make(map[uuid.UUID][]string)
+	if len(ids) == 0 {
+		return counts, roles, nil
+	}
+
+	var countRows []struct {
+		GroupID uuid.UUID
+		Count   int64
+	}
+	err := db.Table("map_group_user").
+		Select("map_group_user.group_id, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = map_group_user.user_id AND users.deleted_at IS NULL").
+		Where("map_group_user.group_id IN ?", ids).
+		Group("map_group_user.group_id").
+		Scan(&countRows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range countRows {
+		counts[row.GroupID] = row.Count
+	}
+
+	var roleRows []struct {
+		GroupID uuid.UUID
+		Name    string
+	}
+	err = db.Table("map_group_role").
+		Select("map_group_role.group_id, roles.name").
+		Joins("JOIN roles ON roles.id = map_group_role.role_id").
+		Where("map_group_role.group_id IN ?", ids).
+		Order("roles.name ASC").
+		Scan(&roleRows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range roleRows {
+		roles[row.GroupID] = append(roles[row.GroupID], row.Name)
+	}
+
+	return counts, roles, nil
+}
+
+func buildGroupResponse(group models.Group, memberCount int64, roles []string) GroupResponse {
+	if roles == nil {
+		roles = []string{}
+	}
+	return GroupResponse{
+		ID:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		MemberCount: memberCount,
+		Roles:       roles,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
+
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	var unique []uuid.UUID
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// userGroupRole is a role a user gets through group membership
+type userGroupRole struct {
+	RoleID    uuid.UUID
+	RoleName  string
+	GroupID   uuid.UUID
+	GroupName string
+	GrantedBy *uuid.UUID
+	CreatedAt time.Time
+}
+
+// loadUserGroupRoles returns roles the user gets from all groups they are a member of
+func loadUserGroupRoles(db *gorm.DB, userID uuid.UUID) ([]userGroupRole, error) {
+	var rows []userGroupRole
+	err := db.Table("map_group_role").
+		Select("roles.id AS role_id, roles.name AS role_name, groups.id AS group_id, groups.name AS group_name, map_group_role.granted_by, map_group_role.created_at").
+		Joins("JOIN roles ON roles.id = map_group_role.role_id").
+		Joins("JOIN groups ON groups.id = map_group_role.group_id").
+		Joins("JOIN map_group_user ON map_group_user.group_id = map_group_role.group_id").
+		Where("map_group_user.user_id = ?", userID).
+		Order("groups.name ASC, roles.name ASC").
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+
+	"sjek/internal/hashchain"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IntegrityReportResponse is the result of verifying the log hash chains
+type IntegrityReportResponse struct {
+	Valid     bool               `json:"valid"`
+	PublicKey string             `json:"public_key"`
+	KeyID     string             `json:"key_id"`
+	Chains    []hashchain.Report `json:"chains"`
+}
+
+// CheckpointResponse lists checkpoints together with the key needed to verify them
+type CheckpointResponse struct {
+	PublicKey   string                   `json:"public_key"`
+	KeyID       string                   `json:"key_id"`
+	Checkpoints []models.ChainCheckpoint `json:"checkpoints"`
+}
+
+// selectedChains returns the chain from the chain query parameter, or all chains
+func selectedChains(c *gin.Context) ([]hashchain.Chain, bool) {
+	name := c.Query("chain")
+	if name == "" {
+		return hashchain.Chains, true
+	}
+
+	chain, ok := hashchain.Find(name)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chain, use audit_logs or login_logs"})
+		return nil, false
+	}
+	return []hashchain.Chain{chain}, true
+}
+
+// VerifyLogIntegrity walks the audit and login log hash chains
+// @Summary      Verify log integrity
+// @Description  Recompute the hash chain of audit_logs and login_logs from the first record and check every signed checkpoint.
+// @Description  Each chain reports the first broken link (missing record, changed content, bad checkpoint) if any.
+// @Tags         integrity
+// @Produce      json
+// @Security     BearerAuth
+// @Param        chain  query     string  false  "Chain to verify (audit_logs/login_logs, default: all)"
+// @Success      200  {object}  IntegrityReportResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /integrity/verify [get]
+func VerifyLogIntegrity(db *gorm.DB, signer hashchain.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		chains, ok := selectedChains(c)
+		if !ok {
+			return
+		}
+
+		response := IntegrityReportResponse{Valid: true, PublicKey: signer.PublicKey(), KeyID: signer.KeyID()}
+		for _, chain := range chains {
+			report, err := chain.Verify(db, signer)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify " + chain.Name})
+				return
+			}
+			response.Valid = response.Valid && report.Valid
+			response.Chains = append(response.Chains, report)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetChainCheckpoints lists signed checkpoints, newest first
+// @Summary      Get hash chain checkpoints
+// @Description  Get signed checkpoints of the log hash chains. Signature is Ed25519 over "<chain>:<seq>:<hash>:<created_at RFC3339Nano UTC>" and can be verified offline with public_key.
+// @Tags         integrity
+// @Produce      json
+// @Security     BearerAuth
+// @Param        chain  query     string  false  "Filter by chain (audit_logs/login_logs)"
+// @Param        limit  query     int     false  "Max checkpoints (default: 50, max: 500)"
+// @Success      200  {object}  CheckpointResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /integrity/checkpoints [get]
+func GetChainCheckpoints(db *gorm.DB, signer hashchain.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		var pagination models.Pagination
+		pagination.Limit = 50
+		if err := c.ShouldBindQuery(&pagination); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if pagination.Limit < 1 {
+			pagination.Limit = 50
+		}
+		if pagination.Limit > 500 {
+			pagination.Limit = 500
+		}
+
+		query := db.Model(&models.ChainCheckpoint{})
+		if chain := c.Query("chain"); chain != "" {
+			if _, ok := hashchain.Find(chain); !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chain, use audit_logs or login_logs"})
+				return
+			}
+			query = query.Where("chain = ?", chain)
+		}
+
+		checkpoints := []models.ChainCheckpoint{}
+		if err := query.Order("created_at DESC").Limit(pagination.Limit).Find(&checkpoints).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch checkpoints"})
+			return
+		}
+
+		c.JSON(http.StatusOK, CheckpointResponse{PublicKey: signer.PublicKey(), KeyID: signer.KeyID(), Checkpoints: checkpoints})
+	}
+}
+
+// CreateChainCheckpoints signs the current head of every chain now
+// @Summary      Create hash chain checkpoints
+// @Description  Write a signed checkpoint for every chain that changed since its last checkpoint, without waiting for CHAIN_CHECKPOINT_INTERVAL
+// @Tags         integrity
+// @Produce      json
+// @Security     BearerAuth
+// @Success      201  {object}  CheckpointResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /integrity/checkpoints [post]
+func CreateChainCheckpoints(db *gorm.DB, signer hashchain.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		written, err := hashchain.CheckpointAll(db, signer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write checkpoints"})
+			return
+		}
+		if written == nil {
+			written = []models.ChainCheckpoint{}
+		}
+
+		c.JSON(http.StatusCreated, CheckpointResponse{PublicKey: signer.PublicKey(), KeyID: signer.KeyID(), Checkpoints: written})
+	}
+}
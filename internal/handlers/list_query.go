@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"sjek/internal/listquery"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bindListQuery parses sort and filter parameters for a list endpoint.
+// Query yang tidak valid langsung dibalas 400 dengan format error yang sama di semua endpoint
+func bindListQuery(c *gin.Context, resource listquery.Resource) (*listquery.Query, bool) {
+	query, err := resource.Parse(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return query, true
+}
+
+// bindKeyset returns cursor pagination when the client sent after, before or pagination=cursor.
+// Nil tanpa error berarti client memakai mode offset (page/limit) seperti biasa
+func bindKeyset(c *gin.Context, resource listquery.Resource, query *listquery.Query, defaultSort string, limit int) (*listquery.Keyset, bool) {
+	values := c.Request.URL.Query()
+	if !listquery.IsCursorRequest(values) {
+		return nil, true
+	}
+
+	keyset, err := resource.ParseKeyset(values, query, defaultSort, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return keyset, true
+}
+
+// findCursorPage loads one keyset page into dest and counts the total only when with_total=true
+func findCursorPage(c *gin.Context, keyset *listquery.Keyset, query *gorm.DB, dest interface{}) (models.CursorPagination, error) {
+	pagination := models.CursorPagination{Limit: keyset.Limit}
+
+	if c.Query("with_total") == "true" {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return pagination, err
+		}
+		pagination.Total = &total
+	}
+
+	next, prev, err := keyset.Find(query, dest)
+	if err != nil {
+		return pagination, err
+	}
+	pagination.NextCursor = next
+	pagination.PrevCursor = prev
+	return pagination, nil
+}
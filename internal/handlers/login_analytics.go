@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sjek/internal/database"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LoginSummary is the login totals over the requested range
+type LoginSummary struct {
+	Total       int64   `json:"total"`
+	Success     int64   `json:"success"`
+	Failed      int64   `json:"failed"`
+	SuccessRate float64 `json:"success_rate" example:"0.92"`
+	UniqueUsers int64   `json:"unique_users"` // user berbeda dengan login sukses
+	UniqueIPs   int64   `json:"unique_ips"`
+}
+
+// LoginTrendBucket is the login totals of one time bucket
+type LoginTrendBucket struct {
+	Bucket      time.Time `json:"bucket"`
+	Total       int64     `json:"total"`
+	Success     int64     `json:"success"`
+	Failed      int64     `json:"failed"`
+	SuccessRate float64   `json:"success_rate"`
+	UniqueUsers int64     `json:"unique_users"`
+}
+
+// LoginTrendResponse represents login counts per time bucket
+type LoginTrendResponse struct {
+	Granularity string             `json:"granularity" example:"day"`
+	Timezone    string             `json:"timezone" example:"Asia/Jakarta"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Summary     LoginSummary       `json:"summary"`
+	Data        []LoginTrendBucket `json:"data"`
+}
+
+// LoginTopEntry is one IP address or username ranked by login attempts
+type LoginTopEntry struct {
+	Value             string    `json:"value"`
+	Attempts          int64     `json:"attempts"`
+	Failed            int64     `json:"failed"`
+	Success           int64     `json:"success"`
+	DistinctUsernames int64     `json:"distinct_usernames,omitempty"` // hanya untuk top IP
+	DistinctIPs       int64     `json:"distinct_ips,omitempty"`       // hanya untuk top username
+	LastAttempt       time.Time `json:"last_attempt"`
+}
+
+// LoginTopResponse represents the top IP addresses or usernames
+type LoginTopResponse struct {
+	Status   string          `json:"status" example:"FAILED"`
+	Timezone string          `json:"timezone"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Data     []LoginTopEntry `json:"data"`
+}
+
+// LoginFailureReason is the number of failed logins with the same message
+type LoginFailureReason struct {
+	Message    string  `json:"message" example:"Invalid password"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage" example:"61.5"`
+}
+
+// LoginFailureResponse represents the breakdown of failed logins by reason
+type LoginFailureResponse struct {
+	Timezone string               `json:"timezone"`
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Summary  LoginSummary         `json:"summary"`
+	Reasons  []LoginFailureReason `json:"reasons"`
+}
+
+// loginGranularities are the allowed trend granularities with the longest range each may cover
+var loginGranularities = map[string]time.Duration{
+	"hour":  31 * 24 * time.Hour,
+	"day":   2 * 366 * 24 * time.Hour,
+	"week":  5 * 366 * 24 * time.Hour,
+	"month": 10 * 366 * 24 * time.Hour,
+}
+
+// loginAnalyticsRange reads timezone (IANA, default UTC) and from_date/to_date (YYYY-MM-DD in that timezone).
+// Default range adalah 30 hari terakhir termasuk hari ini; to_date inklusif
+func loginAnalyticsRange(c *gin.Context) (*time.Location, time.Time, time.Time, bool) {
+	// "Local" diterima time.LoadLocation tapi bukan nama yang dikenal PostgreSQL untuk AT TIME ZONE
+	loc, err := time.LoadLocation(c.DefaultQuery("timezone", "UTC"))
+	if err != nil || loc == time.Local {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone, use an IANA name such as Asia/Jakarta"})
+		return nil, time.Time{}, time.Time{}, false
+	}
+
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	if toDate := c.Query("to_date"); toDate != "" {
+		day, err := time.ParseInLocation("2006-01-02", toDate, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_date, use YYYY-MM-DD"})
+			return nil, time.Time{}, time.Time{}, false
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		day, err := time.ParseInLocation("2006-01-02", fromDate, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_date, use YYYY-MM-DD"})
+			return nil, time.Time{}, time.Time{}, false
+		}
+		from = day
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_date must be before to_date"})
+		return nil, time.Time{}, time.Time{}, false
+	}
+	return loc, from, to, true
+}
+
+// loginAnalyticsQuery returns login_logs limited to [from, to), optionally filtered by username or IP
+func loginAnalyticsQuery(c *gin.Context, from, to time.Time) *gorm.DB {
+	query := database.WithContext(c.Request.Context()).Model(&models.LoginLog{}).Where("login_time >= ? AND login_time < ?", from, to)
+	if username := c.Query("username"); username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if ipAddress := c.Query("ip_address"); ipAddress != "" {
+		query = query.Where("ip_address = ?", ipAddress)
+	}
+	return query
+}
+
+// loginCounts are the aggregate columns shared by summary and trends
+const loginCounts = `COUNT(*) AS total,
+	COUNT(*) FILTER (WHERE status = 'SUCCESS') AS success,
+	COUNT(*) FILTER (WHERE status = 'FAILED') AS failed,
+	COUNT(DISTINCT user_id) FILTER (WHERE status = 'SUCCESS') AS unique_users`
+
+func successRate(success, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(success) / float64(total)
+}
+
+// loginSummary aggregates the totals of query
+func loginSummary(query *gorm.DB) (LoginSummary, error) {
+	var summary LoginSummary
+	err := query.Select(loginCounts + ", COUNT(DISTINCT ip_address) AS unique_ips").Scan(&summary).Error
+	summary.SuccessRate = successRate(summary.Success, summary.Total)
+	return summary, err
+}
+
+// inLocation reinterprets a bucket returned as local wall time (timestamp without time zone) in loc
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// @Summary      Get login trends
+// @Description  Login attempts per hour, day, week or month with success/failure counts, success rate and
+// @Description  unique users with a successful login. Buckets follow the given timezone. Default range is the last 30 days.
+// @Description  Hour covers at most 31 days, day 2 years, week 5 years and month 10 years.
+// @Tags         login-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        granularity  query     string  false  "hour, day (default), week or month"
+// @Param        timezone     query     string  false  "IANA timezone (default: UTC)"
+// @Param        from_date    query     string  false  "From date (YYYY-MM-DD)"
+// @Param        to_date      query     string  false  "To date inclusive (YYYY-MM-DD)"
+// @Param        username     query     string  false  "Filter by username"
+// @Param        ip_address   query     string  false  "Filter by IP address"
+// @Success      200  {object}  LoginTrendResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /login-logs/analytics/trends [get]
+func GetLoginTrends(c *gin.Context) {
+	granularity := c.DefaultQuery("granularity", "day")
+	maxRange, ok := loginGranularities[granularity]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid granularity, use hour, day, week or month"})
+		return
+	}
+
+	loc, from, to, ok := loginAnalyticsRange(c)
+	if !ok {
+		return
+	}
+	if to.Sub(from) > maxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range is too long for granularity " + granularity})
+		return
+	}
+
+	summary, err := loginSummary(loginAnalyticsQuery(c, from, to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate login logs"})
+		return
+	}
+
+	// granularity sudah divalidasi terhadap whitelist, timezone dikirim sebagai parameter
+	buckets := []LoginTrendBucket{}
+	err = loginAnalyticsQuery(c, from, to).
+		Select("date_trunc('"+granularity+"', login_time AT TIME ZONE ?) AS bucket, "+loginCounts, loc.String()).
+		Group("1").Order("1").Scan(&buckets).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate login logs"})
+		return
+	}
+	for i := range buckets {
+		buckets[i].Bucket = inLocation(buckets[i].Bucket, loc)
+		buckets[i].SuccessRate = successRate(buckets[i].Success, buckets[i].Total)
+	}
+
+	c.JSON(http.StatusOK, LoginTrendResponse{
+		Granularity: granularity,
+		Timezone:    loc.String(),
+		From:        from,
+		To:          to,
+		Summary:     summary,
+		Data:        buckets,
+	})
+}
+
+// getLoginTop ranks the values of column by login attempts with the given status.
+// distinct adalah ekspresi SELECT untuk jumlah nilai berbeda dari kolom pasangannya
+func getLoginTop(c *gin.Context, column, distinct string) {
+	status := c.DefaultQuery("status", models.LoginStatusFailed)
+	if status != models.LoginStatusFailed && status != models.LoginStatusSuccess && status != "ALL" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use FAILED, SUCCESS or ALL"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	loc, from, to, ok := loginAnalyticsRange(c)
+	if !ok {
+		return
+	}
+
+	query := loginAnalyticsQuery(c, from, to).Where(column + " <> ''")
+	order := "attempts DESC"
+	if status != "ALL" {
+		query = query.Where("status = ?", status)
+	}
+	if status == models.LoginStatusFailed {
+		order = "failed DESC"
+	}
+
+	entries := []LoginTopEntry{}
+	err = query.Select(column + ` AS value,
+		COUNT(*) AS attempts,
+		COUNT(*) FILTER (WHERE status = 'FAILED') AS failed,
+		COUNT(*) FILTER (WHERE status = 'SUCCESS') AS success,
+		` + distinct + `,
+		MAX(login_time) AS last_attempt`).
+		Group(column).Order(order).Order("value").Limit(limit).Scan(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate login logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginTopResponse{Status: status, Timezone: loc.String(), From: from, To: to, Data: entries})
+}
+
+// @Summary      Get top login IP addresses
+// @Description  IP addresses ranked by login attempts, by default only failed attempts, with the number of
+// @Description  distinct usernames tried from each IP. Default range is the last 30 days.
+// @Tags         login-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status     query     string  false  "FAILED (default), SUCCESS or ALL"
+// @Param        limit      query     int     false  "Number of entries (default: 10, max: 100)"
+// @Param        timezone   query     string  false  "IANA timezone for from_date/to_date (default: UTC)"
+// @Param        from_date  query     string  false  "From date (YYYY-MM-DD)"
+// @Param        to_date    query     string  false  "To date inclusive (YYYY-MM-DD)"
+// @Param        username   query     string  false  "Filter by username"
+// @Success      200  {object}  LoginTopResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /login-logs/analytics/top-ips [get]
+func GetLoginTopIPs(c *gin.Context) {
+	getLoginTop(c, "ip_address", "COUNT(DISTINCT username) AS distinct_usernames")
+}
+
+// @Summary      Get top login usernames
+// @Description  Usernames ranked by login attempts, by default only failed attempts, with the number of
+// @Description  distinct IP addresses used for each. Default range is the last 30 days.
+// @Tags         login-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status      query     string  false  "FAILED (default), SUCCESS or ALL"
+// @Param        limit       query     int     false  "Number of entries (default: 10, max: 100)"
+// @Param        timezone    query     string  false  "IANA timezone for from_date/to_date (default: UTC)"
+// @Param        from_date   query     string  false  "From date (YYYY-MM-DD)"
+// @Param        to_date     query     string  false  "To date inclusive (YYYY-MM-DD)"
+// @Param        ip_address  query     string  false  "Filter by IP address"
+// @Success      200  {object}  LoginTopResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /login-logs/analytics/top-usernames [get]
+func GetLoginTopUsernames(c *gin.Context) {
+	getLoginTop(c, "username", "COUNT(DISTINCT ip_address) AS distinct_ips")
+}
+
+// @Summary      Get login failure breakdown
+// @Description  Failed logins grouped by reason (message) with their share of all failures, plus the
+// @Description  success/failure totals of the range. Default range is the last 30 days.
+// @Tags         login-logs
+// @Produce      json
+// @Security     BearerAuth
+// @Param        timezone    query     string  false  "IANA timezone for from_date/to_date (default: UTC)"
+// @Param        from_date   query     string  false  "From date (YYYY-MM-DD)"
+// @Param        to_date     query     string  false  "To date inclusive (YYYY-MM-DD)"
+// @Param        username    query     string  false  "Filter by username"
+// @Param        ip_address  query     string  false  "Filter by IP address"
+// @Success      200  {object}  LoginFailureResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /login-logs/analytics/failures [get]
+func GetLoginFailures(c *gin.Context) {
+	loc, from, to, ok := loginAnalyticsRange(c)
+	if !ok {
+		return
+	}
+
+	summary, err := loginSummary(loginAnalyticsQuery(c, from, to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate login logs"})
+		return
+	}
+
+	reasons := []LoginFailureReason{}
+	err = loginAnalyticsQuery(c, from, to).Where("status = ?", models.LoginStatusFailed).
+		Select("COALESCE(NULLIF(message, ''), 'Unknown') AS message, COUNT(*) AS count").
+		Group("1").Order("count DESC").Order("message").Scan(&reasons).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate login logs"})
+		return
+	}
+	for i := range reasons {
+		if summary.Failed > 0 {
+			reasons[i].Percentage = float64(reasons[i].Count) * 100 / float64(summary.Failed)
+		}
+	}
+
+	c.JSON(http.StatusOK, LoginFailureResponse{
+		Timezone: loc.String(),
+		From:     from,
+		To:       to,
+		Summary:  summary,
+		Reasons:  reasons,
+	})
+}
@@ -3,11 +3,13 @@ package handlers
 import (
 	"net/http"
 	"sjek/internal/database"
+	"sjek/internal/listquery"
 	"sjek/internal/models"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type LoginLogResponse struct {
@@ -22,13 +24,32 @@ type LoginLogResponse struct {
 	Message   string    `json:"message,omitempty"`
 }
 
+// loginLogListFields is the sort/filter whitelist for login log list endpoints
+var loginLogListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":         {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"user_id":    {Column: "user_id", Type: listquery.UUID, Filter: true},
+	"username":   {Column: "username", Type: listquery.String, Filter: true, Sortable: true},
+	"email":      {Column: "email", Type: listquery.String, Filter: true, Sortable: true},
+	"ip_address": {Column: "ip_address", Type: listquery.String, Filter: true, Sortable: true},
+	"status":     {Column: "status", Type: listquery.String, Filter: true, Sortable: true},
+	"login_time": {Column: "login_time", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
 // @Summary      Get login logs with pagination and filters
-// @Description  Get all login logs with pagination, filter by status, username, date range
+// @Description  Get all login logs with pagination, filter by status, username, date range.
+// @Description  Supports sort=-login_time and filters like filter[status]=FAILED or login_time[gte]=2024-01-01.
+// @Description  Send pagination=cursor, after or before for keyset pagination ordered by (login_time, id); the response is then
+// @Description  models.CursorPaginatedResponse with next_cursor/prev_cursor.
 // @Tags         login-logs
 // @Produce      json
 // @Security     BearerAuth
 // @Param        page      query     int     false  "Page number (default: 1)"
 // @Param        limit     query     int     false  "Items per page (default: 10, max: 100)"
+// @Param        pagination query    string  false  "Set to cursor for keyset pagination"
+// @Param        after     query     string  false  "Cursor for the next page"
+// @Param        before    query     string  false  "Cursor for the previous page"
+// @Param        with_total query    bool    false  "Count total rows in cursor mode"
+// @Param        sort      query     string  false  "Sort fields, prefix - for descending (default: -login_time)"
 // @Param        status    query     string  false  "Filter by status (SUCCESS/FAILED)"
 // @Param        username  query     string  false  "Filter by username contains"
 // @Param        from_date query     string  false  "Filter from date (YYYY-MM-DD)"
@@ -49,12 +70,6 @@ func GetLoginLogs(c *gin.Context) {
 		return
 	}
 
-	// Get filter parameters
-	statusFilter := c.Query("status")
-	usernameFilter := c.Query("username")
-	fromDate := c.Query("from_date")
-	toDate := c.Query("to_date")
-
 	// Validasi input
 	if pagination.Page < 1 {
 		pagination.Page = 1
@@ -69,21 +84,31 @@ func GetLoginLogs(c *gin.Context) {
 	// Hitung offset
 	pagination.Offset = (pagination.Page - 1) * pagination.Limit
 
+	listQuery, ok := bindListQuery(c, loginLogListFields)
+	if !ok {
+		return
+	}
+
 	// Build query dengan filter
-	query := database.DB.Model(&models.LoginLog{})
+	query := listQuery.ApplyFilters(applyLoginLogFilters(c, database.WithContext(c.Request.Context()).Model(&models.LoginLog{})))
 
-	// Apply filters
-	if statusFilter != "" {
-		query = query.Where("status = ?", statusFilter)
-	}
-	if usernameFilter != "" {
-		query = query.Where("username ILIKE ?", "%"+usernameFilter+"%")
-	}
-	if fromDate != "" {
-		query = query.Where("login_time >= ?", fromDate+" 00:00:00")
+	keyset, ok := bindKeyset(c, loginLogListFields, listQuery, "-login_time", pagination.Limit)
+	if !ok {
+		return
 	}
-	if toDate != "" {
-		query = query.Where("login_time <= ?", toDate+" 23:59:59")
+	if keyset != nil {
+		var loginLogs []models.LoginLog
+		cursorPagination, err := findCursorPage(c, keyset, query, &loginLogs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login logs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+			Data:       buildLoginLogResponses(loginLogs),
+			Pagination: cursorPagination,
+		})
+		return
 	}
 
 	// Hitung total records dengan filter
@@ -96,13 +121,21 @@ func GetLoginLogs(c *gin.Context) {
 
 	// Ambil data dengan pagination dan filter, order by login_time desc
 	var loginLogs []models.LoginLog
-	result := query.Order("login_time DESC").Offset(pagination.Offset).Limit(pagination.Limit).Find(&loginLogs)
+	result := listQuery.ApplySort(query, "login_time DESC").Order("id DESC").Offset(pagination.Offset).Limit(pagination.Limit).Find(&loginLogs)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login logs"})
 		return
 	}
 
-	// Transform ke response format
+	// Return response
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       buildLoginLogResponses(loginLogs),
+		Pagination: pagination,
+	})
+}
+
+// buildLoginLogResponses transforms login logs ke response format
+func buildLoginLogResponses(loginLogs []models.LoginLog) []LoginLogResponse {
 	var response []LoginLogResponse
 	for _, log := range loginLogs {
 		response = append(response, LoginLogResponse{
@@ -117,12 +150,25 @@ func GetLoginLogs(c *gin.Context) {
 			Message:   log.Message,
 		})
 	}
+	return response
+}
 
-	// Return response
-	c.JSON(http.StatusOK, models.PaginatedResponse{
-		Data:       response,
-		Pagination: pagination,
-	})
+// applyLoginLogFilters applies the login log list filters from query parameters
+func applyLoginLogFilters(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if statusFilter := c.Query("status"); statusFilter != "" {
+		query = query.Where("status = ?", statusFilter)
+	}
+	if usernameFilter := c.Query("username"); usernameFilter != "" {
+		query = query.Where(`username ILIKE ? ESCAPE '\'`, database.ContainsPattern(usernameFilter))
+	}
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		query = query.Where("login_time >= ?", fromDate+" 00:00:00")
+	}
+	if toDate := c.Query("to_date"); toDate != "" {
+		query = query.Where("login_time <= ?", toDate+" 23:59:59")
+	}
+
+	return query
 }
 
 // @Summary      Get login log by ID
@@ -143,7 +189,7 @@ func GetLoginLog(c *gin.Context) {
 	}
 
 	var loginLog models.LoginLog
-	result := database.DB.First(&loginLog, "id = ?", id)
+	result := database.WithContext(c.Request.Context()).First(&loginLog, "id = ?", id)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Login log not found"})
 		return
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"sjek/internal/database"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type ProfileResponse struct {
+	UserResponse
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Field yang boleh diubah sendiri oleh user, kosong berarti tidak diubah
+type UpdateProfileRequest struct {
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty" binding:"omitempty,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type PermissionAPI struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+type PermissionsResponse struct {
+	Roles []string        `json:"roles"`
+	APIs  []PermissionAPI `json:"apis"`
+	Menus []MenuResponse  `json:"menus"`
+}
+
+// currentUserID returns the authenticated user ID set by AuthMiddleware
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return uuid.Nil, false
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+
+	return userUUID, true
+}
+
+// respondProfile writes the profile of user with the roles the user has right now
+func respondProfile(c *gin.Context, user models.User) {
+	roles, err := database.ActiveRoleNames(database.WithContext(c.Request.Context()), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileResponse{
+		UserResponse: buildUserResponse(user, roles),
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	})
+}
+
+// @Summary      Get current user profile
+// @Description  Get full profile of the authenticated user
+// @Tags         me
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  ProfileResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /me [get]
+func GetMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := database.WithContext(c.Request.Context()).First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	respondProfile(c, user)
+}
+
+// @Summary      Update current user profile
+// @Description  Update username and/or email of the authenticated user
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body UpdateProfileRequest true "Profile fields"
+// @Success      200  {object}  ProfileResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /me [put]
+func UpdateMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.WithContext(c.Request.Context()).First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Username != "" && req.Username != user.Username {
+		var count int64
+		if err := database.WithContext(c.Request.Context()).Unscoped().Model(&models.User{}).Where("username = ? AND id <> ?", req.Username, user.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check username"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+			return
+		}
+		updates["username"] = req.Username
+	}
+	if req.Email != "" && req.Email != user.Email {
+		var count int64
+		if err := database.WithContext(c.Request.Context()).Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", req.Email, user.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+			return
+		}
+		updates["email"] = req.Email
+	}
+
+	if len(updates) > 0 {
+		if err := database.WithContext(c.Request.Context()).Model(&user).Updates(updates).Error; err != nil {
+			// Pengecekan di atas bisa kalah balapan dengan registrasi, constraint unique yang menentukan
+			if database.IsUniqueViolation(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Username or email already exists"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+		if username, ok := updates["username"].(string); ok {
+			user.Username = username
+		}
+		if email, ok := updates["email"].(string); ok {
+			user.Email = email
+		}
+	}
+
+	respondProfile(c, user)
+}
+
+// @Summary      Change current user password
+// @Description  Change password after verifying the current one. Other sessions are logged out.
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body ChangePasswordRequest true "Current and new password"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /me/password [post]
+func ChangeMyPassword(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.WithContext(c.Request.Context()).First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	tx := database.WithContext(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	// Logout dari device lain, token yang sedang dipakai tetap aktif
+	tokenID, _ := c.Get("token_id")
+	if err := tx.Model(&models.UserToken{}).Where("user_id = ? AND id <> ?", user.ID, tokenID).Update("is_active", false).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke other tokens"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// @Summary      Get current user permissions
+// @Description  Get effective roles, API permissions and menus of the authenticated user
+// @Tags         me
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  PermissionsResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /me/permissions [get]
+func GetMyPermissions(c *gin.Context) {
+	userRoles, exists := c.Get("roles")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User roles not found"})
+		return
+	}
+	roles := userRoles.([]string)
+
+	response := PermissionsResponse{
+		Roles: roles,
+		APIs:  []PermissionAPI{},
+		Menus: []MenuResponse{},
+	}
+	if response.Roles == nil {
+		response.Roles = []string{}
+	}
+
+	if len(roles) > 0 {
+		// API yang di-grant ke salah satu role dan tidak di-deny oleh role manapun
+		var apis []models.API
+		err := database.WithContext(c.Request.Context()).
+			Where("id IN (SELECT api_id FROM map_role_api WHERE role_id IN (SELECT id FROM roles WHERE name IN ?))", roles).
+			Where("id NOT IN (SELECT api_id FROM map_role_api_deny WHERE role_id IN (SELECT id FROM roles WHERE name IN ?))", roles).
+			Order("path ASC, method ASC").
+			Find(&apis).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API permissions"})
+			return
+		}
+		for _, api := range apis {
+			response.APIs = append(response.APIs, PermissionAPI{Method: api.Method, Path: api.Path})
+		}
+
+		menus, err := userMenuTree(database.WithContext(c.Request.Context()), roles)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user menus"})
+			return
+		}
+		response.Menus = menus
+	}
+
+	c.JSON(http.StatusOK, response)
+}
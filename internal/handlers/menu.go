@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"net/http"
+	"sjek/internal/audit"
 	"sjek/internal/database"
+	"sjek/internal/listquery"
 	"sjek/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MenuRequest struct {
@@ -54,7 +57,7 @@ func CreateMenu(c *gin.Context) {
 	// Validate parent menu exists if ParentID is provided
 	if req.ParentID != nil {
 		var parentMenu models.Menu
-		if err := database.DB.First(&parentMenu, "id = ?", *req.ParentID).Error; err != nil {
+		if err := database.WithContext(c.Request.Context()).First(&parentMenu, "id = ?", *req.ParentID).Error; err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent menu not found"})
 			return
 		}
@@ -71,7 +74,13 @@ func CreateMenu(c *gin.Context) {
 		Description: req.Description,
 	}
 
-	if err := database.DB.Create(&menu).Error; err != nil {
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&menu).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionCreate, audit.ResourceMenu, menu.ID, nil, menu)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create menu"})
 		return
 	}
@@ -90,20 +99,40 @@ func CreateMenu(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// menuListFields is the sort/filter whitelist for menu list endpoint
+var menuListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":         {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"name":       {Column: "name", Type: listquery.String, Filter: true, Sortable: true},
+	"path":       {Column: "path", Type: listquery.String, Filter: true, Sortable: true},
+	"parent_id":  {Column: "parent_id", Type: listquery.UUID, Filter: true},
+	"sequence":   {Column: "sequence", Type: listquery.Int, Filter: true, Sortable: true},
+	"is_active":  {Column: "is_active", Type: listquery.Bool, Filter: true},
+	"created_at": {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
 // @Summary      Get all menus
-// @Description  Get list of all menus with hierarchical structure
+// @Description  Get list of all menus with hierarchical structure.
+// @Description  Supports sort=name and filters like filter[is_active]=true. In hierarchical mode filters and sort apply to root menus.
 // @Tags         menus
 // @Produce      json
 // @Security     BearerAuth
 // @Param        flat query bool false "Return flat list instead of hierarchical"
+// @Param        sort query string false "Sort fields, prefix - for descending (default: sequence,name)"
 // @Success      200  {array}   MenuResponse
+// @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /menus [get]
 func GetMenus(c *gin.Context) {
 	flat := c.Query("flat") == "true"
 
+	listQuery, ok := bindListQuery(c, menuListFields)
+	if !ok {
+		return
+	}
+
 	var menus []models.Menu
-	query := database.DB.Preload("Roles").Order("sequence ASC, name ASC")
+	query := listQuery.ApplySort(listQuery.ApplyFilters(database.WithContext(c.Request.Context()).Preload("Roles")), "sequence ASC, name ASC")
 	
 	if flat {
 		// Return all menus in flat structure
@@ -120,7 +149,7 @@ func GetMenus(c *gin.Context) {
 		
 		// Load children recursively
 		for i := range menus {
-			loadMenuChildren(&menus[i])
+			loadMenuChildren(database.WithContext(c.Request.Context()), &menus[i])
 		}
 	}
 
@@ -139,6 +168,7 @@ func GetMenus(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      string  true  "Menu ID"
 // @Success      200  {object}  MenuResponse
+// @Header       200  {string}  ETag  "Current version, send back as If-Match on update or delete"
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
 // @Router       /menus/{id} [get]
@@ -150,7 +180,7 @@ func GetMenu(c *gin.Context) {
 	}
 
 	var menu models.Menu
-	if err := database.DB.Preload("Roles").Preload("Children").First(&menu, "id = ?", id).Error; err != nil {
+	if err := database.WithContext(c.Request.Context()).Preload("Roles").Preload("Children").First(&menu, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
 		} else {
@@ -159,6 +189,7 @@ func GetMenu(c *gin.Context) {
 		return
 	}
 
+	setETag(c, menu.UpdatedAt)
 	response := buildMenuResponse(menu)
 	c.JSON(http.StatusOK, response)
 }
@@ -169,11 +200,14 @@ func GetMenu(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id      path    string      true  "Menu ID"
-// @Param        request body    MenuRequest true  "Menu details"
+// @Param        id       path    string      true   "Menu ID"
+// @Param        If-Match header  string      false  "ETag from GET, the request fails with 412 if the menu has changed"
+// @Param        request  body    MenuRequest true   "Menu details"
 // @Success      200  {object}  MenuResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /menus/{id} [put]
 func UpdateMenu(c *gin.Context) {
@@ -190,7 +224,51 @@ func UpdateMenu(c *gin.Context) {
 	}
 
 	var menu models.Menu
-	if err := database.DB.First(&menu, "id = ?", id).Error; err != nil {
+	if err := database.WithContext(c.Request.Context()).First(&menu, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch menu"})
+		}
+		return
+	}
+
+	if !checkIfMatch(c, menu.UpdatedAt) {
+		return
+	}
+
+	saveMenu(c, &menu, req)
+}
+
+// @Summary      Patch menu
+// @Description  Partially update a menu with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) body.
+// @Description  Fields left out keep their value (is_active is no longer reset), only the changed fields are written.
+// @Tags         menus
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path    string      true   "Menu ID"
+// @Param        If-Match header  string      false  "ETag from GET, the request fails with 412 if the menu has changed"
+// @Param        request  body    MenuRequest true   "Only the fields to change"
+// @Success      200  {object}  MenuResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      415  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /menus/{id} [patch]
+func PatchMenu(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu ID"})
+		return
+	}
+
+	var menu models.Menu
+	if err := database.WithContext(c.Request.Context()).First(&menu, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
 		} else {
@@ -199,16 +277,49 @@ func UpdateMenu(c *gin.Context) {
 		return
 	}
 
+	if !checkIfMatch(c, menu.UpdatedAt) {
+		return
+	}
+
+	current := MenuRequest{
+		Name:        menu.Name,
+		Path:        menu.Path,
+		Icon:        menu.Icon,
+		ParentID:    menu.ParentID,
+		Sequence:    menu.Sequence,
+		IsActive:    menu.IsActive,
+		Description: menu.Description,
+	}
+
+	var req MenuRequest
+	columns, ok := bindPatch(c, current, &req)
+	if !ok {
+		return
+	}
+	if len(columns) == 0 {
+		setETag(c, menu.UpdatedAt)
+		c.JSON(http.StatusOK, buildMenuResponse(menu))
+		return
+	}
+
+	saveMenu(c, &menu, req, columns...)
+}
+
+// saveMenu validates the parent, applies req to menu and writes it, only the given columns when set (PATCH)
+func saveMenu(c *gin.Context, menu *models.Menu, req MenuRequest, columns ...string) {
+	since := menu.UpdatedAt
+	before := *menu
+
 	// Validate parent menu exists if ParentID is provided
 	if req.ParentID != nil && *req.ParentID != menu.ID {
 		var parentMenu models.Menu
-		if err := database.DB.First(&parentMenu, "id = ?", *req.ParentID).Error; err != nil {
+		if err := database.WithContext(c.Request.Context()).First(&parentMenu, "id = ?", *req.ParentID).Error; err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent menu not found"})
 			return
 		}
 		
 		// Prevent circular reference
-		if isCircularReference(*req.ParentID, menu.ID) {
+		if isCircularReference(database.WithContext(c.Request.Context()), *req.ParentID, menu.ID) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Circular reference detected"})
 			return
 		}
@@ -223,10 +334,22 @@ func UpdateMenu(c *gin.Context) {
 	menu.IsActive = req.IsActive
 	menu.Description = req.Description
 
-	if err := database.DB.Save(&menu).Error; err != nil {
+	var saved bool
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if saved, err = saveIfUnmodified(tx, menu, since, columns...); err != nil || !saved {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceMenu, menu.ID, before, menu)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu"})
 		return
 	}
+	if !saved {
+		respondPreconditionFailed(c, menu.UpdatedAt)
+		return
+	}
 
 	response := MenuResponse{
 		ID:          menu.ID,
@@ -239,6 +362,7 @@ func UpdateMenu(c *gin.Context) {
 		Description: menu.Description,
 	}
 
+	setETag(c, menu.UpdatedAt)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -246,10 +370,13 @@ func UpdateMenu(c *gin.Context) {
 // @Description  Delete menu by ID
 // @Tags         menus
 // @Security     BearerAuth
-// @Param        id   path      string  true  "Menu ID"
+// @Param        id        path      string  true   "Menu ID"
+// @Param        If-Match  header    string  false  "ETag from GET, the request fails with 412 if the menu has changed"
 // @Success      200  {object}  SuccessResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /menus/{id} [delete]
 func DeleteMenu(c *gin.Context) {
@@ -259,22 +386,40 @@ func DeleteMenu(c *gin.Context) {
 		return
 	}
 
+	var menu models.Menu
+	if err := database.WithContext(c.Request.Context()).First(&menu, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
+		return
+	}
+
+	if !checkIfMatch(c, menu.UpdatedAt) {
+		return
+	}
+
 	// Check if menu has children
 	var childCount int64
-	database.DB.Model(&models.Menu{}).Where("parent_id = ?", id).Count(&childCount)
+	database.WithContext(c.Request.Context()).Model(&models.Menu{}).Where("parent_id = ?", id).Count(&childCount)
 	if childCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete menu with children. Delete children first."})
 		return
 	}
 
-	result := database.DB.Delete(&models.Menu{}, "id = ?", id)
-	if result.Error != nil {
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("updated_at = ?", menu.UpdatedAt).Delete(&models.Menu{}, "id = ?", id)
+		if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		return recordAudit(tx, c, audit.ActionDelete, audit.ResourceMenu, menu.ID, menu, nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete menu"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
+	if rowsAffected == 0 {
+		database.WithContext(c.Request.Context()).Select("updated_at").First(&menu)
+		respondPreconditionFailed(c, menu.UpdatedAt)
 		return
 	}
 
@@ -307,20 +452,31 @@ func AssignRoleToMenu(c *gin.Context) {
 
 	// Check if menu exists
 	var menu models.Menu
-	if err := database.DB.First(&menu, "id = ?", menuID).Error; err != nil {
+	if err := database.WithContext(c.Request.Context()).First(&menu, "id = ?", menuID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
 		return
 	}
 
 	// Check if role exists
 	var role models.Role
-	if err := database.DB.First(&role, "id = ?", roleID).Error; err != nil {
+	if err := database.WithContext(c.Request.Context()).First(&role, "id = ?", roleID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
 	// Assign role to menu
-	if err := database.DB.Model(&menu).Association("Roles").Append(&role); err != nil {
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Table("map_role_menu").Where("menu_id = ? AND role_id = ?", menu.ID, role.ID).Count(&existing).Error; err != nil || existing > 0 {
+			return err
+		}
+
+		if err := tx.Model(&menu).Association("Roles").Append(&role); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionAssign, audit.ResourceRoleMenu, menu.ID, nil, roleMenuAudit(menu, role))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign role to menu"})
 		return
 	}
@@ -354,20 +510,27 @@ func RemoveRoleFromMenu(c *gin.Context) {
 
 	// Check if menu exists
 	var menu models.Menu
-	if err := database.DB.First(&menu, "id = ?", menuID).Error; err != nil {
+	if err := database.WithContext(c.Request.Context()).First(&menu, "id = ?", menuID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
 		return
 	}
 
 	// Check if role exists
 	var role models.Role
-	if err := database.DB.First(&role, "id = ?", roleID).Error; err != nil {
+	if err := database.WithContext(c.Request.Context()).First(&role, "id = ?", roleID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
 	// Remove role from menu
-	if err := database.DB.Model(&menu).Association("Roles").Delete(&role); err != nil {
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("DELETE FROM map_role_menu WHERE menu_id = ? AND role_id = ?", menu.ID, role.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return recordAudit(tx, c, audit.ActionUnassign, audit.ResourceRoleMenu, menu.ID, roleMenuAudit(menu, role), nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove role from menu"})
 		return
 	}
@@ -397,6 +560,17 @@ func GetUserMenus(c *gin.Context) {
 		return
 	}
 
+	response, err := userMenuTree(database.WithContext(c.Request.Context()), roles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user menus"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// userMenuTree builds the active menu tree accessible by the given roles
+func userMenuTree(db *gorm.DB, roles []string) ([]MenuResponse, error) {
 	// Check if user is super_admin
 	isSuperAdmin := false
 	for _, role := range roles {
@@ -408,56 +582,64 @@ func GetUserMenus(c *gin.Context) {
 
 	// Get menus accessible by user roles
 	var menus []models.Menu
-	query := database.DB.Preload("Roles").
+	query := db.Preload("Roles").
 		Joins("JOIN map_role_menu ON menus.id = map_role_menu.menu_id").
 		Joins("JOIN roles ON map_role_menu.role_id = roles.id").
 		Where("roles.name IN ? AND menus.is_active = ?", roles, true).
 		Where("menus.parent_id IS NULL").
+		Where("menus.id NOT IN (?)", deniedMenuIDs(db, roles)).
 		Order("menus.sequence ASC, menus.name ASC").
 		Distinct()
 
 	if err := query.Find(&menus).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user menus"})
-		return
+		return nil, err
 	}
 
 	// Load children recursively (only accessible ones)
 	for i := range menus {
-		loadUserMenuChildren(&menus[i], roles)
+		loadUserMenuChildren(db, &menus[i], roles)
 	}
 
-	var response []MenuResponse
+	response := []MenuResponse{}
 	for _, menu := range menus {
 		response = append(response, buildUserMenuResponse(menu, isSuperAdmin))
 	}
 
-	c.JSON(http.StatusOK, response)
+	return response, nil
 }
 
 // Helper functions
-func loadMenuChildren(menu *models.Menu) {
-	database.DB.Preload("Roles").Where("parent_id = ?", menu.ID).Order("sequence ASC, name ASC").Find(&menu.Children)
+func loadMenuChildren(db *gorm.DB, menu *models.Menu) {
+	db.Preload("Roles").Where("parent_id = ?", menu.ID).Order("sequence ASC, name ASC").Find(&menu.Children)
 	for i := range menu.Children {
-		loadMenuChildren(&menu.Children[i])
+		loadMenuChildren(db, &menu.Children[i])
 	}
 }
 
-func loadUserMenuChildren(menu *models.Menu, userRoles []string) {
+func loadUserMenuChildren(db *gorm.DB, menu *models.Menu, userRoles []string) {
 	var children []models.Menu
-	database.DB.Preload("Roles").
+	db.Preload("Roles").
 		Joins("JOIN map_role_menu ON menus.id = map_role_menu.menu_id").
 		Joins("JOIN roles ON map_role_menu.role_id = roles.id").
 		Where("roles.name IN ? AND menus.is_active = ? AND menus.parent_id = ?", userRoles, true, menu.ID).
+		Where("menus.id NOT IN (?)", deniedMenuIDs(db, userRoles)).
 		Order("menus.sequence ASC, menus.name ASC").
 		Distinct().
 		Find(&children)
 
 	menu.Children = children
 	for i := range menu.Children {
-		loadUserMenuChildren(&menu.Children[i], userRoles)
+		loadUserMenuChildren(db, &menu.Children[i], userRoles)
 	}
 }
 
+// deniedMenuIDs returns subquery of menu IDs denied for any of the given roles
+func deniedMenuIDs(db *gorm.DB, roles []string) *gorm.DB {
+	return db.Model(&models.RoleMenuDeny{}).
+		Select("menu_id").
+		Where("role_id IN (SELECT id FROM roles WHERE name IN ?)", roles)
+}
+
 func buildMenuResponse(menu models.Menu) MenuResponse {
 	var roles []string
 	for _, role := range menu.Roles {
@@ -514,9 +696,9 @@ func buildUserMenuResponse(menu models.Menu, includeRoles bool) MenuResponse {
 	return response
 }
 
-func isCircularReference(parentID, menuID uuid.UUID) bool {
+func isCircularReference(db *gorm.DB, parentID, menuID uuid.UUID) bool {
 	var menu models.Menu
-	if err := database.DB.First(&menu, "id = ?", parentID).Error; err != nil {
+	if err := db.First(&menu, "id = ?", parentID).Error; err != nil {
 		return false
 	}
 
@@ -528,5 +710,157 @@ func isCircularReference(parentID, menuID uuid.UUID) bool {
 		return true
 	}
 
-	return isCircularReference(*menu.ParentID, menuID)
+	return isCircularReference(db, *menu.ParentID, menuID)
+}
+
+// @Summary      Get menu deny rules
+// @Description  Get list of menu deny rules, optionally filtered by role
+// @Tags         menu-assignments
+// @Produce      json
+// @Security     BearerAuth
+// @Param        role_id  query     string  false  "Filter by role ID"
+// @Success      200  {array}   models.RoleMenuDeny
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /menu-assignments/denies [get]
+func GetMenuDenyRules(c *gin.Context) {
+	query := database.WithContext(c.Request.Context()).Preload("Role").Preload("Menu").Order("created_at DESC")
+
+	if roleIDParam := c.Query("role_id"); roleIDParam != "" {
+		roleID, err := uuid.Parse(roleIDParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+			return
+		}
+		query = query.Where("role_id = ?", roleID)
+	}
+
+	var rules []models.RoleMenuDeny
+	if err := query.Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch menu deny rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// @Summary      Deny menu for role
+// @Description  Explicitly hide a menu from a role. Deny rules always override grants.
+// @Tags         menu-assignments
+// @Accept       json
+// @Security     BearerAuth
+// @Param        menu_id path string          true  "Menu ID"
+// @Param        role_id path string          true  "Role ID"
+// @Param        request body DenyRuleRequest false "Deny reason"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /menu-assignments/menus/{menu_id}/roles/{role_id}/deny [post]
+func DenyMenuForRole(c *gin.Context) {
+	menuID, err := uuid.Parse(c.Param("menu_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu ID"})
+		return
+	}
+
+	roleID, err := uuid.Parse(c.Param("role_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var req DenyRuleRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	// Check if menu exists
+	var menu models.Menu
+	if err := database.WithContext(c.Request.Context()).First(&menu, "id = ?", menuID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Menu not found"})
+		return
+	}
+
+	// Check if role exists
+	var role models.Role
+	if err := database.WithContext(c.Request.Context()).First(&role, "id = ?", roleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	rule := models.RoleMenuDeny{
+		RoleID: role.ID,
+		MenuID: menu.ID,
+		Reason: req.Reason,
+	}
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rule)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return recordAudit(tx, c, audit.ActionDeny, audit.ResourceRoleMenuDeny, menu.ID, nil, roleMenuDenyAudit(rule))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deny menu for role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu denied for role successfully"})
+}
+
+// @Summary      Remove menu deny rule
+// @Description  Remove an explicit deny rule between a menu and a role
+// @Tags         menu-assignments
+// @Security     BearerAuth
+// @Param        menu_id path string true "Menu ID"
+// @Param        role_id path string true "Role ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /menu-assignments/menus/{menu_id}/roles/{role_id}/deny [delete]
+func RemoveMenuDenyFromRole(c *gin.Context) {
+	menuID, err := uuid.Parse(c.Param("menu_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu ID"})
+		return
+	}
+
+	roleID, err := uuid.Parse(c.Param("role_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var rule models.RoleMenuDeny
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Returning{}).Delete(&rule, "menu_id = ? AND role_id = ?", menuID, roleID)
+		if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		return recordAudit(tx, c, audit.ActionUndeny, audit.ResourceRoleMenuDeny, menuID, roleMenuDenyAudit(rule), nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove menu deny rule"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deny rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu deny rule removed successfully"})
+}
+
+// roleMenuAudit is the audit snapshot of a map_role_menu row
+func roleMenuAudit(menu models.Menu, role models.Role) gin.H {
+	return gin.H{"menu_id": menu.ID, "menu_name": menu.Name, "role_id": role.ID, "role_name": role.Name}
+}
+
+// roleMenuDenyAudit is the audit snapshot of a deny rule without its relations
+func roleMenuDenyAudit(rule models.RoleMenuDeny) gin.H {
+	return gin.H{"menu_id": rule.MenuID, "role_id": rule.RoleID, "reason": rule.Reason, "created_at": rule.CreatedAt}
 }
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"sjek/internal/database"
+	"sjek/internal/models"
+	"sjek/internal/notification"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	inviteTokenTTL = 7 * 24 * time.Hour
+	resetTokenTTL  = 1 * time.Hour
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func hashPasswordToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createPasswordToken stores a new one-time token and returns its plain value
+func createPasswordToken(tx *gorm.DB, userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	passwordToken := models.PasswordToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: hashPasswordToken(token),
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tx.Create(&passwordToken).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// unusablePassword returns a bcrypt hash of random bytes so nobody can login
+// until the password is set through an invitation link
+func unusablePassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	// bcrypt hanya memakai 72 byte pertama
+	hashed, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(buf)[:64]), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// passwordTokenMessage builds invitation or reset mail for the token
+func passwordTokenMessage(user models.User, token, purpose string) notification.Message {
+	baseURL := os.Getenv("PASSWORD_RESET_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080/password/reset"
+	}
+	link := fmt.Sprintf("%s?token=%s", baseURL, token)
+
+	if purpose == models.PasswordTokenInvite {
+		return notification.Message{
+			To:      user.Email,
+			Subject: "You have been invited to SJEK",
+			Body: fmt.Sprintf("Hi %s, an account has been created for you. Set your password within %d days using this link: %s",
+				user.Username, int(inviteTokenTTL.Hours()/24), link),
+		}
+	}
+
+	return notification.Message{
+		To:      user.Email,
+		Subject: "Reset your SJEK password",
+		Body: fmt.Sprintf("Hi %s, use this link within %d minutes to reset your password: %s",
+			user.Username, int(resetTokenTTL.Minutes()), link),
+	}
+}
+
+// @Summary      Request password reset
+// @Description  Send a password reset link to the email if it belongs to a user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "User email"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Router       /password/forgot [post]
+func ForgotPassword(notifier notification.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Response selalu sama agar email yang terdaftar tidak bisa ditebak
+		response := gin.H{"message": "If the email is registered, a reset link has been sent"}
+
+		var user models.User
+		if err := database.WithContext(c.Request.Context()).Where("email = ?", req.Email).First(&user).Error; err != nil {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		token, err := createPasswordToken(database.WithContext(c.Request.Context()), user.ID, models.PasswordTokenReset, resetTokenTTL)
+		if err != nil {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		if err := notifier.Send(passwordTokenMessage(user, token, models.PasswordTokenReset)); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to send password reset mail", "user_id", user.ID, "error", err)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// @Summary      Reset password
+// @Description  Set a new password using an invitation or reset token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Token and new password"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var passwordToken models.PasswordToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+			hashPasswordToken(req.Token), time.Now()).First(&passwordToken).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&passwordToken).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", passwordToken.UserID).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+
+		// Password baru, semua sesi lama tidak berlaku
+		return tx.Model(&models.UserToken{}).Where("user_id = ?", passwordToken.UserID).Update("is_active", false).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully"})
+}
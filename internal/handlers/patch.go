@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"sjek/internal/jsonpatch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// bindPatch applies the request body to current (the resource in its PUT request shape)
+// and binds the result into dest, validated with the same binding rules as PUT.
+// Body application/json diperlakukan sebagai merge patch (RFC 7396).
+// Mengembalikan field JSON yang berubah, sama dengan nama kolom di database
+func bindPatch(c *gin.Context, current interface{}, dest interface{}) ([]string, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return nil, false
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode resource"})
+		return nil, false
+	}
+
+	var merged []byte
+	switch c.ContentType() {
+	case mergePatchContentType, binding.MIMEJSON:
+		merged, err = jsonpatch.MergePatch(original, body)
+	case jsonPatchContentType:
+		merged, err = jsonpatch.Apply(original, body)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + mergePatchContentType + " or " + jsonPatchContentType})
+		return nil, false
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// Field yang tidak dikenal ditolak supaya typo tidak diam-diam diabaikan
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if err := binding.Validator.ValidateStruct(dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	changed, err := changedFields(current, dest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode resource"})
+		return nil, false
+	}
+	return changed, true
+}
+
+// changedFields compares the JSON encoding of before and after and returns the top-level keys that differ
+func changedFields(before, after interface{}) ([]string, error) {
+	var beforeFields, afterFields map[string]interface{}
+	for _, item := range []struct {
+		value  interface{}
+		fields *map[string]interface{}
+	}{{before, &beforeFields}, {after, &afterFields}} {
+		data, err := json.Marshal(item.value)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, item.fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []string
+	for key, value := range afterFields {
+		if !reflect.DeepEqual(value, beforeFields[key]) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
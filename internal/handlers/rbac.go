@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"sjek/internal/audit"
+	"sjek/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+type RBACImportResponse struct {
+	DryRun  bool           `json:"dry_run"`
+	Changes []rbac.Change  `json:"changes"`
+	Summary map[string]int `json:"summary"`
+}
+
+// ExportRBAC exports roles, APIs, menus and their mappings
+// @Summary      Export RBAC configuration
+// @Description  Export complete RBAC state (roles, APIs, menus, grants and deny rules) as YAML or JSON
+// @Tags         rbac
+// @Produce      json
+// @Produce      application/x-yaml
+// @Security     BearerAuth
+// @Param        format  query     string  false  "Output format (yaml/json, default: yaml)"
+// @Success      200  {object}  rbac.Document
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /rbac/export [get]
+func ExportRBAC(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		format := c.DefaultQuery("format", "yaml")
+		if format != "yaml" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, use yaml or json"})
+			return
+		}
+
+		doc, err := rbac.Export(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export RBAC configuration"})
+			return
+		}
+
+		c.Header("Content-Disposition", "attachment; filename=rbac."+format)
+		if format == "json" {
+			c.IndentedJSON(http.StatusOK, doc)
+			return
+		}
+		c.YAML(http.StatusOK, doc)
+	}
+}
+
+// ImportRBAC imports an RBAC document, by default only returning the diff
+// @Summary      Import RBAC configuration
+// @Description  Compare an RBAC document (YAML or JSON) with the database. With dry_run=false the changes are applied in a single transaction.
+// @Tags         rbac
+// @Accept       json
+// @Accept       application/x-yaml
+// @Produce      json
+// @Security     BearerAuth
+// @Param        dry_run  query     bool           false  "Only show diff without applying (default: true)"
+// @Param        request  body      rbac.Document  true   "RBAC document"
+// @Success      200  {object}  RBACImportResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /rbac/import [post]
+func ImportRBAC(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		dryRun := c.DefaultQuery("dry_run", "true") != "false"
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+
+		var doc rbac.Document
+		if strings.Contains(c.ContentType(), "yaml") {
+			err = yaml.Unmarshal(body, &doc)
+		} else {
+			err = json.Unmarshal(body, &doc)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid RBAC document: " + err.Error()})
+			return
+		}
+
+		if err := doc.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var diff *rbac.Diff
+		if dryRun {
+			diff, err = rbac.Plan(db, &doc)
+		} else {
+			diff, err = rbac.Apply(db, &doc, func(tx *gorm.DB, diff *rbac.Diff) error {
+				if len(diff.Changes) == 0 {
+					return nil
+				}
+				return recordAudit(tx, c, audit.ActionImport, audit.ResourceRBAC, "", nil, diff)
+			})
+		}
+		if err != nil {
+			// Dokumen sudah divalidasi di atas, error di sini berasal dari database
+			slog.ErrorContext(c.Request.Context(), "Failed to import RBAC configuration", "dry_run", dryRun, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import RBAC configuration"})
+			return
+		}
+
+		c.JSON(http.StatusOK, RBACImportResponse{
+			DryRun:  dryRun,
+			Changes: diff.Changes,
+			Summary: diff.Summary,
+		})
+	}
+}
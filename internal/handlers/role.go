@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"net/http"
+	"sjek/internal/audit"
 	"sjek/internal/database"
+	"sjek/internal/listquery"
 	"sjek/internal/models"
+	"sjek/internal/webhook"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RoleRequest struct {
@@ -18,6 +24,27 @@ type RoleResponse struct {
 	Name string    `json:"name"`
 }
 
+// AssignRoleRequest berisi masa berlaku assignment, semua field optional
+type AssignRoleRequest struct {
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+type RoleAssignmentResponse struct {
+	RoleID     uuid.UUID  `json:"role_id"`
+	RoleName   string     `json:"role_name"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	GrantedBy  *uuid.UUID `json:"granted_by,omitempty"`
+	IsActive   bool       `json:"is_active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Source     string     `json:"source"` // direct atau group
+	GroupID    *uuid.UUID `json:"group_id,omitempty"`
+	GroupName  string     `json:"group_name,omitempty"`
+}
+
 // @Summary      Create new role
 // @Description  Create a new role
 // @Tags         roles
@@ -41,8 +68,13 @@ func CreateRole(c *gin.Context) {
 		Name: req.Name,
 	}
 
-	result := database.DB.Create(&role)
-	if result.Error != nil {
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionCreate, audit.ResourceRole, role.ID, nil, role)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
 		return
 	}
@@ -50,17 +82,32 @@ func CreateRole(c *gin.Context) {
 	c.JSON(http.StatusCreated, RoleResponse{ID: role.ID, Name: role.Name})
 }
 
+// roleListFields is the sort/filter whitelist for role list endpoint
+var roleListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":         {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"name":       {Column: "name", Type: listquery.String, Filter: true, Sortable: true},
+	"created_at": {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
 // @Summary      Get all roles
-// @Description  Get list of all roles
+// @Description  Get list of all roles. Supports sort=-created_at and filters like name[like]=admin.
 // @Tags         roles
 // @Produce      json
 // @Security     BearerAuth
+// @Param        sort  query     string  false  "Sort fields, prefix - for descending (default: name)"
 // @Success      200  {array}   RoleResponse
+// @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /roles [get]
 func GetRoles(c *gin.Context) {
+	listQuery, ok := bindListQuery(c, roleListFields)
+	if !ok {
+		return
+	}
+
 	var roles []models.Role
-	result := database.DB.Find(&roles)
+	result := listQuery.ApplySort(listQuery.ApplyFilters(database.WithContext(c.Request.Context())), "name ASC").Find(&roles)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
 		return
@@ -81,6 +128,7 @@ func GetRoles(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      string  true  "Role ID"
 // @Success      200  {object}  RoleResponse
+// @Header       200  {string}  ETag  "Current version, send back as If-Match on update or delete"
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
 // @Router       /roles/{id} [get]
@@ -92,12 +140,13 @@ func GetRole(c *gin.Context) {
 	}
 
 	var role models.Role
-	result := database.DB.First(&role, "id = ?", id)
+	result := database.WithContext(c.Request.Context()).First(&role, "id = ?", id)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
+	setETag(c, role.UpdatedAt)
 	c.JSON(http.StatusOK, RoleResponse{ID: role.ID, Name: role.Name})
 }
 
@@ -107,11 +156,14 @@ func GetRole(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id      path    string      true  "Role ID"
-// @Param        request body    RoleRequest true  "Role details"
+// @Param        id       path    string      true   "Role ID"
+// @Param        If-Match header  string      false  "ETag from GET, the request fails with 412 if the role has changed"
+// @Param        request  body    RoleRequest true   "Role details"
 // @Success      200  {object}  RoleResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /roles/{id} [put]
 func UpdateRole(c *gin.Context) {
@@ -128,19 +180,94 @@ func UpdateRole(c *gin.Context) {
 	}
 
 	var role models.Role
-	result := database.DB.First(&role, "id = ?", id)
+	result := database.WithContext(c.Request.Context()).First(&role, "id = ?", id)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
+	if !checkIfMatch(c, role.UpdatedAt) {
+		return
+	}
+
+	saveRole(c, &role, req)
+}
+
+// @Summary      Patch role
+// @Description  Partially update a role with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) body.
+// @Description  The patched result is validated like PUT and only the changed fields are written.
+// @Tags         roles
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path    string      true   "Role ID"
+// @Param        If-Match header  string      false  "ETag from GET, the request fails with 412 if the role has changed"
+// @Param        request  body    RoleRequest true   "Only the fields to change"
+// @Success      200  {object}  RoleResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      415  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /roles/{id} [patch]
+func PatchRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var role models.Role
+	if err := database.WithContext(c.Request.Context()).First(&role, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if !checkIfMatch(c, role.UpdatedAt) {
+		return
+	}
+
+	var req RoleRequest
+	columns, ok := bindPatch(c, RoleRequest{Name: role.Name}, &req)
+	if !ok {
+		return
+	}
+	if len(columns) == 0 {
+		setETag(c, role.UpdatedAt)
+		c.JSON(http.StatusOK, RoleResponse{ID: role.ID, Name: role.Name})
+		return
+	}
+
+	saveRole(c, &role, req, columns...)
+}
+
+// saveRole applies req to role and writes it, only the given columns when set (PATCH)
+func saveRole(c *gin.Context, role *models.Role, req RoleRequest, columns ...string) {
+	since := role.UpdatedAt
+	before := *role
+
 	role.Name = req.Name
-	result = database.DB.Save(&role)
-	if result.Error != nil {
+	var saved bool
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if saved, err = saveIfUnmodified(tx, role, since, columns...); err != nil || !saved {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceRole, role.ID, before, role)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
 		return
 	}
+	if !saved {
+		respondPreconditionFailed(c, role.UpdatedAt)
+		return
+	}
 
+	setETag(c, role.UpdatedAt)
 	c.JSON(http.StatusOK, RoleResponse{ID: role.ID, Name: role.Name})
 }
 
@@ -148,9 +275,13 @@ func UpdateRole(c *gin.Context) {
 // @Description  Delete role by ID
 // @Tags         roles
 // @Security     BearerAuth
-// @Param        id   path      string  true  "Role ID"
+// @Param        id        path      string  true   "Role ID"
+// @Param        If-Match  header    string  false  "ETag from GET, the request fails with 412 if the role has changed"
 // @Success      200  {object}  SuccessResponse
 // @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /roles/{id} [delete]
 func DeleteRole(c *gin.Context) {
@@ -160,21 +291,54 @@ func DeleteRole(c *gin.Context) {
 		return
 	}
 
-	result := database.DB.Delete(&models.Role{}, "id = ?", id)
-	if result.Error != nil {
+	var role models.Role
+	if err := database.WithContext(c.Request.Context()).First(&role, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if !checkIfMatch(c, role.UpdatedAt) {
+		return
+	}
+
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Row dikunci dulu: holder role harus dibaca sebelum delete meng-cascade map_group_role
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("updated_at = ?", role.UpdatedAt).
+			Limit(1).Find(&models.Role{}, "id = ?", id)
+		if rowsAffected = result.RowsAffected; result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		if err := webhook.EnqueueRoleRemoval(tx, role, "Role deleted"); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Role{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionDelete, audit.ResourceRole, role.ID, role, nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
 		return
 	}
 
+	if rowsAffected == 0 {
+		database.WithContext(c.Request.Context()).Select("updated_at").First(&role)
+		respondPreconditionFailed(c, role.UpdatedAt)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
 }
 
 // @Summary      Assign role to user
-// @Description  Assign a role to a user
+// @Description  Assign a role to a user, optionally limited to a validity window. Re-assigning updates the window.
 // @Tags         role-assignments
+// @Accept       json
 // @Security     BearerAuth
-// @Param        role_id path    string true "Role ID"
-// @Param        user_id path    string true "User ID"
+// @Param        role_id path    string            true  "Role ID"
+// @Param        user_id path    string            true  "User ID"
+// @Param        request body    AssignRoleRequest false "Validity window and reason"
 // @Success      200  {object}  SuccessResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
@@ -193,21 +357,89 @@ func AssignRoleToUser(c *gin.Context) {
 		return
 	}
 
+	var req AssignRoleRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	if req.ValidUntil != nil {
+		if !req.ValidUntil.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "valid_until must be in the future"})
+			return
+		}
+		if req.ValidFrom != nil && !req.ValidUntil.After(*req.ValidFrom) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "valid_until must be after valid_from"})
+			return
+		}
+	}
+
 	var user models.User
-	result := database.DB.First(&user, "id = ?", userID)
+	result := database.WithContext(c.Request.Context()).First(&user, "id = ?", userID)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	var role models.Role
-	result = database.DB.First(&role, "id = ?", roleID)
+	result = database.WithContext(c.Request.Context()).First(&role, "id = ?", roleID)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
-	if err := database.DB.Model(&user).Association("Roles").Append(&role); err != nil {
+	assignment := models.UserRole{
+		UserID:     user.ID,
+		RoleID:     role.ID,
+		ValidFrom:  req.ValidFrom,
+		ValidUntil: req.ValidUntil,
+		Reason:     req.Reason,
+	}
+
+	// Catat user yang memberikan role
+	if grantedBy, exists := c.Get("user_id"); exists {
+		if grantedByUUID, err := uuid.Parse(grantedBy.(string)); err == nil {
+			assignment.GrantedBy = &grantedByUUID
+		}
+	}
+
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		roles, err := webhook.SnapshotRoles(tx, []uuid.UUID{user.ID})
+		if err != nil {
+			return err
+		}
+
+		// Assignment lama (jika ada) untuk before di audit
+		var before *models.UserRole
+		var existing models.UserRole
+		if err := tx.Where("user_id = ? AND role_id = ?", user.ID, role.ID).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.UserID != uuid.Nil {
+			before = &existing
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"valid_from", "valid_until", "reason", "granted_by"}),
+		}).Create(&assignment).Error
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, audit.ActionAssign, audit.ResourceUserRole, user.ID, before, assignment); err != nil {
+			return err
+		}
+		// Tidak ada event jika user sudah memiliki role ini, misalnya lewat group
+		if roles.Has(user.ID, role.ID) {
+			return nil
+		}
+		return webhook.Enqueue(tx, webhook.RoleAssigned, webhook.RoleChange{
+			User:       webhook.UserData(user),
+			Role:       webhook.RoleData(role),
+			ValidUntil: assignment.ValidUntil,
+			Reason:     assignment.Reason,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign role to user"})
 		return
 	}
@@ -240,24 +472,104 @@ func RemoveRoleFromUser(c *gin.Context) {
 	}
 
 	var user models.User
-	result := database.DB.First(&user, "id = ?", userID)
+	result := database.WithContext(c.Request.Context()).First(&user, "id = ?", userID)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	var role models.Role
-	result = database.DB.First(&role, "id = ?", roleID)
+	result = database.WithContext(c.Request.Context()).First(&role, "id = ?", roleID)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
-	// Perbaikan: Tidak perlu menyimpan hasil Association().Delete() ke dalam result
-	if err := database.DB.Model(&user).Association("Roles").Delete(&role); err != nil {
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var assignment models.UserRole
+		result := tx.Where("user_id = ? AND role_id = ?", user.ID, role.ID).Limit(1).Find(&assignment)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		roles, err := webhook.SnapshotRoles(tx, []uuid.UUID{user.ID})
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.UserRole{}, "user_id = ? AND role_id = ?", user.ID, role.ID).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, audit.ActionUnassign, audit.ResourceUserRole, user.ID, assignment, nil); err != nil {
+			return err
+		}
+		// role.unassigned hanya dikirim jika role tidak lagi dimiliki lewat group
+		return webhook.EnqueueRoleChanges(tx, roles, "")
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove role from user"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Role removed from user successfully"})
 }
+
+// @Summary      Get user role assignments
+// @Description  Get all role assignments of a user including validity window, direct and through group membership (source=group)
+// @Tags         role-assignments
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id path    string true "User ID"
+// @Success      200  {array}   RoleAssignmentResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /role-assignments/users/{user_id} [get]
+func GetUserRoleAssignments(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var assignments []models.UserRole
+	if err := database.WithContext(c.Request.Context()).Preload("Role").Where("user_id = ?", userID).Order("created_at ASC").Find(&assignments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role assignments"})
+		return
+	}
+
+	now := time.Now()
+	var response []RoleAssignmentResponse
+	for _, assignment := range assignments {
+		response = append(response, RoleAssignmentResponse{
+			RoleID:     assignment.RoleID,
+			RoleName:   assignment.Role.Name,
+			ValidFrom:  assignment.ValidFrom,
+			ValidUntil: assignment.ValidUntil,
+			Reason:     assignment.Reason,
+			GrantedBy:  assignment.GrantedBy,
+			IsActive:   assignment.IsActiveAt(now),
+			CreatedAt:  assignment.CreatedAt,
+			Source:     "direct",
+		})
+	}
+
+	groupRoles, err := loadUserGroupRoles(database.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role assignments"})
+		return
+	}
+	for _, groupRole := range groupRoles {
+		groupID := groupRole.GroupID
+		response = append(response, RoleAssignmentResponse{
+			RoleID:    groupRole.RoleID,
+			RoleName:  groupRole.RoleName,
+			GrantedBy: groupRole.GrantedBy,
+			IsActive:  true,
+			CreatedAt: groupRole.CreatedAt,
+			Source:    "group",
+			GroupID:   &groupID,
+			GroupName: groupRole.GroupName,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
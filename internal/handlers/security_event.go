@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// securityEventHeartbeat keeps idle streams open through proxies
+const securityEventHeartbeat = 15 * time.Second
+
+// roleChangeResources are the audit resource types published as role.changed events
+var roleChangeResources = map[string]bool{
+	audit.ResourceRole:         true,
+	audit.ResourceUserRole:     true,
+	audit.ResourceGroupRole:    true,
+	audit.ResourceRoleAPI:      true,
+	audit.ResourceRoleMenu:     true,
+	audit.ResourceRoleAPIDeny:  true,
+	audit.ResourceRoleMenuDeny: true,
+	audit.ResourceRBAC:         true,
+}
+
+// publishSecurityEvent fills the actor, IP and request ID from the request and publishes
+// the event on db. Dengan db transaksi, event terkirim saat commit
+func publishSecurityEvent(db *gorm.DB, c *gin.Context, event events.Event) error {
+	if actorID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		event.ActorID = &actorID
+	}
+	if event.IPAddress == "" {
+		event.IPAddress = c.ClientIP()
+	}
+	event.RequestID = c.GetString("request_id")
+	return events.Publish(db, event)
+}
+
+// parseEventTypes reads the comma separated types filter; empty means all types
+func parseEventTypes(param string) ([]events.Type, error) {
+	var types []events.Type
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !events.Type(name).Valid() {
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+		types = append(types, events.Type(name))
+	}
+	return types, nil
+}
+
+// @Summary      Stream security events
+// @Description  Server-Sent Events stream of security events from all replicas: login.failed, user.locked,
+// @Description  token.revoked, role.changed and access.denied. Each message has id, event (the type) and
+// @Description  data (the event as JSON). A comment line is sent every 15 seconds as heartbeat.
+// @Description  Events published while the client is disconnected are not replayed.
+// @Tags         security-events
+// @Produce      text/event-stream
+// @Security     BearerAuth
+// @Param        types  query     string  false  "Comma separated event types to receive (default: all)"
+// @Success      200    {object}  events.Event
+// @Failure      400    {object}  ErrorResponse
+// @Failure      503    {object}  ErrorResponse
+// @Router       /security-events/stream [get]
+func StreamSecurityEvents(bus *events.Bus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		types, err := parseEventTypes(c.Query("types"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if bus == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Security event stream is not available"})
+			return
+		}
+
+		sub := bus.Subscribe(types...)
+		defer sub.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // nginx tidak boleh mem-buffer stream
+		c.Status(http.StatusOK)
+		fmt.Fprint(c.Writer, ": connected\n\n")
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(securityEventHeartbeat)
+		defer heartbeat.Stop()
+
+		ctx := c.Request.Context()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(c.Writer, ": ping\n\n")
+			case event, ok := <-sub.C:
+				if !ok {
+					// Bus ditutup saat server shutdown
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to encode security event", "event_id", event.ID, "error", err)
+					continue
+				}
+				fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			}
+			c.Writer.Flush()
+		}
+	}
+}
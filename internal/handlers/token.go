@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"net/http"
+	"sjek/internal/audit"
 	"sjek/internal/database"
+	"sjek/internal/events"
+	"sjek/internal/listquery"
 	"sjek/internal/models"
+	"sjek/internal/webhook"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type TokenResponse struct {
@@ -20,6 +25,17 @@ type TokenResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// tokenListFields is the sort/filter whitelist for token list endpoints
+var tokenListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":         {Column: "id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"user_id":    {Column: "user_id", Type: listquery.UUID, Filter: true},
+	"ip_address": {Column: "ip_address", Type: listquery.String, Filter: true, Sortable: true},
+	"user_agent": {Column: "user_agent", Type: listquery.String, Filter: true, Sortable: true},
+	"is_active":  {Column: "is_active", Type: listquery.Bool, Filter: true},
+	"expires_at": {Column: "expires_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"created_at": {Column: "created_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
 // @Summary      Logout user
 // @Description  Logout user by deactivating current token
 // @Tags         auth
@@ -43,8 +59,19 @@ func Logout(c *gin.Context) {
 	}
 
 	// Deactivate token
-	result := database.DB.Model(&models.UserToken{}).Where("id = ?", tokenUUID).Update("is_active", false)
-	if result.Error != nil {
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).Where("id = ?", tokenUUID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		return webhook.Enqueue(tx, webhook.SessionRevoked, webhook.Session{
+			UserID:   userID,
+			Username: c.GetString("username"),
+			TokenID:  &tokenUUID,
+			Reason:   "logout",
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
 		return
 	}
@@ -53,10 +80,11 @@ func Logout(c *gin.Context) {
 }
 
 // @Summary      Get user active tokens
-// @Description  Get all active tokens for current user
+// @Description  Get all active tokens for current user. Supports sort=-created_at and filters like ip_address[like]=10.0.
 // @Tags         tokens
 // @Produce      json
 // @Security     BearerAuth
+// @Param        sort  query     string  false  "Sort fields, prefix - for descending (default: -created_at)"
 // @Success      200  {array}   TokenResponse
 // @Failure      401  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
@@ -75,10 +103,16 @@ func GetUserTokens(c *gin.Context) {
 		return
 	}
 
+	listQuery, ok := bindListQuery(c, tokenListFields)
+	if !ok {
+		return
+	}
+
 	// Get active tokens
 	var tokens []models.UserToken
-	result := database.DB.Where("user_id = ? AND is_active = ? AND expires_at > ?", 
-		userUUID, true, time.Now()).Order("created_at DESC").Find(&tokens)
+	query := database.WithContext(c.Request.Context()).Where("user_id = ? AND is_active = ? AND expires_at > ?", 
+		userUUID, true, time.Now())
+	result := listQuery.ApplySort(listQuery.ApplyFilters(query), "created_at DESC").Find(&tokens)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
 		return
@@ -113,12 +147,6 @@ func GetUserTokens(c *gin.Context) {
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tokens/{id} [delete]
 func RevokeToken(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
-		return
-	}
-
 	tokenID := c.Param("id")
 	tokenUUID, err := uuid.Parse(tokenID)
 	if err != nil {
@@ -126,23 +154,38 @@ func RevokeToken(c *gin.Context) {
 		return
 	}
 
-	userUUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
-	// Check if token belongs to user
+	// Kepemilikan token dicek oleh policy.OwnToken pada route
 	var token models.UserToken
-	result := database.DB.Where("id = ? AND user_id = ?", tokenUUID, userUUID).First(&token)
+	result := database.WithContext(c.Request.Context()).Where("id = ?", tokenUUID).First(&token)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
 		return
 	}
 
-	// Deactivate token
-	result = database.DB.Model(&token).Update("is_active", false)
-	if result.Error != nil {
+	// Deactivate token, event token.revoked terkirim hanya jika update tersimpan
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&token).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := webhook.Enqueue(tx, webhook.SessionRevoked, webhook.Session{
+			UserID:    token.UserID,
+			TokenID:   &token.ID,
+			IPAddress: token.IPAddress,
+			UserAgent: token.UserAgent,
+			Reason:    "revoked",
+		}); err != nil {
+			return err
+		}
+		if err := publishSecurityEvent(tx, c, events.Event{
+			Type:   events.TypeTokenRevoked,
+			UserID: &token.UserID,
+			Data:   map[string]interface{}{"token_id": token.ID},
+		}); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionRevoke, audit.ResourceToken, token.ID, tokenAudit(token), nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
 		return
 	}
@@ -172,11 +215,45 @@ func RevokeAllTokens(c *gin.Context) {
 	}
 
 	// Deactivate all tokens for user
-	result := database.DB.Model(&models.UserToken{}).Where("user_id = ?", userUUID).Update("is_active", false)
-	if result.Error != nil {
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserToken{}).Where("user_id = ? AND is_active = ?", userUUID, true).Update("is_active", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := webhook.Enqueue(tx, webhook.SessionRevoked, webhook.Session{
+			UserID:   userUUID,
+			Username: c.GetString("username"),
+			Reason:   "revoked_all",
+			Count:    result.RowsAffected,
+		}); err != nil {
+			return err
+		}
+		if err := publishSecurityEvent(tx, c, events.Event{
+			Type:     events.TypeTokenRevoked,
+			UserID:   &userUUID,
+			Username: c.GetString("username"),
+			Data:     map[string]interface{}{"scope": "all", "count": result.RowsAffected},
+		}); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionRevoke, audit.ResourceToken, userUUID, nil, gin.H{"user_id": userUUID, "scope": "all", "count": result.RowsAffected})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "All tokens revoked successfully"})
-}
\ No newline at end of file
+}
+
+// tokenAudit is the audit snapshot of a token, tanpa nilai token
+func tokenAudit(token models.UserToken) gin.H {
+	return gin.H{
+		"id":         token.ID,
+		"user_id":    token.UserID,
+		"ip_address": token.IPAddress,
+		"user_agent": token.UserAgent,
+		"expires_at": token.ExpiresAt,
+		"is_active":  token.IsActive,
+	}
+}
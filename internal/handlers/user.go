@@ -2,26 +2,49 @@ package handlers
 
 import (
 	"net/http"
+	"sjek/internal/audit"
 	"sjek/internal/database"
+	"sjek/internal/listquery"
 	"sjek/internal/models"
+	"sjek/internal/webhook"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserResponse struct {
-	ID            uuid.UUID         `json:"id"`
-	Username      string            `json:"username"`
-	Email         string            `json:"email"`
-	Type          models.UserType   `json:"type"`
-	Status        models.UserStatus `json:"status"`
-	ActivatedDate *time.Time        `json:"activated_date,omitempty"`
-	InactiveDate  *time.Time        `json:"inactive_date,omitempty"`
-	Roles         []string          `json:"roles"`
+	ID             uuid.UUID         `json:"id"`
+	Username       string            `json:"username"`
+	Email          string            `json:"email"`
+	Type           models.UserType   `json:"type"`
+	Status         models.UserStatus `json:"status"`
+	ActivatedDate  *time.Time        `json:"activated_date,omitempty"`
+	InactiveDate   *time.Time        `json:"inactive_date,omitempty"`
+	SuspendedUntil *time.Time        `json:"suspended_until,omitempty"`
+	SuspendReason  string            `json:"suspend_reason,omitempty"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+	Roles          []string          `json:"roles"`
+	Search         *UserSearchMatch  `json:"search,omitempty"`
 }
 
+// userListFields is the sort/filter whitelist for user list endpoints
+var userListFields = listquery.Resource{Fields: map[string]listquery.Field{
+	"id":              {Column: "users.id", Type: listquery.UUID, Filter: true, Sortable: true},
+	"username":        {Column: "users.username", Type: listquery.String, Filter: true, Sortable: true},
+	"email":           {Column: "users.email", Type: listquery.String, Filter: true, Sortable: true},
+	"type":            {Column: "users.type", Type: listquery.String, Filter: true, Sortable: true},
+	"status":          {Column: "users.status", Type: listquery.String, Filter: true, Sortable: true},
+	"activated_date":  {Column: "users.activated_date", Type: listquery.Time, Filter: true, Sortable: true, Nullable: true},
+	"inactive_date":   {Column: "users.inactive_date", Type: listquery.Time, Filter: true, Sortable: true, Nullable: true},
+	"suspended_until": {Column: "users.suspended_until", Type: listquery.Time, Filter: true, Sortable: true, Nullable: true},
+	"created_at":      {Column: "users.created_at", Type: listquery.Time, Filter: true, Sortable: true},
+	"updated_at":      {Column: "users.updated_at", Type: listquery.Time, Filter: true, Sortable: true},
+}}
+
 // Struct untuk update user
 type UpdateUserRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -31,15 +54,29 @@ type UpdateUserRequest struct {
 
 // Update GetUsers function untuk include field baru
 // @Summary      Get all users with pagination and filters
-// @Description  Get all users with pagination, filter by type, email contains, username contains
+// @Description  Get all users with pagination, filter by type, email contains, username contains.
+// @Description  Parameter q runs full-text and fuzzy search over username, email and driver details, ordered by relevance with highlights.
+// @Description  Supports sort=-created_at,username and filters like filter[status][in]=ACTIVE,SUSPENDED or created_at[gte]=2024-01-01.
+// @Description  Send pagination=cursor, after or before for keyset pagination; the response is then models.CursorPaginatedResponse
+// @Description  with next_cursor/prev_cursor (relevance ordering of q is not applied in cursor mode).
 // @Tags         users
 // @Produce      json
 // @Security     BearerAuth
-// @Param        page     query     int     false  "Page number (default: 1)"
-// @Param        limit    query     int     false  "Items per page (default: 10, max: 100)"
-// @Param        type     query     string  false  "Filter by user type (ADMIN/DRIVER)"
-// @Param        email    query     string  false  "Filter by email contains"
-// @Param        username query     string  false  "Filter by username contains"
+// @Param        page      query     int     false  "Page number (default: 1)"
+// @Param        limit     query     int     false  "Items per page (default: 10, max: 100)"
+// @Param        pagination query    string  false  "Set to cursor for keyset pagination"
+// @Param        after     query     string  false  "Cursor for the next page"
+// @Param        before    query     string  false  "Cursor for the previous page"
+// @Param        with_total query    bool    false  "Count total rows in cursor mode"
+// @Param        q         query     string  false  "Search username, email, vehicle plate and license number"
+// @Param        sort      query     string  false  "Sort fields, prefix - for descending (e.g. -created_at,username)"
+// @Param        type      query     string  false  "Filter by user type (ADMIN/DRIVER)"
+// @Param        status    query     string  false  "Filter by status (ACTIVE/INACTIVE/SUSPENDED)"
+// @Param        email     query     string  false  "Filter by email contains"
+// @Param        username  query     string  false  "Filter by username contains"
+// @Param        from_date query     string  false  "Filter created from date (YYYY-MM-DD)"
+// @Param        to_date   query     string  false  "Filter created to date (YYYY-MM-DD)"
+// @Param        deleted   query     bool    false  "List soft deleted users instead of active ones"
 // @Success      200  {object}  models.PaginatedResponse{data=[]UserResponse}
 // @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
@@ -56,11 +93,6 @@ func GetUsers(c *gin.Context) {
 		return
 	}
 
-	// Get filter parameters
-	typeFilter := c.Query("type")
-	emailFilter := c.Query("email")
-	usernameFilter := c.Query("username")
-
 	// Validasi input
 	if pagination.Page < 1 {
 		pagination.Page = 1
@@ -75,18 +107,38 @@ func GetUsers(c *gin.Context) {
 	// Hitung offset
 	pagination.Offset = (pagination.Page - 1) * pagination.Limit
 
+	listQuery, ok := bindListQuery(c, userListFields)
+	if !ok {
+		return
+	}
+
 	// Build query dengan filter
-	query := database.DB.Model(&models.User{})
+	query := listQuery.ApplyFilters(applyUserFilters(c, database.WithContext(c.Request.Context()).Model(&models.User{})))
+	q := strings.TrimSpace(c.Query("q"))
 
-	// Apply filters
-	if typeFilter != "" {
-		query = query.Where("type = ?", typeFilter)
-	}
-	if emailFilter != "" {
-		query = query.Where("email ILIKE ?", "%"+emailFilter+"%")
+	keyset, ok := bindKeyset(c, userListFields, listQuery, "created_at", pagination.Limit)
+	if !ok {
+		return
 	}
-	if usernameFilter != "" {
-		query = query.Where("username ILIKE ?", "%"+usernameFilter+"%")
+	if keyset != nil {
+		var users []models.User
+		cursorPagination, err := findCursorPage(c, keyset, query, &users)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+
+		response, err := buildUserListResponse(database.WithContext(c.Request.Context()), q, users)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rank search results"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+			Data:       response,
+			Pagination: cursorPagination,
+		})
+		return
 	}
 
 	// Hitung total records dengan filter
@@ -97,31 +149,27 @@ func GetUsers(c *gin.Context) {
 	}
 	pagination.Total = total
 
+	// Tanpa sort eksplisit, hasil pencarian diurutkan berdasarkan relevansi
+	if q != "" && !listQuery.HasSort() {
+		query = orderByUserSearchRank(query, q)
+	} else {
+		query = listQuery.ApplySort(query, "users.created_at ASC")
+	}
+	query = query.Order("users.id ASC")
+
 	// Ambil data dengan pagination dan filter
 	var users []models.User
-	result := query.Preload("Roles").Offset(pagination.Offset).Limit(pagination.Limit).Find(&users)
+	result := query.Offset(pagination.Offset).Limit(pagination.Limit).Find(&users)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
 	// Transform ke response format
-	var response []UserResponse
-	for _, user := range users {
-		var roles []string
-		for _, role := range user.Roles {
-			roles = append(roles, role.Name)
-		}
-		response = append(response, UserResponse{
-			ID:            user.ID,
-			Username:      user.Username,
-			Email:         user.Email,
-			Type:          user.Type,
-			Status:        user.Status,
-			ActivatedDate: user.ActivatedDate,
-			InactiveDate:  user.InactiveDate,
-			Roles:         roles,
-		})
+	response, err := buildUserListResponse(database.WithContext(c.Request.Context()), q, users)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rank search results"})
+		return
 	}
 
 	// Return response
@@ -131,6 +179,66 @@ func GetUsers(c *gin.Context) {
 	})
 }
 
+// buildUserListResponse maps users to response format with their active roles, plus search rank
+// and highlights when q is set
+func buildUserListResponse(db *gorm.DB, q string, users []models.User) ([]UserResponse, error) {
+	ids := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	roles, err := database.ActiveRoleNamesByUser(db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches map[uuid.UUID]*UserSearchMatch
+	if q != "" {
+		if matches, err = loadUserSearchMatches(db, q, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	var response []UserResponse
+	for _, user := range users {
+		userResponse := buildUserResponse(user, roles[user.ID])
+		userResponse.Search = matches[user.ID]
+		response = append(response, userResponse)
+	}
+	return response, nil
+}
+
+// applyUserFilters applies the user list filters from query parameters
+func applyUserFilters(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if c.Query("deleted") == "true" {
+		// Hanya user yang sudah di-soft delete
+		query = query.Unscoped().Where("users.deleted_at IS NOT NULL")
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = applyUserSearch(query, q)
+	}
+	if typeFilter := c.Query("type"); typeFilter != "" {
+		query = query.Where("users.type = ?", typeFilter)
+	}
+	if statusFilter := c.Query("status"); statusFilter != "" {
+		query = query.Where("users.status = ?", statusFilter)
+	}
+	if emailFilter := c.Query("email"); emailFilter != "" {
+		query = query.Where(`users.email ILIKE ? ESCAPE '\'`, database.ContainsPattern(emailFilter))
+	}
+	if usernameFilter := c.Query("username"); usernameFilter != "" {
+		query = query.Where(`users.username ILIKE ? ESCAPE '\'`, database.ContainsPattern(usernameFilter))
+	}
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		query = query.Where("users.created_at >= ?", fromDate+" 00:00:00")
+	}
+	if toDate := c.Query("to_date"); toDate != "" {
+		query = query.Where("users.created_at <= ?", toDate+" 23:59:59")
+	}
+
+	return query
+}
+
 // @Summary      Get user by ID
 // @Description  Get user details by user ID
 // @Tags         users
@@ -138,8 +246,11 @@ func GetUsers(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        id   path      string  true  "User ID"
 // @Success      200  {object}  UserResponse
+// @Header       200  {string}  ETag  "Current version, send back as If-Match on update or delete"
 // @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
 // @Router       /users/{id} [get]
 func GetUser(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -149,17 +260,19 @@ func GetUser(c *gin.Context) {
 	}
 
 	var user models.User
-	result := database.DB.Preload("Roles").First(&user, "id = ?", id)
+	result := database.WithContext(c.Request.Context()).First(&user, "id = ?", id)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	var roles []string
-	for _, role := range user.Roles {
-		roles = append(roles, role.Name)
+	roles, err := database.ActiveRoleNames(database.WithContext(c.Request.Context()), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user roles"})
+		return
 	}
 
+	setETag(c, user.UpdatedAt)
 	c.JSON(http.StatusOK, UserResponse{
 		ID:       user.ID,
 		Username: user.Username,
@@ -167,13 +280,39 @@ func GetUser(c *gin.Context) {
 	})
 }
 
+// buildUserResponse maps user model and its active roles (see database.ActiveRoles) to response format
+func buildUserResponse(user models.User, roles []string) UserResponse {
+	var deletedAt *time.Time
+	if user.DeletedAt.Valid {
+		deletedAt = &user.DeletedAt.Time
+	}
+
+	return UserResponse{
+		ID:             user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		Type:           user.Type,
+		Status:         user.Status,
+		ActivatedDate:  user.ActivatedDate,
+		InactiveDate:   user.InactiveDate,
+		SuspendedUntil: user.SuspendedUntil,
+		SuspendReason:  user.SuspendReason,
+		DeletedAt:      deletedAt,
+		Roles:          roles,
+	}
+}
+
 // @Summary      Delete user
-// @Description  Delete user by ID
+// @Description  Soft delete user by ID and revoke all tokens. The user can be restored until the retention period ends, after which personal data is anonymized.
 // @Tags         users
 // @Security     BearerAuth
-// @Param        id   path      string  true  "User ID"
+// @Param        id        path      string  true   "User ID"
+// @Param        If-Match  header    string  false  "ETag from GET, the request fails with 412 if the user has changed"
 // @Success      200  {object}  SuccessResponse
 // @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /users/{id} [delete]
 func DeleteUser(c *gin.Context) {
@@ -183,26 +322,116 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
-	result := database.DB.Delete(&models.User{}, "id = ?", id)
-	if result.Error != nil {
+	var user models.User
+	if err := database.WithContext(c.Request.Context()).First(&user, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkIfMatch(c, user.UpdatedAt) {
+		return
+	}
+
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Soft delete, data tetap ada untuk login_logs dan user_tokens
+		result := tx.Where("updated_at = ?", user.UpdatedAt).Delete(&models.User{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+		if rowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.UserToken{}).Where("user_id = ?", id).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, audit.ActionDelete, audit.ResourceUser, user.ID, user, nil); err != nil {
+			return err
+		}
+		return webhook.Enqueue(tx, webhook.UserDeleted, webhook.UserData(user))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
 
+	if rowsAffected == 0 {
+		// Berubah atau sudah dihapus oleh request lain sejak dibaca
+		database.WithContext(c.Request.Context()).Unscoped().Select("updated_at").First(&user)
+		respondPreconditionFailed(c, user.UpdatedAt)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
+// @Summary      Restore deleted user
+// @Description  Restore a soft deleted user that has not been anonymized yet
+// @Tags         users
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/restore [post]
+func RestoreUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var rowsAffected int64
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL AND anonymized_at IS NULL", id).
+			Update("deleted_at", nil)
+		if result.Error != nil || result.RowsAffected == 0 {
+			rowsAffected = result.RowsAffected
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+
+		var user models.User
+		if err := tx.First(&user, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, audit.ActionRestore, audit.ResourceUser, id, nil, user); err != nil {
+			return err
+		}
+		return webhook.Enqueue(tx, webhook.UserRestored, webhook.UserData(user))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted user not found or already anonymized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User restored successfully"})
+}
+
 // @Summary      Update user
 // @Description  Update user details by ID
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id      path    string            true  "User ID"
-// @Param        request body    UpdateUserRequest true  "User details"
+// @Param        id       path    string            true   "User ID"
+// @Param        If-Match header  string            false  "ETag from GET, the request fails with 412 if the user has changed"
+// @Param        request  body    UpdateUserRequest true   "User details"
 // @Success      200  {object}  SuccessResponse
 // @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /users/{id} [put]
 func UpdateUser(c *gin.Context) {
@@ -219,12 +448,77 @@ func UpdateUser(c *gin.Context) {
 	}
 
 	var user models.User
-	result := database.DB.First(&user, "id = ?", id)
+	result := database.WithContext(c.Request.Context()).First(&user, "id = ?", id)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
+	if !checkIfMatch(c, user.UpdatedAt) {
+		return
+	}
+
+	saveUser(c, &user, req)
+}
+
+// @Summary      Patch user
+// @Description  Partially update a user with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) body.
+// @Description  The patched result is validated like PUT and only the changed fields are written.
+// @Tags         users
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path    string            true   "User ID"
+// @Param        If-Match header  string            false  "ETag from GET, the request fails with 412 if the user has changed"
+// @Param        request  body    UpdateUserRequest true   "Only the fields to change"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      415  {object}  ErrorResponse
+// @Failure      428  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id} [patch]
+func PatchUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := database.WithContext(c.Request.Context()).First(&user, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkIfMatch(c, user.UpdatedAt) {
+		return
+	}
+
+	// Password tidak pernah dikembalikan, jadi hanya berubah kalau ada di patch
+	var req UpdateUserRequest
+	columns, ok := bindPatch(c, UpdateUserRequest{Username: user.Username, Email: user.Email}, &req)
+	if !ok {
+		return
+	}
+	if len(columns) == 0 {
+		setETag(c, user.UpdatedAt)
+		c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+		return
+	}
+
+	saveUser(c, &user, req, columns...)
+}
+
+// saveUser applies req to user and writes it, only the given columns when set (PATCH)
+func saveUser(c *gin.Context, user *models.User, req UpdateUserRequest, columns ...string) {
+	since := user.UpdatedAt
+	before := *user
+
 	// Update username dan email
 	user.Username = req.Username
 	user.Email = req.Email
@@ -239,11 +533,26 @@ func UpdateUser(c *gin.Context) {
 		user.Password = string(hashedPassword)
 	}
 
-	result = database.DB.Save(&user)
-	if result.Error != nil {
+	var saved bool
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if saved, err = saveIfUnmodified(tx, user, since, columns...); err != nil || !saved {
+			return err
+		}
+		if err := recordAudit(tx, c, audit.ActionUpdate, audit.ResourceUser, user.ID, before, user); err != nil {
+			return err
+		}
+		return webhook.Enqueue(tx, webhook.UserUpdated, webhook.UserData(*user))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
+	if !saved {
+		respondPreconditionFailed(c, user.UpdatedAt)
+		return
+	}
 
+	setETag(c, user.UpdatedAt)
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/models"
+	"sjek/internal/notification"
+	"sjek/internal/spreadsheet"
+	"sjek/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportRows    = 5000
+	maxImportSize    = 10 << 20 // 10MB
+	importBatchSize  = 100
+	importRoleSep    = ";"
+	importHeaderRows = 1
+)
+
+// ImportUserRow memakai aturan validasi yang sama dengan RegisterUserRequest,
+// password boleh kosong karena user akan menerima email undangan
+type ImportUserRow struct {
+	Username string          `binding:"required"`
+	Email    string          `binding:"required,email"`
+	Password string          `binding:"omitempty,min=6"`
+	Type     models.UserType `binding:"required,oneof=ADMIN DRIVER"`
+	Roles    []string
+}
+
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+type ImportUsersResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Total   int              `json:"total"`
+	Valid   int              `json:"valid"`
+	Created int              `json:"created"`
+	Invited int              `json:"invited"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// importRow is a validated row ready to be inserted
+type importRow struct {
+	number int
+	data   ImportUserRow
+	roles  []models.Role
+}
+
+// ImportUsers creates users in bulk from CSV or XLSX
+// @Summary      Import users from CSV/XLSX
+// @Description  Import users from a CSV or XLSX file with columns username, email, type, roles (separated by ;) and optional password. Rows without password receive an invitation mail. Valid rows are created in batches; invalid rows and rows that fail to insert are reported without affecting the other rows.
+// @Tags         users
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        file     formData  file  true   "CSV or XLSX file"
+// @Param        dry_run  query     bool  false  "Only validate without creating users"
+// @Success      200  {object}  ImportUsersResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/import [post]
+func ImportUsers(db *gorm.DB, notifier notification.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		dryRun := c.Query("dry_run") == "true"
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+			return
+		}
+
+		format, err := spreadsheet.FormatFromFilename(fileHeader.Filename)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to open file"})
+			return
+		}
+		defer file.Close()
+
+		rows, err := spreadsheet.ReadRows(format, file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file: " + err.Error()})
+			return
+		}
+
+		table, err := spreadsheet.NewTable(rows)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !table.HasColumn("username") || !table.HasColumn("email") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File must have username and email columns"})
+			return
+		}
+		if len(table.Rows) > maxImportRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many rows, maximum is %d", maxImportRows)})
+			return
+		}
+
+		var allRoles []models.Role
+		if err := db.Find(&allRoles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+			return
+		}
+		rolesByName := make(map[string]models.Role)
+		for _, role := range allRoles {
+			rolesByName[role.Name] = role
+		}
+
+		response := ImportUsersResponse{DryRun: dryRun, Errors: []ImportRowError{}}
+		seenUsernames := make(map[string]int)
+		seenEmails := make(map[string]int)
+		var valid []importRow
+
+		for i, row := range table.Rows {
+			if spreadsheet.IsEmptyRow(row) {
+				continue
+			}
+			response.Total++
+			number := i + importHeaderRows + 1
+
+			data := ImportUserRow{
+				Username: table.Value(row, "username"),
+				Email:    table.Value(row, "email"),
+				Password: table.Value(row, "password"),
+				Type:     models.UserType(strings.ToUpper(table.Value(row, "type"))),
+			}
+			if data.Type == "" {
+				data.Type = models.UserTypeDriver
+			}
+			for _, name := range strings.Split(table.Value(row, "roles"), importRoleSep) {
+				if name = strings.TrimSpace(name); name != "" {
+					data.Roles = append(data.Roles, name)
+				}
+			}
+
+			var rowErrors []string
+			if err := binding.Validator.ValidateStruct(&data); err != nil {
+				rowErrors = append(rowErrors, err.Error())
+			}
+
+			var roles []models.Role
+			for _, name := range data.Roles {
+				role, ok := rolesByName[name]
+				if !ok {
+					rowErrors = append(rowErrors, "Role "+name+" not found")
+					continue
+				}
+				roles = append(roles, role)
+			}
+
+			if data.Username != "" {
+				if previous, ok := seenUsernames[strings.ToLower(data.Username)]; ok {
+					rowErrors = append(rowErrors, "Duplicate username in file, see row "+strconv.Itoa(previous))
+				}
+				seenUsernames[strings.ToLower(data.Username)] = number
+			}
+			if data.Email != "" {
+				if previous, ok := seenEmails[strings.ToLower(data.Email)]; ok {
+					rowErrors = append(rowErrors, "Duplicate email in file, see row "+strconv.Itoa(previous))
+				}
+				seenEmails[strings.ToLower(data.Email)] = number
+			}
+
+			if len(rowErrors) == 0 {
+				if err := checkUserUnique(db, data.Username, data.Email); err != nil {
+					rowErrors = append(rowErrors, err.Error())
+				}
+			}
+
+			if len(rowErrors) > 0 {
+				response.Errors = append(response.Errors, ImportRowError{Row: number, Errors: rowErrors})
+				continue
+			}
+
+			valid = append(valid, importRow{number: number, data: data, roles: roles})
+		}
+
+		response.Valid = len(valid)
+		response.Failed = len(response.Errors)
+
+		if dryRun || len(valid) == 0 {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		var grantedBy *uuid.UUID
+		if actorID, exists := c.Get("user_id"); exists {
+			if actorUUID, err := uuid.Parse(actorID.(string)); err == nil {
+				grantedBy = &actorUUID
+			}
+		}
+		actor := auditActor(c)
+
+		for start := 0; start < len(valid); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(valid) {
+				end = len(valid)
+			}
+			batch := valid[start:end]
+
+			var invitations []notification.Message
+			var rowErrors []ImportRowError
+			err := db.Transaction(func(tx *gorm.DB) error {
+				invitations, rowErrors = nil, nil
+				for _, row := range batch {
+					// Savepoint per row: row yang gagal di-rollback sendiri, row lain di batch tetap dibuat
+					var message *notification.Message
+					err := tx.Transaction(func(rowTx *gorm.DB) error {
+						var err error
+						message, err = createImportedUser(rowTx, row, grantedBy, actor)
+						return err
+					})
+					if err != nil {
+						slog.ErrorContext(c.Request.Context(), "Failed to import user", "row", row.number, "error", err)
+						rowErrors = append(rowErrors, ImportRowError{Row: row.number, Errors: []string{importErrorMessage(err)}})
+						continue
+					}
+					if message != nil {
+						invitations = append(invitations, *message)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				// Commit batch gagal, tidak ada row di batch ini yang tersimpan
+				slog.ErrorContext(c.Request.Context(), "Failed to import user batch", "first_row", batch[0].number, "error", err)
+				for _, row := range batch {
+					response.Errors = append(response.Errors, ImportRowError{Row: row.number, Errors: []string{"Failed to create user"}})
+				}
+				response.Failed += len(batch)
+				continue
+			}
+
+			response.Errors = append(response.Errors, rowErrors...)
+			response.Failed += len(rowErrors)
+			response.Created += len(batch) - len(rowErrors)
+			for _, message := range invitations {
+				if err := notifier.Send(message); err != nil {
+					slog.ErrorContext(c.Request.Context(), "Failed to send invitation", "to", message.To, "error", err)
+					continue
+				}
+				response.Invited++
+			}
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// importErrorMessage maps an insert error of a single row to a message safe for the client
+func importErrorMessage(err error) string {
+	if database.IsUniqueViolation(err) {
+		return "Username or email already exists"
+	}
+	return "Failed to create user"
+}
+
+// createImportedUser inserts a user with roles and returns the invitation mail if needed
+func createImportedUser(tx *gorm.DB, row importRow, grantedBy *uuid.UUID, actor audit.Actor) (*notification.Message, error) {
+	var password string
+	var err error
+	if row.data.Password != "" {
+		var hashed []byte
+		hashed, err = bcrypt.GenerateFromPassword([]byte(row.data.Password), bcrypt.DefaultCost)
+		password = string(hashed)
+	} else {
+		password, err = unusablePassword()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		ID:            uuid.New(),
+		Username:      row.data.Username,
+		Email:         row.data.Email,
+		Password:      password,
+		Type:          row.data.Type,
+		Status:        models.UserStatusActive,
+		ActivatedDate: &now,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	for _, role := range row.roles {
+		assignment := models.UserRole{
+			UserID:    user.ID,
+			RoleID:    role.ID,
+			Reason:    "Bulk import",
+			GrantedBy: grantedBy,
+		}
+		if err := tx.Create(&assignment).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	roleNames := make([]string, 0, len(row.roles))
+	for _, role := range row.roles {
+		roleNames = append(roleNames, role.Name)
+	}
+	err = audit.Record(tx, actor, audit.Entry{
+		Action:       audit.ActionImport,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.String(),
+		After: struct {
+			models.User
+			Roles []string `json:"roles"`
+		}{user, roleNames},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := webhook.Enqueue(tx, webhook.UserCreated, webhook.UserData(user)); err != nil {
+		return nil, err
+	}
+	for _, role := range row.roles {
+		change := webhook.RoleChange{User: webhook.UserData(user), Role: webhook.RoleData(role), Reason: "Bulk import"}
+		if err := webhook.Enqueue(tx, webhook.RoleAssigned, change); err != nil {
+			return nil, err
+		}
+	}
+
+	if row.data.Password != "" {
+		return nil, nil
+	}
+
+	token, err := createPasswordToken(tx, user.ID, models.PasswordTokenInvite, inviteTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	message := passwordTokenMessage(user, token, models.PasswordTokenInvite)
+	return &message, nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+
+	"sjek/internal/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserSearchMatch berisi skor relevansi dan potongan teks yang cocok dengan q
+type UserSearchMatch struct {
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+const userSearchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+
+// userSearchTSQuery converts free text into a prefix tsquery, e.g. "budi b12" -> "budi:* & b12:*".
+// Karakter selain huruf dan angka dibuang sehingga input user tidak bisa merusak sintaks tsquery
+func userSearchTSQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, word+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// applyUserSearch filters users by full-text match on search_vector,
+// or fuzzy/substring match on username, email and vehicle plate
+func applyUserSearch(query *gorm.DB, q string) *gorm.DB {
+	like := database.ContainsPattern(q)
+	fuzzy := query.Session(&gorm.Session{NewDB: true}).
+		Where(`users.username ILIKE ? ESCAPE '\' OR users.email ILIKE ? ESCAPE '\'`, like, like).
+		Or("users.username % ? OR users.email % ?", q, q).
+		Or(`users.id IN (SELECT user_id FROM driver_profiles WHERE vehicle_plate ILIKE ? ESCAPE '\' OR license_number ILIKE ? ESCAPE '\')`, like, like)
+
+	if tsQuery := userSearchTSQuery(q); tsQuery != "" {
+		fuzzy = fuzzy.Or("users.search_vector @@ to_tsquery('simple', ?)", tsQuery)
+	}
+
+	return query.Where(fuzzy)
+}
+
+// orderByUserSearchRank orders users by full-text rank combined with trigram similarity
+func orderByUserSearchRank(query *gorm.DB, q string) *gorm.DB {
+	tsQuery := userSearchTSQuery(q)
+	if tsQuery == "" {
+		return query.Order(gorm.Expr("GREATEST(similarity(users.username, ?), similarity(users.email, ?)) DESC", q, q))
+	}
+	return query.Order(gorm.Expr(
+		"ts_rank(users.search_vector, to_tsquery('simple', ?)) + GREATEST(similarity(users.username, ?), similarity(users.email, ?)) DESC",
+		tsQuery, q, q,
+	))
+}
+
+// loadUserSearchMatches returns rank and highlighted fields for the given users
+func loadUserSearchMatches(db *gorm.DB, q string, ids []uuid.UUID) (map[uuid.UUID]*UserSearchMatch, error) {
+	matches := make(map[uuid.UUID]*UserSearchMatch)
+	if len(ids) == 0 {
+		return matches, nil
+	}
+
+	// tsquery kosong valid di Postgres, rank cukup dari similarity dan tidak ada highlight
+	tsQuery := userSearchTSQuery(q)
+
+	var rows []struct {
+		ID                    uuid.UUID
+		Rank                  float64
+		UsernameHighlight     string
+		EmailHighlight        string
+		VehiclePlateHighlight string
+	}
+	err := db.Unscoped().Table("users").
+		Select(`users.id,
+			COALESCE(ts_rank(users.search_vector, to_tsquery('simple', @ts)), 0) + GREATEST(similarity(users.username, @q), similarity(users.email, @q)) AS rank,
+			ts_headline('simple', users.username, to_tsquery('simple', @ts), @opts) AS username_highlight,
+			ts_headline('simple', users.email, to_tsquery('simple', @ts), @opts) AS email_highlight,
+			COALESCE(ts_headline('simple', driver_profiles.vehicle_plate, to_tsquery('simple', @ts), @opts), '') AS vehicle_plate_highlight`,
+			map[string]interface{}{"ts": tsQuery, "q": q, "opts": userSearchHeadlineOptions}).
+		Joins("LEFT JOIN driver_profiles ON driver_profiles.user_id = users.id").
+		Where("users.id IN ?", ids).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		highlights := map[string]string{}
+		for field, value := range map[string]string{
+			"username":      row.UsernameHighlight,
+			"email":         row.EmailHighlight,
+			"vehicle_plate": row.VehiclePlateHighlight,
+		} {
+			if strings.Contains(value, "<mark>") {
+				highlights[field] = value
+			}
+		}
+		matches[row.ID] = &UserSearchMatch{Rank: row.Rank, Highlights: highlights}
+	}
+	return matches, nil
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/events"
+	"sjek/internal/models"
+	"sjek/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatusChangeRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// errInvalidStatusTransition is returned when the lifecycle does not allow the status change
+var errInvalidStatusTransition = errors.New("invalid status transition")
+
+// transitionUserStatus changes user status following the lifecycle state machine,
+// records the history and revokes tokens when the user loses access
+func transitionUserStatus(tx *gorm.DB, user *models.User, to models.UserStatus, reason string, until *time.Time, changedBy *uuid.UUID) error {
+	from := user.Status
+	if !from.CanTransition(to) {
+		return fmt.Errorf("%w: cannot change status from %s to %s", errInvalidStatusTransition, from, to)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":          to,
+		"suspended_until": nil,
+		"suspend_reason":  "",
+	}
+	switch to {
+	case models.UserStatusActive:
+		updates["activated_date"] = now
+		updates["inactive_date"] = nil
+	case models.UserStatusInactive:
+		updates["inactive_date"] = now
+	case models.UserStatusSuspended:
+		updates["suspended_until"] = until
+		updates["suspend_reason"] = reason
+	}
+
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	history := models.UserStatusHistory{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		Until:      until,
+		ChangedBy:  changedBy,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	// User yang tidak aktif atau disuspend langsung logout dari semua device
+	if to != models.UserStatusActive {
+		if err := tx.Model(&models.UserToken{}).Where("user_id = ?", user.ID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+
+	user.Status = to
+	return webhook.Enqueue(tx, webhook.StatusEventType(to), webhook.StatusChange{
+		User:       webhook.UserData(*user),
+		FromStatus: from,
+		Reason:     reason,
+		Until:      until,
+	})
+}
+
+// changeUserStatus is the shared handler body for lifecycle endpoints. from membatasi status
+// asal (kosong berarti semua status yang diizinkan state machine), dicek di dalam transaksi
+func changeUserStatus(c *gin.Context, from, to models.UserStatus, reason string, until *time.Time) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var changedBy *uuid.UUID
+	if actorID, exists := c.Get("user_id"); exists {
+		if actorUUID, err := uuid.Parse(actorID.(string)); err == nil {
+			changedBy = &actorUUID
+		}
+	}
+
+	var user models.User
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if from != "" && user.Status != from {
+			return fmt.Errorf("%w: user is %s, expected %s", errInvalidStatusTransition, user.Status, from)
+		}
+		before := user
+		if err := transitionUserStatus(tx, &user, to, reason, until, changedBy); err != nil {
+			return err
+		}
+		if to != models.UserStatusActive {
+			// User dikunci dan semua token-nya dicabut oleh transitionUserStatus
+			err := publishSecurityEvent(tx, c, events.Event{
+				Type:     events.TypeUserLocked,
+				UserID:   &user.ID,
+				Username: user.Username,
+				Data: map[string]interface{}{
+					"status": to,
+					"reason": reason,
+					"until":  until,
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceUser, user.ID, before, user)
+	})
+	if err != nil {
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case errors.Is(err, errInvalidStatusTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change user status"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("User status changed to %s", to)})
+}
+
+// @Summary      Activate user
+// @Description  Activate an INACTIVE user
+// @Tags         user-status
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string               true   "User ID"
+// @Param        request body  StatusChangeRequest  false  "Reason"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/activate [post]
+func ActivateUser(c *gin.Context) {
+	var req StatusChangeRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	// User SUSPENDED diaktifkan lewat reactivate
+	changeUserStatus(c, models.UserStatusInactive, models.UserStatusActive, req.Reason, nil)
+}
+
+// @Summary      Deactivate user
+// @Description  Deactivate an ACTIVE or SUSPENDED user and revoke all tokens
+// @Tags         user-status
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string               true   "User ID"
+// @Param        request body  StatusChangeRequest  false  "Reason"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/deactivate [post]
+func DeactivateUser(c *gin.Context) {
+	var req StatusChangeRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	changeUserStatus(c, "", models.UserStatusInactive, req.Reason, nil)
+}
+
+// @Summary      Suspend user
+// @Description  Suspend an ACTIVE user, optionally until a given time, and revoke all tokens
+// @Tags         user-status
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string              true  "User ID"
+// @Param        request body  SuspendUserRequest  true  "Reason and optional end of suspension"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/suspend [post]
+func SuspendUser(c *gin.Context) {
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+		return
+	}
+	changeUserStatus(c, "", models.UserStatusSuspended, req.Reason, req.Until)
+}
+
+// @Summary      Reactivate user
+// @Description  Lift the suspension of a SUSPENDED user
+// @Tags         user-status
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string               true   "User ID"
+// @Param        request body  StatusChangeRequest  false  "Reason"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/reactivate [post]
+func ReactivateUser(c *gin.Context) {
+	var req StatusChangeRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	// Reactivate hanya untuk user yang disuspend, user INACTIVE memakai activate
+	changeUserStatus(c, models.UserStatusSuspended, models.UserStatusActive, req.Reason, nil)
+}
+
+// @Summary      Get user status history
+// @Description  Get history of status transitions of a user
+// @Tags         user-status
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {array}   models.UserStatusHistory
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /users/{id}/status-history [get]
+func GetUserStatusHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var history []models.UserStatusHistory
+	if err := database.WithContext(c.Request.Context()).Where("user_id = ?", id).Order("created_at DESC").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"sjek/internal/audit"
+	"sjek/internal/database"
+	"sjek/internal/models"
+	"sjek/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookRequest membuat atau mengubah subscription. EventTypes kosong berarti semua event,
+// Secret kosong saat create akan dibuat otomatis
+type WebhookRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+	IsActive   *bool    `json:"is_active,omitempty"`
+}
+
+// WebhookSecretResponse is the subscription with its signing secret, only returned on create and rotate
+type WebhookSecretResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret" example:"whsec_..."`
+}
+
+// WebhookDeliveryResponse is one delivery with its attempt log
+type WebhookDeliveryResponse struct {
+	models.WebhookDelivery
+	AttemptLog []models.WebhookAttempt `json:"attempt_log"`
+}
+
+// @Summary      Get webhook event types
+// @Description  List the event types a webhook subscription can filter on.
+// @Description  role.assigned and role.unassigned cover direct user role assignments, including expiry and role deletion.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   string
+// @Router       /webhooks/event-types [get]
+func GetWebhookEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, webhook.EventTypes)
+}
+
+// bindWebhookRequest validates the URL and event types of req
+func bindWebhookRequest(c *gin.Context) (WebhookRequest, bool) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL must be an absolute http or https URL"})
+		return req, false
+	}
+	if err := webhook.CheckTarget(target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL must not point to localhost or a private network"})
+		return req, false
+	}
+
+	for _, eventType := range req.EventTypes {
+		if !webhook.ValidEventType(eventType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + eventType})
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// @Summary      Create webhook subscription
+// @Description  Subscribe a URL to user, role and session events. Deliveries are POSTed as JSON and signed with
+// @Description  X-Webhook-Signature: sha256=HMAC-SHA256(secret, X-Webhook-Timestamp + "." + body).
+// @Description  The secret is generated when omitted and is only returned in this response.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body WebhookRequest true "Subscription details"
+// @Success      201  {object}  WebhookSecretResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	req, ok := bindWebhookRequest(c)
+	if !ok {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhook.NewSecret(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+	}
+
+	subscription := models.WebhookSubscription{
+		ID:         uuid.New(),
+		Name:       req.Name,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: models.StringList(req.EventTypes),
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if createdBy, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		subscription.CreatedBy = &createdBy
+	}
+
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Select semua kolom supaya is_active=false tidak diganti default
+		if err := tx.Select("*").Create(&subscription).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionCreate, audit.ResourceWebhook, subscription.ID, nil, subscription)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, WebhookSecretResponse{WebhookSubscription: subscription, Secret: secret})
+}
+
+// @Summary      Get webhook subscriptions
+// @Description  List webhook subscriptions, newest first. Secrets are never returned.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   models.WebhookSubscription
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	subscriptions := []models.WebhookSubscription{}
+	if err := database.WithContext(c.Request.Context()).Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// @Summary      Get webhook subscription by ID
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  models.WebhookSubscription
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /webhooks/{id} [get]
+func GetWebhook(c *gin.Context) {
+	subscription, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary      Update webhook subscription
+// @Description  Update name, URL, event types and active flag. A non-empty secret replaces the signing secret.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path    string          true  "Webhook ID"
+// @Param        request body    WebhookRequest  true  "Subscription details"
+// @Success      200  {object}  models.WebhookSubscription
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	subscription, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	req, ok := bindWebhookRequest(c)
+	if !ok {
+		return
+	}
+
+	before := subscription
+	subscription.Name = req.Name
+	subscription.URL = req.URL
+	subscription.EventTypes = models.StringList(req.EventTypes)
+	if req.IsActive != nil {
+		subscription.IsActive = *req.IsActive
+	}
+	if req.Secret != "" {
+		subscription.Secret = req.Secret
+	}
+
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("name", "url", "event_types", "is_active", "secret").Save(&subscription).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceWebhook, subscription.ID, before, subscription)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary      Rotate webhook secret
+// @Description  Generate a new signing secret. Deliveries sent after this call are signed with the new secret.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  WebhookSecretResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id}/rotate-secret [post]
+func RotateWebhookSecret(c *gin.Context) {
+	subscription, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	err = database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&subscription).Update("secret", secret).Error; err != nil {
+			return err
+		}
+		// Secret tidak masuk snapshot audit (json:"-"), yang tercatat hanya bahwa rotasi terjadi
+		return recordAudit(tx, c, audit.ActionUpdate, audit.ResourceWebhook, subscription.ID, nil, gin.H{"secret_rotated": true})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate secret"})
+		return
+	}
+
+	subscription.Secret = secret
+	c.JSON(http.StatusOK, WebhookSecretResponse{WebhookSubscription: subscription, Secret: secret})
+}
+
+// @Summary      Delete webhook subscription
+// @Description  Delete a subscription. Pending deliveries move to the dead-letter list; delivery logs are kept.
+// @Tags         webhooks
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	subscription, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	err := database.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&subscription).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, audit.ActionDelete, audit.ResourceWebhook, subscription.ID, subscription, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// findWebhookDeliveries lists deliveries of query with pagination, newest first
+func findWebhookDeliveries(c *gin.Context, query *gorm.DB) {
+	var pagination models.Pagination
+	pagination.Page = 1
+	pagination.Limit = 10
+
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.Limit < 1 {
+		pagination.Limit = 10
+	}
+	if pagination.Limit > 100 {
+		pagination.Limit = 100
+	}
+	pagination.Offset = (pagination.Page - 1) * pagination.Limit
+
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count webhook deliveries"})
+		return
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	err := query.Order("created_at DESC").Order("id DESC").
+		Offset(pagination.Offset).Limit(pagination.Limit).Find(&deliveries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       deliveries,
+		Pagination: pagination,
+	})
+}
+
+// @Summary      Get webhook deliveries
+// @Description  Delivery log of a subscription with pagination, newest first
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path      string  true   "Webhook ID"
+// @Param        status      query     string  false  "Filter by status (PENDING/SUCCEEDED/DEAD)"
+// @Param        event_type  query     string  false  "Filter by event type"
+// @Param        page        query     int     false  "Page number (default: 1)"
+// @Param        limit       query     int     false  "Items per page (default: 10, max: 100)"
+// @Success      200  {object}  models.PaginatedResponse{data=[]models.WebhookDelivery}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	subscription, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	query := database.WithContext(c.Request.Context()).Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscription.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	findWebhookDeliveries(c, query)
+}
+
+// @Summary      Get webhook dead-letters
+// @Description  Deliveries of all subscriptions that ran out of retries or whose subscription was deleted or disabled.
+// @Description  Use POST /webhooks/deliveries/{id}/retry to send one again.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        subscription_id  query     string  false  "Filter by webhook ID"
+// @Param        event_type       query     string  false  "Filter by event type"
+// @Param        page             query     int     false  "Page number (default: 1)"
+// @Param        limit            query     int     false  "Items per page (default: 10, max: 100)"
+// @Success      200  {object}  models.PaginatedResponse{data=[]models.WebhookDelivery}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/dead-letters [get]
+func GetWebhookDeadLetters(c *gin.Context) {
+	query := database.WithContext(c.Request.Context()).Model(&models.WebhookDelivery{}).Where("status = ?", models.WebhookDeliveryDead)
+	if subscriptionID := c.Query("subscription_id"); subscriptionID != "" {
+		id, err := uuid.Parse(subscriptionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+			return
+		}
+		query = query.Where("subscription_id = ?", id)
+	}
+	findWebhookDeliveries(c, query)
+}
+
+// @Summary      Get webhook delivery by ID
+// @Description  Get a delivery with its event payload and the log of every attempt
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Delivery ID"
+// @Success      200  {object}  WebhookDeliveryResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/deliveries/{id} [get]
+func GetWebhookDelivery(c *gin.Context) {
+	delivery, ok := findWebhookDelivery(c)
+	if !ok {
+		return
+	}
+
+	attempts := []models.WebhookAttempt{}
+	if err := database.WithContext(c.Request.Context()).Where("delivery_id = ?", delivery.ID).Order("created_at").Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch delivery attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookDeliveryResponse{WebhookDelivery: delivery, AttemptLog: attempts})
+}
+
+// @Summary      Retry webhook delivery
+// @Description  Queue a dead delivery again with a fresh retry budget. The attempt log is kept.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Delivery ID"
+// @Success      200  {object}  models.WebhookDelivery
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/deliveries/{id}/retry [post]
+func RetryWebhookDelivery(c *gin.Context) {
+	delivery, ok := findWebhookDelivery(c)
+	if !ok {
+		return
+	}
+
+	if delivery.Status != models.WebhookDeliveryDead {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only dead deliveries can be retried"})
+		return
+	}
+
+	now := time.Now()
+	result := database.WithContext(c.Request.Context()).Model(&delivery).Where("status = ?", models.WebhookDeliveryDead).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry delivery"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only dead deliveries can be retried"})
+		return
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	c.JSON(http.StatusOK, delivery)
+}
+
+func findWebhook(c *gin.Context) (models.WebhookSubscription, bool) {
+	var subscription models.WebhookSubscription
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return subscription, false
+	}
+
+	if err := database.WithContext(c.Request.Context()).First(&subscription, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook"})
+		}
+		return subscription, false
+	}
+
+	return subscription, true
+}
+
+func findWebhookDelivery(c *gin.Context) (models.WebhookDelivery, bool) {
+	var delivery models.WebhookDelivery
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return delivery, false
+	}
+
+	if err := database.WithContext(c.Request.Context()).Preload("Event").First(&delivery, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch delivery"})
+		}
+		return delivery, false
+	}
+
+	return delivery, true
+}
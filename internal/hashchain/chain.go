@@ -0,0 +1,200 @@
+// Package hashchain links log records into a tamper-evident SHA-256 chain.
+//
+// Setiap record menyimpan hash dari isinya dan hash record sebelumnya, sehingga mengubah,
+// menghapus atau menyisipkan record akan memutus chain. Checkpoint yang ditandatangani
+// secara berkala mencegah seluruh chain ditulis ulang tanpa ketahuan.
+package hashchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"sjek/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Record is a log model that can be chained
+type Record interface {
+	Link() *models.ChainLink
+	ChainRecordID() string
+	// ChainSeal sets derived fields before hashing and returns the extra columns it set
+	ChainSeal(now time.Time) []string
+	ChainContent() []interface{}
+}
+
+// ContentChecker is implemented by records with content that is verified outside the chain hash
+type ContentChecker interface {
+	CheckChainContent() error
+}
+
+// Chain is one hash chain, stored in the table with the same name
+type Chain struct {
+	Name string
+	load func(db *gorm.DB, afterSeq int64, limit int) ([]Record, error)
+	// unchained loads rows written before the table was chained
+	unchained func(db *gorm.DB, limit int) ([]Record, error)
+}
+
+func chainOf[T any, P interface {
+	*T
+	Record
+}](name string) Chain {
+	toRecords := func(rows []T) []Record {
+		records := make([]Record, len(rows))
+		for i := range rows {
+			records[i] = P(&rows[i])
+		}
+		return records
+	}
+
+	return Chain{
+		Name: name,
+		load: func(db *gorm.DB, afterSeq int64, limit int) ([]Record, error) {
+			var rows []T
+			err := db.Where("chain_seq > ?", afterSeq).Order("chain_seq ASC").Limit(limit).Find(&rows).Error
+			return toRecords(rows), err
+		},
+		unchained: func(db *gorm.DB, limit int) ([]Record, error) {
+			var rows []T
+			err := db.Where("chain_seq IS NULL").Order("created_at ASC, id ASC").Limit(limit).Find(&rows).Error
+			return toRecords(rows), err
+		},
+	}
+}
+
+var (
+	AuditLogs = chainOf[models.AuditLog]("audit_logs")
+	LoginLogs = chainOf[models.LoginLog]("login_logs")
+
+	// Chains lists every chained table
+	Chains = []Chain{AuditLogs, LoginLogs}
+)
+
+// Find returns the chain with the given name
+func Find(name string) (Chain, bool) {
+	for _, chain := range Chains {
+		if chain.Name == name {
+			return chain, true
+		}
+	}
+	return Chain{}, false
+}
+
+const batchSize = 500
+
+// head is the last linked record of a chain
+type head struct {
+	ChainSeq int64
+	Hash     string
+}
+
+// lock serializes writers of the chain until tx ends
+func (ch Chain) lock(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "hashchain:"+ch.Name).Error
+}
+
+func (ch Chain) head(tx *gorm.DB) (head, error) {
+	var h head
+	err := tx.Table(ch.Name).Select("chain_seq", "hash").
+		Where("chain_seq IS NOT NULL").Order("chain_seq DESC").Limit(1).Scan(&h).Error
+	return h, err
+}
+
+// Hash computes the chain hash of a record from the previous hash and its content
+func Hash(prevHash string, record Record) (string, error) {
+	content, err := json.Marshal(record.ChainContent())
+	if err != nil {
+		return "", fmt.Errorf("hashchain: encode %s: %v", record.ChainRecordID(), err)
+	}
+
+	sum := sha256.New()
+	sum.Write([]byte(prevHash))
+	sum.Write([]byte("\n"))
+	sum.Write(content)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// link seals record and points it to h
+func link(record Record, h head) ([]string, error) {
+	columns := record.ChainSeal(time.Now())
+
+	seq := h.ChainSeq + 1
+	l := record.Link()
+	l.ChainSeq = &seq
+	l.PrevHash = h.Hash
+
+	hash, err := Hash(h.Hash, record)
+	if err != nil {
+		return nil, err
+	}
+	l.Hash = hash
+	return columns, nil
+}
+
+// Append links record to the end of the chain and inserts it using tx
+func (ch Chain) Append(tx *gorm.DB, record Record) error {
+	if err := ch.lock(tx); err != nil {
+		return err
+	}
+
+	h, err := ch.head(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := link(record, h); err != nil {
+		return err
+	}
+	return tx.Create(record).Error
+}
+
+// Backfill links rows written before chaining was enabled, oldest first.
+// Returns the number of rows linked
+func (ch Chain) Backfill(db *gorm.DB) (int, error) {
+	linked := 0
+	for {
+		var count int
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := ch.lock(tx); err != nil {
+				return err
+			}
+
+			h, err := ch.head(tx)
+			if err != nil {
+				return err
+			}
+
+			records, err := ch.unchained(tx, batchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, record := range records {
+				columns, err := link(record, h)
+				if err != nil {
+					return err
+				}
+				columns = append(columns, "chain_seq", "prev_hash", "hash")
+				if err := tx.Model(record).Select(columns).Updates(record).Error; err != nil {
+					return err
+				}
+
+				l := record.Link()
+				h = head{ChainSeq: *l.ChainSeq, Hash: l.Hash}
+			}
+			count = len(records)
+			return nil
+		})
+		if err != nil {
+			return linked, err
+		}
+
+		linked += count
+		if count < batchSize {
+			return linked, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package hashchain
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"sjek/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Signer signs chain checkpoints with an Ed25519 key derived from a secret.
+// Auditor cukup memegang public key untuk memverifikasi checkpoint
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewSigner derives the signing key from secret
+func NewSigner(secret []byte) Signer {
+	seed := sha256.Sum256(secret)
+	return Signer{key: ed25519.NewKeyFromSeed(seed[:])}
+}
+
+// PublicKey returns the hex encoded public key
+func (s Signer) PublicKey() string {
+	return hex.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// KeyID is a short fingerprint of the public key stored with each checkpoint
+func (s Signer) KeyID() string {
+	sum := sha256.Sum256(s.key.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+func checkpointMessage(cp models.ChainCheckpoint) []byte {
+	return []byte(fmt.Sprintf("%s:%d:%s:%s", cp.Chain, cp.Seq, cp.Hash,
+		cp.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)))
+}
+
+func (s Signer) sign(cp models.ChainCheckpoint) string {
+	return hex.EncodeToString(ed25519.Sign(s.key, checkpointMessage(cp)))
+}
+
+// Verify checks the checkpoint signature against this signer's public key
+func (s Signer) Verify(cp models.ChainCheckpoint) bool {
+	signature, err := hex.DecodeString(cp.Signature)
+	if err != nil || cp.KeyID != s.KeyID() {
+		return false
+	}
+	return ed25519.Verify(s.key.Public().(ed25519.PublicKey), checkpointMessage(cp), signature)
+}
+
+// Checkpoint signs the current head of the chain. Tidak ada checkpoint baru jika head
+// belum berubah sejak checkpoint terakhir; hasilnya nil
+func (ch Chain) Checkpoint(db *gorm.DB, signer Signer) (*models.ChainCheckpoint, error) {
+	var checkpoint *models.ChainCheckpoint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := ch.lock(tx); err != nil {
+			return err
+		}
+
+		h, err := ch.head(tx)
+		if err != nil || h.ChainSeq == 0 {
+			return err
+		}
+
+		var last models.ChainCheckpoint
+		result := tx.Where("chain = ?", ch.Name).Order("seq DESC").Limit(1).Find(&last)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 && last.Seq >= h.ChainSeq {
+			return nil
+		}
+
+		cp := models.ChainCheckpoint{
+			Chain:     ch.Name,
+			Seq:       h.ChainSeq,
+			Hash:      h.Hash,
+			KeyID:     signer.KeyID(),
+			CreatedAt: time.Now().Truncate(time.Microsecond),
+		}
+		cp.Signature = signer.sign(cp)
+		if err := tx.Create(&cp).Error; err != nil {
+			return err
+		}
+		checkpoint = &cp
+		return nil
+	})
+	return checkpoint, err
+}
+
+// CheckpointAll writes a checkpoint for every chain that moved since its last checkpoint
+func CheckpointAll(db *gorm.DB, signer Signer) ([]models.ChainCheckpoint, error) {
+	var written []models.ChainCheckpoint
+	for _, chain := range Chains {
+		cp, err := chain.Checkpoint(db, signer)
+		if err != nil {
+			return written, fmt.Errorf("checkpoint %s: %v", chain.Name, err)
+		}
+		if cp != nil {
+			written = append(written, *cp)
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,147 @@
+package hashchain
+
+import (
+	"sjek/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Reasons a chain is reported broken
+const (
+	ReasonMissingRecord       = "missing_record"
+	ReasonPrevHashMismatch    = "prev_hash_mismatch"
+	ReasonHashMismatch        = "hash_mismatch"
+	ReasonContentMismatch     = "content_mismatch"
+	ReasonCheckpointMismatch  = "checkpoint_mismatch"
+	ReasonCheckpointSignature = "invalid_checkpoint_signature"
+	ReasonTruncated           = "records_missing_after_checkpoint"
+)
+
+// Break describes the first broken link found in a chain
+type Break struct {
+	Seq      int64  `json:"seq"`
+	RecordID string `json:"record_id,omitempty"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// Report is the result of walking one chain
+type Report struct {
+	Chain               string `json:"chain"`
+	Valid               bool   `json:"valid"`
+	Records             int64  `json:"records"`
+	HeadSeq             int64  `json:"head_seq"`
+	HeadHash            string `json:"head_hash,omitempty"`
+	CheckpointsVerified int    `json:"checkpoints_verified"`
+	Unchained           int64  `json:"unchained"`
+	Broken              *Break `json:"broken,omitempty"`
+}
+
+// Verify walks the chain from the first record, recomputing every hash and checking every
+// signed checkpoint. Verifikasi berhenti di link pertama yang rusak
+func (ch Chain) Verify(db *gorm.DB, signer Signer) (Report, error) {
+	report := Report{Chain: ch.Name}
+
+	if err := db.Table(ch.Name).Where("chain_seq IS NULL").Count(&report.Unchained).Error; err != nil {
+		return report, err
+	}
+
+	var checkpoints []models.ChainCheckpoint
+	if err := db.Where("chain = ?", ch.Name).Order("seq ASC, created_at ASC").Find(&checkpoints).Error; err != nil {
+		return report, err
+	}
+
+	err := walk(&report, func(afterSeq int64) ([]Record, error) {
+		return ch.load(db, afterSeq, batchSize)
+	}, checkpoints, signer)
+	return report, err
+}
+
+// walk verifies the records returned by load, batch demi batch mulai dari seq 1, against the
+// checkpoints sorted by seq. Link pertama yang rusak disimpan di report.Broken
+func walk(report *Report, load func(afterSeq int64) ([]Record, error), checkpoints []models.ChainCheckpoint, signer Signer) error {
+	prevHash := ""
+	var seq int64
+	for {
+		records, err := load(seq)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if broken := checkRecord(record, seq+1, prevHash); broken != nil {
+				report.Broken = broken
+				return nil
+			}
+
+			l := record.Link()
+			seq, prevHash = *l.ChainSeq, l.Hash
+			report.Records++
+			report.HeadSeq, report.HeadHash = seq, prevHash
+
+			// Checkpoint pada seq ini harus cocok dengan hash yang baru dihitung ulang
+			for len(checkpoints) > 0 && checkpoints[0].Seq <= seq {
+				if broken := checkCheckpoint(checkpoints[0], l.Hash, signer); broken != nil {
+					broken.RecordID = record.ChainRecordID()
+					report.Broken = broken
+					return nil
+				}
+				report.CheckpointsVerified++
+				checkpoints = checkpoints[1:]
+			}
+		}
+
+		if len(records) < batchSize {
+			break
+		}
+	}
+
+	// Checkpoint melewati head berarti record di akhir chain telah dihapus
+	if len(checkpoints) > 0 {
+		cp := checkpoints[0]
+		if !signer.Verify(cp) {
+			report.Broken = &Break{Seq: cp.Seq, Reason: ReasonCheckpointSignature}
+			return nil
+		}
+		report.Broken = &Break{Seq: seq + 1, Reason: ReasonTruncated, Expected: cp.Hash}
+		return nil
+	}
+
+	report.Valid = true
+	return nil
+}
+
+func checkRecord(record Record, expectedSeq int64, prevHash string) *Break {
+	l := record.Link()
+	id := record.ChainRecordID()
+
+	if *l.ChainSeq != expectedSeq {
+		return &Break{Seq: expectedSeq, RecordID: id, Reason: ReasonMissingRecord}
+	}
+	if l.PrevHash != prevHash {
+		return &Break{Seq: expectedSeq, RecordID: id, Reason: ReasonPrevHashMismatch, Expected: prevHash, Actual: l.PrevHash}
+	}
+
+	hash, err := Hash(l.PrevHash, record)
+	if err != nil || hash != l.Hash {
+		return &Break{Seq: expectedSeq, RecordID: id, Reason: ReasonHashMismatch, Expected: hash, Actual: l.Hash}
+	}
+
+	if checker, ok := record.(ContentChecker); ok {
+		if err := checker.CheckChainContent(); err != nil {
+			return &Break{Seq: expectedSeq, RecordID: id, Reason: ReasonContentMismatch, Actual: err.Error()}
+		}
+	}
+	return nil
+}
+
+func checkCheckpoint(cp models.ChainCheckpoint, hash string, signer Signer) *Break {
+	if !signer.Verify(cp) {
+		return &Break{Seq: cp.Seq, Reason: ReasonCheckpointSignature}
+	}
+	if cp.Hash != hash {
+		return &Break{Seq: cp.Seq, Reason: ReasonCheckpointMismatch, Expected: cp.Hash, Actual: hash}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package hashchain
+
+import (
+	"testing"
+	"time"
+
+	"sjek/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// testChain links n audit logs in memory, seq 1 sampai n
+func testChain(n int) []Record {
+	records := make([]Record, n)
+	var h head
+	for i := range records {
+		actorID := uuid.New()
+		log := &models.AuditLog{
+			ID:            uuid.New(),
+			ActorID:       &actorID,
+			ActorUsername: "admin01",
+			Action:        "UPDATE",
+			ResourceType:  "user",
+			ResourceID:    uuid.NewString(),
+			After:         models.JSON(`{"status": "ACTIVE"}`),
+			IPAddress:     "10.0.0.7",
+		}
+		if _, err := link(log, h); err != nil {
+			panic(err)
+		}
+		h = head{ChainSeq: *log.ChainSeq, Hash: log.Hash}
+		records[i] = log
+	}
+	return records
+}
+
+func testCheckpoint(signer Signer, records []Record, seq int64) models.ChainCheckpoint {
+	cp := models.ChainCheckpoint{
+		Chain:     "audit_logs",
+		Seq:       seq,
+		Hash:      records[seq-1].Link().Hash,
+		KeyID:     signer.KeyID(),
+		CreatedAt: time.Now().Truncate(time.Microsecond),
+	}
+	cp.Signature = signer.sign(cp)
+	return cp
+}
+
+// relink recomputes the hashes from seq onwards, seperti penyerang yang menulis ulang chain
+func relink(records []Record, seq int64) {
+	h := head{ChainSeq: seq - 1}
+	if seq > 1 {
+		h.Hash = records[seq-2].Link().Hash
+	}
+	for _, record := range records[seq-1:] {
+		if _, err := link(record, h); err != nil {
+			panic(err)
+		}
+		h = head{ChainSeq: *record.Link().ChainSeq, Hash: record.Link().Hash}
+	}
+}
+
+func auditLog(records []Record, seq int64) *models.AuditLog {
+	return records[seq-1].(*models.AuditLog)
+}
+
+func TestWalk(t *testing.T) {
+	signer := NewSigner([]byte("test-secret"))
+	otherSigner := NewSigner([]byte("other-secret"))
+
+	tests := []struct {
+		name   string
+		tamper func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint)
+		// want is nil for a valid chain; RecordID berisi seq record yang dilaporkan, 0 jika kosong
+		want       *Break
+		wantRecord int64
+	}{
+		{
+			name: "untouched",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				return records, checkpoints
+			},
+		},
+		{
+			name: "content changed",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				auditLog(records, 4).ResourceID = uuid.NewString()
+				return records, checkpoints
+			},
+			want:       &Break{Seq: 4, Reason: ReasonHashMismatch},
+			wantRecord: 4,
+		},
+		{
+			name: "personal data changed",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				auditLog(records, 2).ActorUsername = "someone-else"
+				return records, checkpoints
+			},
+			want:       &Break{Seq: 2, Reason: ReasonContentMismatch},
+			wantRecord: 2,
+		},
+		{
+			name: "prev_hash changed",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				records[4].Link().PrevHash = records[2].Link().Hash
+				return records, checkpoints
+			},
+			want:       &Break{Seq: 5, Reason: ReasonPrevHashMismatch},
+			wantRecord: 5,
+		},
+		{
+			name: "record deleted",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				return append(records[:2:2], records[3:]...), checkpoints
+			},
+			want:       &Break{Seq: 3, Reason: ReasonMissingRecord},
+			wantRecord: 4,
+		},
+		{
+			name: "chain rewritten after content change",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				auditLog(records, 2).ResourceID = uuid.NewString()
+				relink(records, 2)
+				return records, checkpoints
+			},
+			want:       &Break{Seq: 3, Reason: ReasonCheckpointMismatch},
+			wantRecord: 3,
+		},
+		{
+			name: "checkpoint signature changed",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				checkpoints[0].Signature = testCheckpoint(signer, records, 2).Signature
+				return records, checkpoints
+			},
+			want:       &Break{Seq: 3, Reason: ReasonCheckpointSignature},
+			wantRecord: 3,
+		},
+		{
+			name: "chain rewritten with a forged checkpoint",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				auditLog(records, 5).ResourceID = uuid.NewString()
+				relink(records, 5)
+				checkpoints[1] = testCheckpoint(otherSigner, records, 6)
+				return records, checkpoints
+			},
+			want:       &Break{Seq: 6, Reason: ReasonCheckpointSignature},
+			wantRecord: 6,
+		},
+		{
+			name: "records after checkpoint deleted",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				return records[:4], checkpoints
+			},
+			want: &Break{Seq: 5, Reason: ReasonTruncated},
+		},
+		{
+			name: "records deleted with a tampered checkpoint",
+			tamper: func(records []Record, checkpoints []models.ChainCheckpoint) ([]Record, []models.ChainCheckpoint) {
+				checkpoints[1].Hash = records[3].Link().Hash
+				return records[:4], checkpoints
+			},
+			want: &Break{Seq: 6, Reason: ReasonCheckpointSignature},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := testChain(6)
+			checkpoints := []models.ChainCheckpoint{testCheckpoint(signer, records, 3), testCheckpoint(signer, records, 6)}
+			ids := make([]string, len(records))
+			for i, record := range records {
+				ids[i] = record.ChainRecordID()
+			}
+			records, checkpoints = tt.tamper(records, checkpoints)
+
+			var report Report
+			err := walk(&report, func(afterSeq int64) ([]Record, error) {
+				var batch []Record
+				for _, record := range records {
+					if *record.Link().ChainSeq > afterSeq {
+						batch = append(batch, record)
+					}
+				}
+				return batch, nil
+			}, checkpoints, signer)
+			if err != nil {
+				t.Fatalf("walk() error = %v", err)
+			}
+
+			if tt.want == nil {
+				if !report.Valid || report.Broken != nil {
+					t.Fatalf("chain reported broken: %+v", report.Broken)
+				}
+				if report.Records != 6 || report.HeadSeq != 6 || report.CheckpointsVerified != 2 {
+					t.Errorf("records = %d, head_seq = %d, checkpoints_verified = %d, want 6, 6, 2",
+						report.Records, report.HeadSeq, report.CheckpointsVerified)
+				}
+				return
+			}
+
+			if report.Valid || report.Broken == nil {
+				t.Fatal("chain reported valid")
+			}
+			if report.Broken.Seq != tt.want.Seq || report.Broken.Reason != tt.want.Reason {
+				t.Errorf("broken at seq %d (%s), want seq %d (%s)", report.Broken.Seq, report.Broken.Reason, tt.want.Seq, tt.want.Reason)
+			}
+			wantID := ""
+			if tt.wantRecord > 0 {
+				wantID = ids[tt.wantRecord-1]
+			}
+			if report.Broken.RecordID != wantID {
+				t.Errorf("record_id = %q, want %q", report.Broken.RecordID, wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"log/slog"
+	"time"
+
+	"sjek/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// accessLogPartitionsAhead is how many daily access log partitions are kept ready
+const accessLogPartitionsAhead = 7
+
+// StartAccessLogMaintenance periodically creates upcoming daily access log partitions
+// and drops partitions older than retention
+func StartAccessLogMaintenance(db *gorm.DB, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			now := time.Now()
+			if err := database.EnsureAccessLogPartitions(db, now, accessLogPartitionsAhead); err != nil {
+				slog.Error("Failed to create access log partitions", "error", err)
+			}
+
+			dropped, err := database.DropAccessLogPartitions(db, now.Add(-retention))
+			if err != nil {
+				slog.Error("Failed to drop old access log partitions", "error", err)
+			}
+			if len(dropped) > 0 {
+				slog.Info("Dropped old access log partitions", "partitions", dropped)
+			}
+			<-ticker.C
+		}
+	}()
+}
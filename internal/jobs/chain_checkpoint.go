@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"log/slog"
+	"time"
+
+	"sjek/internal/hashchain"
+
+	"gorm.io/gorm"
+)
+
+// StartChainCheckpoints periodically links unchained log rows and writes
+// signed checkpoints of the audit and login log hash chains
+func StartChainCheckpoints(db *gorm.DB, signer hashchain.Signer, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// Row lama dari sebelum hash chain aktif dirangkai terlebih dahulu
+			for _, chain := range hashchain.Chains {
+				if count, err := chain.Backfill(db); err != nil {
+					slog.Error("Failed to link rows into hash chain", "chain", chain.Name, "error", err)
+				} else if count > 0 {
+					slog.Info("Linked rows into hash chain", "chain", chain.Name, "count", count)
+				}
+			}
+
+			if _, err := hashchain.CheckpointAll(db, signer); err != nil {
+				slog.Error("Failed to write hash chain checkpoint", "error", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
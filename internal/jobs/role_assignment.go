@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sjek/internal/models"
+	"sjek/internal/notification"
+	"sjek/internal/webhook"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StartRoleAssignmentCleanup periodically removes expired role assignments
+// and notifies the affected users
+func StartRoleAssignmentCleanup(db *gorm.DB, notifier notification.Notifier, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := CleanupExpiredRoleAssignments(db, notifier); err != nil {
+				slog.Error("Failed to cleanup expired role assignments", "error", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// CleanupExpiredRoleAssignments deletes assignments whose valid_until has passed
+func CleanupExpiredRoleAssignments(db *gorm.DB, notifier notification.Notifier) error {
+	var expired []models.UserRole
+	if err := db.Preload("Role").Where("valid_until IS NOT NULL AND valid_until <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return err
+	}
+
+	for _, assignment := range expired {
+		var removed bool
+		var user models.User
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("user_id = ? AND role_id = ? AND valid_until = ?",
+				assignment.UserID, assignment.RoleID, assignment.ValidUntil).Delete(&models.UserRole{})
+			// Assignment sudah diperpanjang oleh admin di antara query dan delete
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			removed = true
+
+			// Unscoped: assignment user yang sudah dihapus tetap harus bisa dibersihkan
+			if err := tx.Unscoped().First(&user, "id = ?", assignment.UserID).Error; err != nil {
+				return err
+			}
+			// Role yang masih dimiliki lewat group tidak hilang, jadi tidak ada event
+			roles, err := webhook.SnapshotRoles(tx, []uuid.UUID{assignment.UserID})
+			if err != nil || roles.Has(assignment.UserID, assignment.RoleID) {
+				return err
+			}
+			return webhook.Enqueue(tx, webhook.RoleUnassigned, webhook.RoleChange{
+				User:       webhook.UserData(user),
+				Role:       webhook.RoleData(assignment.Role),
+				ValidUntil: assignment.ValidUntil,
+				Reason:     "Role assignment expired",
+			})
+		})
+		if err != nil {
+			slog.Error("Failed to delete expired role assignment", "user_id", assignment.UserID, "role_id", assignment.RoleID, "error", err)
+			continue
+		}
+		if !removed {
+			continue
+		}
+
+		slog.Info("Expired role removed from user", "role", assignment.Role.Name, "user_id", assignment.UserID)
+		if user.DeletedAt.Valid {
+			continue
+		}
+
+		msg := notification.Message{
+			To:      user.Email,
+			Subject: "Role assignment expired",
+			Body: fmt.Sprintf("Hi %s, your role %q expired on %s and has been removed from your account.",
+				user.Username, assignment.Role.Name, assignment.ValidUntil.Format(time.RFC1123)),
+		}
+		if err := notifier.Send(msg); err != nil {
+			slog.Error("Failed to notify user about expired role", "user_id", user.ID, "error", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"sjek/internal/models"
+	"sjek/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// StartUserPurge periodically anonymizes users that were soft deleted
+// longer than the retention period ago
+func StartUserPurge(db *gorm.DB, store storage.Storage, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if count, err := PurgeDeletedUsers(db, store, retention); err != nil {
+				slog.Error("Failed to purge deleted users", "error", err)
+			} else if count > 0 {
+				slog.Info("Anonymized deleted users", "count", count)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// PurgeDeletedUsers anonymizes personal data of users deleted before the retention cutoff.
+// Row user tetap ada agar login_logs dan user_tokens tetap valid dan statistik tidak berubah.
+// Dokumen KYC dihapus dari database dan storage.
+func PurgeDeletedUsers(db *gorm.DB, store storage.Storage, retention time.Duration) (int, error) {
+	cutoff := time.Now().Add(-retention)
+
+	var users []models.User
+	err := db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL", cutoff).
+		Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range users {
+		var storageKeys []string
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Document{}).Where("user_id = ?", user.ID).Pluck("storage_key", &storageKeys).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.Document{}).Error; err != nil {
+				return err
+			}
+			return AnonymizeUser(tx, user)
+		}); err != nil {
+			slog.Error("Failed to anonymize user", "user_id", user.ID, "error", err)
+			continue
+		}
+		purged++
+
+		// File dihapus setelah commit supaya rollback tidak meninggalkan metadata tanpa file
+		for _, key := range storageKeys {
+			if err := store.Delete(context.Background(), key); err != nil {
+				slog.Error("Failed to delete document of purged user", "storage_key", key, "user_id", user.ID, "error", err)
+			}
+		}
+	}
+
+	return purged, nil
+}
+
+// AnonymizeUser replaces personal data of a user in its profile, login history and audit trail
+func AnonymizeUser(tx *gorm.DB, user models.User) error {
+	now := time.Now()
+	anonymousName := models.AnonymizedUsername(user.ID)
+
+	err := tx.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"username":       anonymousName,
+		"email":          anonymousName + "@anonymized.invalid",
+		"password":       "",
+		"suspend_reason": "",
+		"anonymized_at":  now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.LoginLog{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+		"username":   anonymousName,
+		"email":      "",
+		"ip_address": "",
+		"user_agent": "",
+		// Salt dihapus supaya pii_hash tidak bisa dicocokkan dengan data asli, hash chain tetap utuh
+		"pii_salt":      "",
+		"anonymized_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	// Audit log tetap ada sebagai jejak perubahan, tetapi data pribadi user dihapus bersama
+	// salt-nya: identitas jika user adalah actor, snapshot jika entry tentang user tersebut
+	err = tx.Model(&models.AuditLog{}).Where("actor_id = ?", user.ID).Updates(map[string]interface{}{
+		"actor_username":      anonymousName,
+		"ip_address":          "",
+		"user_agent":          "",
+		"actor_pii_salt":      "",
+		"actor_anonymized_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.AuditLog{}).
+		Where("resource_id = ? OR before->>'user_id' = ? OR after->>'user_id' = ?", user.ID.String(), user.ID.String(), user.ID.String()).
+		Updates(map[string]interface{}{
+			"before":           nil,
+			"after":            nil,
+			"changes":          nil,
+			"data_pii_salt":    "",
+			"data_redacted_at": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.UserToken{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+		"is_active":  false,
+		"ip_address": "",
+		"user_agent": "",
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	// User anonim tidak lagi menjadi anggota tim
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.GroupMember{}).Error; err != nil {
+		return err
+	}
+
+	// Data SIM dan plat nomor termasuk data pribadi
+	return tx.Where("user_id = ?", user.ID).Delete(&models.DriverProfile{}).Error
+}
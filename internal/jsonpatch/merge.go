@@ -0,0 +1,71 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902)
+// documents to JSON values.
+//
+// Merge patch: {"email": "baru@sjek.id", "icon": null}  (null menghapus field)
+// JSON Patch:  [{"op": "replace", "path": "/email", "value": "baru@sjek.id"}]
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidPatch is returned for malformed patch documents or operations on missing paths
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// ErrTestFailed is returned when a JSON Patch "test" operation does not match
+var ErrTestFailed = errors.New("test operation failed")
+
+// MergePatch applies an RFC 7396 merge patch to doc and returns the merged document
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := unmarshal(doc, &target); err != nil {
+			return nil, fmt.Errorf("invalid document: %w", err)
+		}
+	}
+
+	var p interface{}
+	if err := unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// unmarshal keeps numbers as json.Number so large integers survive the round trip
+func unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.Decode(&struct{}{}) != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
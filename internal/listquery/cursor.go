@@ -0,0 +1,284 @@
+package listquery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Keyset is a cursor (keyset) pagination request.
+// Halaman berikutnya diambil dengan WHERE (sort keys) > nilai row terakhir,
+// sehingga tidak melambat di halaman dalam dan tidak bergeser saat ada row baru
+type Keyset struct {
+	Sorts    []Sort
+	Limit    int
+	values   []interface{}
+	backward bool
+}
+
+// cursor is the opaque value encoded in after/before parameters
+type cursor struct {
+	Keys   []string      `json:"k"`
+	Values []interface{} `json:"v"`
+}
+
+var schemaCache = &sync.Map{}
+
+// IsCursorRequest returns true if the client asked for cursor pagination
+func IsCursorRequest(values url.Values) bool {
+	return values.Get("after") != "" || values.Get("before") != "" || values.Get("pagination") == "cursor"
+}
+
+// ParseKeyset builds keyset pagination from after/before parameters.
+// Urutan memakai sort dari query (atau defaultSort), ditambah field "id" sebagai tie-breaker
+func (r Resource) ParseKeyset(values url.Values, query *Query, defaultSort string, limit int) (*Keyset, error) {
+	sorts := query.Sorts
+	if len(sorts) == 0 {
+		defaults, err := r.parseSort(defaultSort)
+		if err != nil {
+			return nil, err
+		}
+		sorts = defaults
+	}
+
+	for _, s := range sorts {
+		if r.Fields[s.Field].Nullable {
+			return nil, &Error{Param: "sort", Message: fmt.Sprintf("field %q cannot be used with cursor pagination", s.Field)}
+		}
+	}
+
+	hasID := false
+	for _, s := range sorts {
+		if s.Field == "id" {
+			hasID = true
+		}
+	}
+	if !hasID {
+		idField, ok := r.Fields["id"]
+		if !ok {
+			return nil, &Error{Param: "sort", Message: "resource does not support cursor pagination"}
+		}
+		sorts = append(append([]Sort{}, sorts...), Sort{Field: "id", Column: idField.Column, Desc: sorts[len(sorts)-1].Desc})
+	}
+
+	keyset := &Keyset{Sorts: sorts, Limit: limit}
+
+	after, before := values.Get("after"), values.Get("before")
+	if after != "" && before != "" {
+		return nil, &Error{Param: "after", Message: "after and before cannot be used together"}
+	}
+
+	param, raw := "after", after
+	if before != "" {
+		param, raw = "before", before
+		keyset.backward = true
+	}
+	if raw == "" {
+		return keyset, nil
+	}
+
+	decoded, err := r.decodeCursor(raw, sorts)
+	if err != nil {
+		return nil, &Error{Param: param, Message: err.Error()}
+	}
+	keyset.values = decoded
+	return keyset, nil
+}
+
+// signature identifies the sort order a cursor was created for, e.g. "-login_time,-id"
+func signature(sorts []Sort) []string {
+	keys := make([]string, len(sorts))
+	for i, s := range sorts {
+		if s.Desc {
+			keys[i] = "-" + s.Field
+		} else {
+			keys[i] = s.Field
+		}
+	}
+	return keys
+}
+
+func (r Resource) decodeCursor(raw string, sorts []Sort) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	if strings.Join(c.Keys, ",") != strings.Join(signature(sorts), ",") || len(c.Values) != len(sorts) {
+		return nil, fmt.Errorf("cursor does not match the requested sort order")
+	}
+
+	values := make([]interface{}, len(sorts))
+	for i, s := range sorts {
+		value, err := decodeCursorValue(r.Fields[s.Field].Type, c.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("malformed cursor")
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func decodeCursorValue(fieldType FieldType, raw interface{}) (interface{}, error) {
+	switch fieldType {
+	case Int:
+		number, ok := raw.(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected number")
+		}
+		return int(number), nil
+	case Bool:
+		value, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool")
+		}
+		return value, nil
+	}
+
+	text, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected string")
+	}
+	switch fieldType {
+	case Time:
+		return time.Parse(time.RFC3339Nano, text)
+	case UUID:
+		return uuid.Parse(text)
+	}
+	return text, nil
+}
+
+func encodeCursor(sorts []Sort, values []interface{}) string {
+	encoded := make([]interface{}, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case time.Time:
+			encoded[i] = v.Format(time.RFC3339Nano)
+		case uuid.UUID:
+			encoded[i] = v.String()
+		default:
+			encoded[i] = v
+		}
+	}
+
+	data, _ := json.Marshal(cursor{Keys: signature(sorts), Values: encoded})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// condition builds (k1 > v1) OR (k1 = v1 AND k2 > v2) ... respecting each key direction
+func (k *Keyset) condition() (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	for i, s := range k.Sorts {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, k.Sorts[j].Column+" = ?")
+			args = append(args, k.values[j])
+		}
+
+		// Ke depan: ASC pakai >, DESC pakai <. Ke belakang sebaliknya
+		op := ">"
+		if s.Desc != k.backward {
+			op = "<"
+		}
+		parts = append(parts, s.Column+" "+op+" ?")
+		args = append(args, k.values[i])
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// Find loads one page into dest (pointer to slice of model structs) and returns next/prev cursors
+func (k *Keyset) Find(db *gorm.DB, dest interface{}) (next string, prev string, err error) {
+	if k.values != nil {
+		condition, args := k.condition()
+		db = db.Where(condition, args...)
+	}
+
+	for _, s := range k.Sorts {
+		// Mundur: ambil dengan urutan terbalik lalu dibalik lagi di memory
+		if s.Desc != k.backward {
+			db = db.Order(s.Column + " DESC")
+		} else {
+			db = db.Order(s.Column + " ASC")
+		}
+	}
+
+	if err := db.Limit(k.Limit + 1).Find(dest).Error; err != nil {
+		return "", "", err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	hasMore := rows.Len() > k.Limit
+	if hasMore {
+		rows.Set(rows.Slice(0, k.Limit))
+	}
+	if k.backward {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if rows.Len() == 0 {
+		return "", "", nil
+	}
+
+	sch, err := schema.Parse(dest, schemaCache, db.NamingStrategy)
+	if err != nil {
+		return "", "", err
+	}
+
+	first, err := k.rowValues(sch, rows.Index(0))
+	if err != nil {
+		return "", "", err
+	}
+	last, err := k.rowValues(sch, rows.Index(rows.Len()-1))
+	if err != nil {
+		return "", "", err
+	}
+
+	if (!k.backward && hasMore) || (k.backward && k.values != nil) {
+		next = encodeCursor(k.Sorts, last)
+	}
+	if (k.backward && hasMore) || (!k.backward && k.values != nil) {
+		prev = encodeCursor(k.Sorts, first)
+	}
+	return next, prev, nil
+}
+
+// rowValues reads the sort key values of one row using the gorm schema
+func (k *Keyset) rowValues(sch *schema.Schema, row reflect.Value) ([]interface{}, error) {
+	values := make([]interface{}, len(k.Sorts))
+	for i, s := range k.Sorts {
+		column := s.Column
+		if dot := strings.LastIndex(column, "."); dot >= 0 {
+			column = column[dot+1:]
+		}
+
+		field := sch.LookUpField(column)
+		if field == nil {
+			return nil, fmt.Errorf("cursor column %s not found in %s", column, sch.Name)
+		}
+		values[i], _ = field.ValueOf(context.Background(), reflect.Indirect(row))
+	}
+	return values, nil
+}
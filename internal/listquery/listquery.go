@@ -0,0 +1,326 @@
+// Package listquery parses sorting and filtering parameters for list endpoints.
+//
+// Sorting:   sort=-created_at,username   (prefix "-" untuk descending)
+// Filtering: filter[status][in]=ACTIVE,SUSPENDED
+//
+//	filter[type]=DRIVER            (operator default eq)
+//	created_at[gte]=2024-01-01     (bentuk singkat tanpa prefix filter)
+//
+// Setiap resource mendefinisikan whitelist field yang boleh di-filter dan di-sort,
+// field atau operator di luar whitelist menghasilkan *Error.
+package listquery
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sjek/internal/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FieldType int
+
+const (
+	String FieldType = iota
+	Int
+	Bool
+	Time
+	UUID
+)
+
+type Operator string
+
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpIn   Operator = "in"
+	OpNin  Operator = "nin"
+	OpLike Operator = "like"
+	OpNull Operator = "null"
+)
+
+// operatorsByType lists operators allowed for each field type
+var operatorsByType = map[FieldType][]Operator{
+	String: {OpEq, OpNe, OpIn, OpNin, OpLike, OpNull},
+	Int:    {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNin, OpNull},
+	Bool:   {OpEq, OpNe, OpNull},
+	Time:   {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpNull},
+	UUID:   {OpEq, OpNe, OpIn, OpNin, OpNull},
+}
+
+var sqlOperators = map[Operator]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+}
+
+// Field describes a queryable field, Column is the SQL column used in conditions.
+// Nullable field tidak bisa dipakai sebagai key cursor pagination
+type Field struct {
+	Column   string
+	Type     FieldType
+	Sortable bool
+	Filter   bool
+	Nullable bool
+}
+
+// Resource is the whitelist of fields for one list endpoint
+type Resource struct {
+	Fields map[string]Field
+}
+
+type Filter struct {
+	Field    string
+	Column   string
+	Operator Operator
+	Value    interface{}
+}
+
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+type Query struct {
+	Filters []Filter
+	Sorts   []Sort
+}
+
+// Error is returned for invalid sort or filter parameters
+type Error struct {
+	Param   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query parameter %s: %s", e.Param, e.Message)
+}
+
+var (
+	filterKeyPattern    = regexp.MustCompile(`^filter\[(\w+)\](?:\[(\w+)\])?$`)
+	shorthandKeyPattern = regexp.MustCompile(`^(\w+)\[(\w+)\]$`)
+)
+
+// Parse reads sort and filter parameters from query values.
+// Parameter lain (page, limit, filter lama tanpa bracket) diabaikan
+func (r Resource) Parse(values url.Values) (*Query, error) {
+	query := &Query{}
+
+	if sortParam := values.Get("sort"); sortParam != "" {
+		sorts, err := r.parseSort(sortParam)
+		if err != nil {
+			return nil, err
+		}
+		query.Sorts = sorts
+	}
+
+	// Urutkan key supaya SQL dan pesan error deterministik
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var name, op string
+		if match := filterKeyPattern.FindStringSubmatch(key); match != nil {
+			name, op = match[1], match[2]
+		} else if match := shorthandKeyPattern.FindStringSubmatch(key); match != nil {
+			name, op = match[1], match[2]
+		} else {
+			continue
+		}
+		if op == "" {
+			op = string(OpEq)
+		}
+
+		for _, raw := range values[key] {
+			filter, err := r.parseFilter(key, name, Operator(op), raw)
+			if err != nil {
+				return nil, err
+			}
+			query.Filters = append(query.Filters, filter)
+		}
+	}
+
+	return query, nil
+}
+
+func (r Resource) parseSort(param string) ([]Sort, error) {
+	var sorts []Sort
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		field, ok := r.Fields[name]
+		if !ok || !field.Sortable {
+			return nil, &Error{Param: "sort", Message: fmt.Sprintf("field %q is not sortable", name)}
+		}
+		sorts = append(sorts, Sort{Field: name, Column: field.Column, Desc: desc})
+	}
+	return sorts, nil
+}
+
+func (r Resource) parseFilter(param, name string, op Operator, raw string) (Filter, error) {
+	field, ok := r.Fields[name]
+	if !ok || !field.Filter {
+		return Filter{}, &Error{Param: param, Message: fmt.Sprintf("field %q is not filterable", name)}
+	}
+
+	allowed := false
+	for _, candidate := range operatorsByType[field.Type] {
+		if candidate == op {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return Filter{}, &Error{Param: param, Message: fmt.Sprintf("operator %q is not supported for field %q", op, name)}
+	}
+
+	filter := Filter{Field: name, Column: field.Column, Operator: op}
+
+	switch op {
+	case OpNull:
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Filter{}, &Error{Param: param, Message: "expected true or false"}
+		}
+		filter.Value = isNull
+	case OpIn, OpNin:
+		var list []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := parseValue(field.Type, strings.TrimSpace(item))
+			if err != nil {
+				return Filter{}, &Error{Param: param, Message: err.Error()}
+			}
+			list = append(list, value)
+		}
+		filter.Value = list
+	case OpLike:
+		filter.Value = database.ContainsPattern(raw)
+	default:
+		value, err := parseValue(field.Type, raw)
+		if err != nil {
+			return Filter{}, &Error{Param: param, Message: err.Error()}
+		}
+		// Tanggal tanpa jam: lte/gt mencakup sampai akhir hari tersebut
+		if field.Type == Time && isDateOnly(raw) {
+			if op == OpLte {
+				filter.Operator = OpLt
+				value = value.(time.Time).AddDate(0, 0, 1)
+			} else if op == OpGt {
+				filter.Operator = OpGte
+				value = value.(time.Time).AddDate(0, 0, 1)
+			}
+		}
+		filter.Value = value
+	}
+
+	return filter, nil
+}
+
+func isDateOnly(raw string) bool {
+	_, err := time.Parse("2006-01-02", raw)
+	return err == nil
+}
+
+func parseValue(fieldType FieldType, raw string) (interface{}, error) {
+	switch fieldType {
+	case Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", raw)
+		}
+		return value, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", raw)
+		}
+		return value, nil
+	case Time:
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value, nil
+		}
+		value, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("expected RFC3339 time or YYYY-MM-DD date, got %q", raw)
+		}
+		return value, nil
+	case UUID:
+		value, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected UUID, got %q", raw)
+		}
+		return value, nil
+	}
+	return raw, nil
+}
+
+// HasSort returns true if the client requested an explicit sort order
+func (q *Query) HasSort() bool {
+	return len(q.Sorts) > 0
+}
+
+// ApplyFilters adds WHERE conditions for all parsed filters
+func (q *Query) ApplyFilters(db *gorm.DB) *gorm.DB {
+	for _, filter := range q.Filters {
+		switch filter.Operator {
+		case OpNull:
+			if filter.Value.(bool) {
+				db = db.Where(filter.Column + " IS NULL")
+			} else {
+				db = db.Where(filter.Column + " IS NOT NULL")
+			}
+		case OpIn:
+			db = db.Where(filter.Column+" IN ?", filter.Value)
+		case OpNin:
+			db = db.Where(filter.Column+" NOT IN ?", filter.Value)
+		case OpLike:
+			db = db.Where(filter.Column+` ILIKE ? ESCAPE '\'`, filter.Value)
+		default:
+			db = db.Where(filter.Column+" "+sqlOperators[filter.Operator]+" ?", filter.Value)
+		}
+	}
+	return db
+}
+
+// ApplySort adds ORDER BY for the requested sort, or the fallback order when none was given
+func (q *Query) ApplySort(db *gorm.DB, fallback string) *gorm.DB {
+	if !q.HasSort() {
+		if fallback != "" {
+			db = db.Order(fallback)
+		}
+		return db
+	}
+
+	for _, s := range q.Sorts {
+		if s.Desc {
+			db = db.Order(s.Column + " DESC")
+		} else {
+			db = db.Order(s.Column + " ASC")
+		}
+	}
+	return db
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM query logs to slog. Query gagal dicatat sebagai error, query yang
+// lebih lama dari SlowThreshold sebagai warning, dan semua query pada level info
+type GormLogger struct {
+	Level         gormlogger.LogLevel
+	SlowThreshold time.Duration
+}
+
+// GormFromEnv builds the GORM logger from DB_LOG_LEVEL (silent/error/warn/info, default warn)
+// and DB_SLOW_QUERY_THRESHOLD (default 200ms)
+func GormFromEnv() *GormLogger {
+	l := &GormLogger{Level: gormlogger.Warn, SlowThreshold: 200 * time.Millisecond}
+
+	switch strings.ToLower(os.Getenv("DB_LOG_LEVEL")) {
+	case "silent":
+		l.Level = gormlogger.Silent
+	case "error":
+		l.Level = gormlogger.Error
+	case "info":
+		l.Level = gormlogger.Info
+	}
+
+	if value := os.Getenv("DB_SLOW_QUERY_THRESHOLD"); value != "" {
+		if threshold, err := time.ParseDuration(value); err == nil {
+			l.SlowThreshold = threshold
+		} else {
+			slog.Warn("Invalid DB_SLOW_QUERY_THRESHOLD, using default", "value", value, "default", l.SlowThreshold.String())
+		}
+	}
+	return l
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.Level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, data...), "component", "gorm")
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, data...), "component", "gorm")
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, data...), "component", "gorm")
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{"component", "gorm", "sql", sql, "rows", rows, "duration_ms", float64(elapsed.Microseconds()) / 1000}
+	}
+
+	switch {
+	// Record not found adalah alur normal (404), bukan error database
+	case err != nil && l.Level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		slog.ErrorContext(ctx, "Query failed", append(attrs(), "error", err.Error())...)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.Level >= gormlogger.Warn:
+		slog.WarnContext(ctx, "Slow query", append(attrs(), "threshold_ms", l.SlowThreshold.Milliseconds())...)
+	case l.Level >= gormlogger.Info:
+		slog.InfoContext(ctx, "Query", attrs()...)
+	}
+}
+
+// ParamsFilter redacts secrets from query parameters before GORM renders the SQL for logging
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, RedactParams(sql, params)
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveColumns are never written to the log, apa pun bentuk nilainya
+var sensitiveColumns = map[string]bool{
+	"password":       true,
+	"token":          true,
+	"token_hash":     true,
+	"secret":         true,
+	"pii_salt":       true,
+	"actor_pii_salt": true,
+	"data_pii_salt":  true,
+}
+
+var (
+	// "column" = $1, column IN ($1,$2), column LIKE $1
+	comparisonPattern = regexp.MustCompile(`(?i)"?(\w+)"?\s*(?:=|<>|!=|\bIN\b|\bLIKE\b|\bILIKE\b)\s*\(?\s*((?:\$\d+\s*,?\s*)+)`)
+	insertPattern     = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES\s*(.*)$`)
+	tuplePattern      = regexp.MustCompile(`\(([^()]*\$\d+[^()]*)\)`)
+	placeholder       = regexp.MustCompile(`\$(\d+)`)
+
+	// Nilai yang jelas rahasia walaupun kolomnya tidak dikenali: hash bcrypt dan JWT
+	secretValuePattern = regexp.MustCompile(`^(\$2[aby]\$\d{2}\$.{53}|eyJ[\w-]+\.[\w-]+\.[\w-]+)$`)
+)
+
+// RedactParams replaces parameters bound to sensitive columns, or that look like secrets,
+// with [REDACTED]. sql memakai placeholder $n seperti yang dihasilkan driver postgres
+func RedactParams(sql string, params []interface{}) []interface{} {
+	if len(params) == 0 {
+		return params
+	}
+
+	hidden := make(map[int]bool)
+	for _, match := range comparisonPattern.FindAllStringSubmatch(sql, -1) {
+		if sensitiveColumns[strings.ToLower(match[1])] {
+			markPlaceholders(hidden, match[2])
+		}
+	}
+
+	if match := insertPattern.FindStringSubmatch(sql); match != nil {
+		columns := strings.Split(match[1], ",")
+		for _, tuple := range tuplePattern.FindAllStringSubmatch(match[2], -1) {
+			for i, value := range strings.Split(tuple[1], ",") {
+				if i < len(columns) && sensitiveColumns[strings.ToLower(strings.Trim(strings.TrimSpace(columns[i]), `"`))] {
+					markPlaceholders(hidden, value)
+				}
+			}
+		}
+	}
+
+	filtered := make([]interface{}, len(params))
+	for i, param := range params {
+		if s, ok := param.(string); ok && secretValuePattern.MatchString(s) {
+			hidden[i] = true
+		}
+		if hidden[i] {
+			filtered[i] = redacted
+		} else {
+			filtered[i] = param
+		}
+	}
+	return filtered
+}
+
+// markPlaceholders records the zero-based parameter index of every $n in s
+func markPlaceholders(hidden map[int]bool, s string) {
+	for _, match := range placeholder.FindAllStringSubmatch(s, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil && n > 0 {
+			hidden[n-1] = true
+		}
+	}
+}
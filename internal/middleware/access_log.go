@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"time"
+
+	"sjek/internal/accesslog"
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AccessLog records every request (subject to sampling and route exclusions) to the
+// access log table through w. Dipasang setelah RequestID; user_id dan token_id dibaca
+// setelah c.Next() sehingga terisi oleh AuthMiddleware pada route yang dilindungi
+func AccessLog(w *accesslog.Writer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		status := c.Writer.Status()
+		if !w.ShouldRecord(route, status) {
+			return
+		}
+
+		entry := models.AccessLog{
+			ID:        uuid.New(),
+			CreatedAt: start,
+			Method:    c.Request.Method,
+			Route:     route,
+			Status:    status,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			IPAddress: c.ClientIP(),
+			RequestID: c.GetString("request_id"),
+		}
+		if userID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+			entry.UserID = &userID
+		}
+		if tokenID, err := uuid.Parse(c.GetString("token_id")); err == nil {
+			entry.TokenID = &tokenID
+		}
+		w.Record(entry)
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"sjek/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DriverVerificationRequired returns true when unverified drivers are limited to /me routes
+func DriverVerificationRequired() bool {
+	return os.Getenv("DRIVER_VERIFICATION_REQUIRED") == "true"
+}
+
+// DriverAccessDenial returns why a driver may only use /me routes, or an empty string when the
+// user has full access. Driver tanpa profile, dengan profile pending/ditolak, atau SIM kedaluwarsa
+// tetap bisa login untuk melengkapi profile lewat /me/driver-profile
+func DriverAccessDenial(db *gorm.DB, user models.User) (string, error) {
+	if user.Type != models.UserTypeDriver || !DriverVerificationRequired() {
+		return "", nil
+	}
+
+	var profile models.DriverProfile
+	if err := db.Where("user_id = ?", user.ID).First(&profile).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "Driver profile is not completed", nil
+		}
+		return "", err
+	}
+
+	if profile.VerificationStatus != models.VerificationVerified {
+		return "Driver profile is not verified", nil
+	}
+	if !profile.IsLicenseValidAt(time.Now()) {
+		return "Driver license has expired", nil
+	}
+	return "", nil
+}
+
+// DriverVerificationMiddleware denies routes outside /me to drivers that are not verified yet.
+// Dicek setiap request supaya verifikasi langsung berlaku tanpa login ulang.
+// Harus dipasang setelah AuthMiddleware.
+func DriverVerificationMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		if !DriverVerificationRequired() {
+			c.Next()
+			return
+		}
+
+		userID, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("id", "type").First(&user, "id = ?", userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		denial, err := DriverAccessDenial(db, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check driver profile"})
+			c.Abort()
+			return
+		}
+		if denial != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": denial + ", only /me endpoints are available"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
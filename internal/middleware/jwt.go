@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"sjek/internal/database"
+	"sjek/internal/events"
 	"sjek/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -41,7 +44,7 @@ func GenerateToken(userID, username string, roles []string) (string, error) {
 }
 
 // SaveTokenToDB saves token to database
-func SaveTokenToDB(userID, token, ipAddress, userAgent string) error {
+func SaveTokenToDB(ctx context.Context, userID, token, ipAddress, userAgent string) error {
 	// Parse userID to UUID
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
@@ -59,13 +62,13 @@ func SaveTokenToDB(userID, token, ipAddress, userAgent string) error {
 		UserAgent: userAgent,
 	}
 
-	return database.DB.Create(&userToken).Error
+	return database.WithContext(ctx).Create(&userToken).Error
 }
 
 // ValidateTokenFromDB validates token exists in database and is active
-func ValidateTokenFromDB(token string) (*models.UserToken, error) {
+func ValidateTokenFromDB(ctx context.Context, token string) (*models.UserToken, error) {
 	var userToken models.UserToken
-	err := database.DB.Where("token = ? AND is_active = ? AND expires_at > ?", 
+	err := database.WithContext(ctx).Where("token = ? AND is_active = ? AND expires_at > ?", 
 		token, true, time.Now()).First(&userToken).Error
 	
 	if err != nil {
@@ -94,7 +97,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		tokenString := bearerToken[1]
 
 		// Validate token from database first
-		userToken, err := ValidateTokenFromDB(tokenString)
+		userToken, err := ValidateTokenFromDB(c.Request.Context(), tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token not found or expired"})
 			c.Abort()
@@ -121,10 +124,18 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Roles diambil ulang dari database agar assignment yang kedaluwarsa tidak berlaku
+		roles, err := database.ActiveRoleNames(database.WithContext(c.Request.Context()), userToken.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve user roles"})
+			c.Abort()
+			return
+		}
+
 		// Set context values
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
-		c.Set("roles", claims.Roles)
+		c.Set("roles", roles)
 		c.Set("token_id", userToken.ID.String())
 		c.Next()
 	}
@@ -163,8 +174,52 @@ func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// APIAccess is the result of resolving API permissions for a set of roles
+type APIAccess struct {
+	API       models.API
+	GrantedBy []string
+	DeniedBy  []string
+}
+
+// Allowed returns true if at least one role grants the API and none denies it
+func (a *APIAccess) Allowed() bool {
+	return len(a.DeniedBy) == 0 && len(a.GrantedBy) > 0
+}
+
+// ResolveAPIAccess finds the API registered for method and route path and
+// lists which of the given roles grant or deny it. Returns gorm.ErrRecordNotFound
+// if the API is not registered.
+func ResolveAPIAccess(db *gorm.DB, roles []string, method, path string) (*APIAccess, error) {
+	var access APIAccess
+	if err := db.Where("path = ? AND method = ?", path, method).First(&access.API).Error; err != nil {
+		return nil, err
+	}
+
+	// Deny rule selalu menang atas grant
+	err := db.Table("roles").
+		Joins("JOIN map_role_api_deny ON map_role_api_deny.role_id = roles.id").
+		Where("map_role_api_deny.api_id = ? AND roles.name IN ?", access.API.ID, roles).
+		Order("roles.name ASC").
+		Pluck("roles.name", &access.DeniedBy).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Table("roles").
+		Joins("JOIN map_role_api ON map_role_api.role_id = roles.id").
+		Where("map_role_api.api_id = ? AND roles.name IN ?", access.API.ID, roles).
+		Order("roles.name ASC").
+		Pluck("roles.name", &access.GrantedBy).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &access, nil
+}
+
 func APIAccessMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
 		// Dapatkan roles dari context yang sudah diset oleh AuthMiddleware
 		userRoles, exists := c.Get("roles")
 		if !exists {
@@ -178,23 +233,27 @@ func APIAccessMiddleware(db *gorm.DB) gin.HandlerFunc {
 		path := c.FullPath()
 		method := c.Request.Method
 
-		// Cari API di database
-		var api models.API
-		if err := db.Where("path = ? AND method = ?", path, method).First(&api).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+		// Cari API di database beserta grant dan deny untuk roles user
+		access, err := ResolveAPIAccess(db, roles, method, path)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API access"})
+			}
 			c.Abort()
 			return
 		}
 
-		// Cek apakah user memiliki role yang bisa mengakses API ini
-		var count int64
-		if err := db.Table("map_role_api").Where("api_id = ? AND role_id IN (SELECT id FROM roles WHERE name IN ?)", api.ID, roles).Count(&count).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API access"})
+		if len(access.DeniedBy) > 0 {
+			publishAccessDenied(c, db, roles, access)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this API is explicitly denied for your role"})
 			c.Abort()
 			return
 		}
 
-		if count == 0 {
+		if !access.Allowed() {
+			publishAccessDenied(c, db, roles, access)
 			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this API"})
 			c.Abort()
 			return
@@ -203,3 +262,26 @@ func APIAccessMiddleware(db *gorm.DB) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// publishAccessDenied emits an access.denied security event. Kegagalan publish hanya
+// dicatat di log supaya tidak mengubah response
+func publishAccessDenied(c *gin.Context, db *gorm.DB, roles []string, access *APIAccess) {
+	event := events.Event{
+		Type:      events.TypeAccessDenied,
+		Username:  c.GetString("username"),
+		IPAddress: c.ClientIP(),
+		RequestID: c.GetString("request_id"),
+		Data: map[string]interface{}{
+			"method":    c.Request.Method,
+			"route":     c.FullPath(),
+			"roles":     roles,
+			"denied_by": access.DeniedBy,
+		},
+	}
+	if userID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		event.UserID = &userID
+	}
+	if err := events.Publish(db, event); err != nil {
+		slog.WarnContext(c.Request.Context(), "Failed to publish security event", "type", event.Type, "error", err)
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"sjek/internal/models"
+	"sjek/internal/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidResourceID is returned by loaders when the path parameter is not a valid UUID
+var ErrInvalidResourceID = errors.New("Invalid resource ID")
+
+// ResourceLoader loads the attributes of the resource addressed by the request
+type ResourceLoader func(c *gin.Context) (policy.Resource, error)
+
+// SubjectFromContext builds policy subject from values set by AuthMiddleware
+func SubjectFromContext(c *gin.Context, db *gorm.DB) (policy.Subject, error) {
+	var subject policy.Subject
+
+	userID, _ := c.Get("user_id")
+	subject.UserID, _ = userID.(string)
+
+	userRoles, _ := c.Get("roles")
+	subject.Roles, _ = userRoles.([]string)
+
+	userUUID, err := uuid.Parse(subject.UserID)
+	if err != nil {
+		return subject, err
+	}
+
+	var user models.User
+	if err := db.Select("id", "type").First(&user, "id = ?", userUUID).Error; err != nil {
+		return subject, err
+	}
+	subject.Type = user.Type
+
+	return subject, nil
+}
+
+// PolicyMiddleware evaluates attribute based policies for the current route.
+// Harus dipasang setelah AuthMiddleware.
+func PolicyMiddleware(db *gorm.DB, loader ResourceLoader, policies ...policy.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		subject, err := SubjectFromContext(c, db)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		resource, err := loader(c)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		decision := policy.Evaluate(policies, subject, resource)
+		if !decision.Allowed {
+			// Nama policy dan alasannya hanya dicatat di log, tidak dikirim ke client
+			slog.InfoContext(c.Request.Context(), "Access denied by policy",
+				"policy", decision.Policy, "reason", decision.Reason,
+				"user_id", subject.UserID, "resource_type", resource.Type, "resource_id", resource.ID)
+			if decision.HideResource {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+			} else {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// UserResource loads the user addressed by the :id path parameter
+func UserResource(db *gorm.DB) ResourceLoader {
+	return func(c *gin.Context) (policy.Resource, error) {
+		db := db.WithContext(c.Request.Context())
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return policy.Resource{}, ErrInvalidResourceID
+		}
+
+		var user models.User
+		if err := db.Select("id", "type", "status").First(&user, "id = ?", id).Error; err != nil {
+			return policy.Resource{}, err
+		}
+
+		return policy.Resource{
+			Type:       "user",
+			ID:         user.ID.String(),
+			OwnerID:    user.ID.String(),
+			Status:     string(user.Status),
+			Attributes: map[string]string{"type": string(user.Type)},
+		}, nil
+	}
+}
+
+// TokenResource loads the token addressed by the :id path parameter
+func TokenResource(db *gorm.DB) ResourceLoader {
+	return func(c *gin.Context) (policy.Resource, error) {
+		db := db.WithContext(c.Request.Context())
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return policy.Resource{}, ErrInvalidResourceID
+		}
+
+		var token models.UserToken
+		if err := db.Select("id", "user_id", "is_active").First(&token, "id = ?", id).Error; err != nil {
+			return policy.Resource{}, err
+		}
+
+		status := "INACTIVE"
+		if token.IsActive {
+			status = "ACTIVE"
+		}
+
+		return policy.Resource{
+			Type:    "token",
+			ID:      token.ID.String(),
+			OwnerID: token.UserID.String(),
+			Status:  status,
+		}, nil
+	}
+}
+
+// DocumentResource loads the document addressed by the :id path parameter
+func DocumentResource(db *gorm.DB) ResourceLoader {
+	return func(c *gin.Context) (policy.Resource, error) {
+		db := db.WithContext(c.Request.Context())
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return policy.Resource{}, ErrInvalidResourceID
+		}
+
+		var document models.Document
+		if err := db.Select("id", "user_id", "type", "status").First(&document, "id = ?", id).Error; err != nil {
+			return policy.Resource{}, err
+		}
+
+		return policy.Resource{
+			Type:       "document",
+			ID:         document.ID.String(),
+			OwnerID:    document.UserID.String(),
+			Status:     string(document.Status),
+			Attributes: map[string]string{"type": string(document.Type)},
+		}, nil
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"strings"
+
+	"sjek/internal/policy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RouteKind is the middleware chain a route is registered behind
+type RouteKind string
+
+const (
+	// RoutePublic routes do not require authentication
+	RoutePublic RouteKind = "public"
+	// RouteAuthenticated routes only require a valid token, misalnya /me
+	RouteAuthenticated RouteKind = "authenticated"
+	// RouteProtected routes run AuthMiddleware, DriverVerificationMiddleware and APIAccessMiddleware
+	RouteProtected RouteKind = "protected"
+)
+
+// RoutePolicy is the PolicyMiddleware configuration of a route
+type RoutePolicy struct {
+	Loader   ResourceLoader
+	Policies []policy.Policy
+}
+
+// RouteAccess records which middleware guards each route. gin tidak mengekspos handler chain
+// per route, jadi router mencatatnya saat setup supaya /access/explain bisa mengevaluasi
+// rantai yang sama
+type RouteAccess struct {
+	kinds    map[string]RouteKind
+	policies map[string]RoutePolicy
+}
+
+// NewRouteAccess creates an empty registry
+func NewRouteAccess() *RouteAccess {
+	return &RouteAccess{kinds: make(map[string]RouteKind), policies: make(map[string]RoutePolicy)}
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// Set records the kind of a single route
+func (r *RouteAccess) Set(method, path string, kind RouteKind) {
+	r.kinds[routeKey(method, path)] = kind
+}
+
+// Mark records kind for every route that has no kind yet. Dipanggil setelah setiap kelompok
+// route didaftarkan dengan router.Routes()
+func (r *RouteAccess) Mark(routes gin.RoutesInfo, kind RouteKind) {
+	for _, route := range routes {
+		if _, ok := r.kinds[routeKey(route.Method, route.Path)]; !ok {
+			r.Set(route.Method, route.Path, kind)
+		}
+	}
+}
+
+// Kind returns the kind of a registered route pattern
+func (r *RouteAccess) Kind(method, path string) (RouteKind, bool) {
+	kind, ok := r.kinds[routeKey(method, path)]
+	return kind, ok
+}
+
+// Policy returns the policies of a route pattern, if the route uses PolicyMiddleware
+func (r *RouteAccess) Policy(method, path string) (RoutePolicy, bool) {
+	routePolicy, ok := r.policies[routeKey(method, path)]
+	return routePolicy, ok
+}
+
+// Handle registers a route of rg behind PolicyMiddleware and records its policies
+func (r *RouteAccess) Handle(rg *gin.RouterGroup, method, path string, db *gorm.DB, routePolicy RoutePolicy, handlers ...gin.HandlerFunc) {
+	fullPath := strings.TrimSuffix(rg.BasePath(), "/") + path
+	r.policies[routeKey(method, fullPath)] = routePolicy
+	guard := PolicyMiddleware(db, routePolicy.Loader, routePolicy.Policies...)
+	rg.Handle(method, path, append([]gin.HandlerFunc{guard}, handlers...)...)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessLog adalah satu request HTTP. Tabel access_logs dipartisi per hari berdasarkan
+// created_at dan dibuat dengan SQL (lihat database.MigrateAccessLogs), bukan AutoMigrate
+type AccessLog struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	CreatedAt time.Time  `json:"created_at" gorm:"primaryKey"`
+	Method    string     `json:"method"`
+	Route     string     `json:"route"` // route pattern, misalnya /users/:id; kosong jika tidak ada route yang cocok
+	Status    int        `json:"status"`
+	LatencyMs float64    `json:"latency_ms"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`
+	TokenID   *uuid.UUID `json:"token_id,omitempty" gorm:"type:uuid"`
+	IPAddress string     `json:"ip_address"`
+	RequestID string     `json:"request_id,omitempty"`
+}
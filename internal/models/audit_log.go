@@ -0,0 +1,155 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JSON is a raw JSON document stored in a jsonb column
+type JSON []byte
+
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", value)
+	}
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
+// AuditLog mencatat setiap perubahan data oleh admin, ditulis dalam transaksi yang sama dengan perubahannya
+type AuditLog struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4();index:idx_audit_logs_created_at_id,priority:2"`
+	ActorID       *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid;index"`
+	ActorUsername string     `json:"actor_username,omitempty"`
+	Action        string     `json:"action" gorm:"type:varchar(30);not null;index"`
+	ResourceType  string     `json:"resource_type" gorm:"type:varchar(50);not null;index:idx_audit_logs_resource,priority:1"`
+	ResourceID    string     `json:"resource_id" gorm:"index:idx_audit_logs_resource,priority:2"`
+	Before        JSON       `json:"before,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	After         JSON       `json:"after,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	Changes       JSON       `json:"changes,omitempty" gorm:"type:jsonb" swaggertype:"object"`
+	IPAddress     string     `json:"ip_address"`
+	UserAgent     string     `json:"user_agent"`
+	RequestID     string     `json:"request_id,omitempty" gorm:"index"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"index:idx_audit_logs_created_at_id,priority:1"`
+
+	// Data pribadi actor (username, IP, user agent) dan snapshot before/after/changes di-hash
+	// terpisah dengan salt acak masing-masing, seperti LoginLog, supaya purge user bisa
+	// menghapus data dan salt tanpa memutus hash chain
+	ActorPIIHash      string     `json:"-" gorm:"type:varchar(64)"`
+	ActorPIISalt      string     `json:"-" gorm:"type:varchar(32)"`
+	ActorAnonymizedAt *time.Time `json:"actor_anonymized_at,omitempty"`
+	DataPIIHash       string     `json:"-" gorm:"type:varchar(64)"`
+	DataPIISalt       string     `json:"-" gorm:"type:varchar(32)"`
+	DataRedactedAt    *time.Time `json:"data_redacted_at,omitempty"`
+	ChainLink
+}
+
+func (a *AuditLog) actorDataHash() string {
+	return piiHash(a.ActorPIISalt, a.ActorUsername, a.IPAddress, a.UserAgent)
+}
+
+// anonymizedActorHash is the hash of the actor data as AnonymizeUser leaves it
+func (a *AuditLog) anonymizedActorHash() string {
+	username := ""
+	if a.ActorID != nil {
+		username = AnonymizedUsername(*a.ActorID)
+	}
+	return piiHash("", username, "", "")
+}
+
+func (a *AuditLog) snapshotDataHash() string {
+	return piiHash(a.DataPIISalt, canonicalJSON(a.Before), canonicalJSON(a.After), canonicalJSON(a.Changes))
+}
+
+// canonicalJSON encodes a jsonb value independent of how Postgres formats it
+func canonicalJSON(j JSON) string {
+	encoded, _ := json.Marshal(chainJSON(j))
+	return string(encoded)
+}
+
+func newPIISalt() string {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	return hex.EncodeToString(salt)
+}
+
+// ChainRecordID identifies the entry in chain verification reports
+func (a *AuditLog) ChainRecordID() string {
+	return a.ID.String()
+}
+
+// ChainSeal fixes created_at and hashes the personal data before the entry is hashed
+func (a *AuditLog) ChainSeal(now time.Time) []string {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = now
+	}
+	a.CreatedAt = a.CreatedAt.Truncate(time.Microsecond)
+
+	if a.ActorPIIHash == "" {
+		a.ActorPIISalt = newPIISalt()
+		a.ActorPIIHash = a.actorDataHash()
+	}
+	if a.DataPIIHash == "" {
+		a.DataPIISalt = newPIISalt()
+		a.DataPIIHash = a.snapshotDataHash()
+	}
+	return []string{"actor_pii_hash", "actor_pii_salt", "data_pii_hash", "data_pii_salt"}
+}
+
+// ChainContent returns the hashed fields of the entry
+func (a *AuditLog) ChainContent() []interface{} {
+	return []interface{}{a.ID, a.ActorID, a.Action, a.ResourceType, a.ResourceID, a.RequestID,
+		chainTime(a.CreatedAt), a.ActorPIIHash, a.DataPIIHash}
+}
+
+// CheckChainContent verifies the actor data and the snapshots against their hashes. Bagian
+// yang sudah dianonimisasi harus persis hasil anonimisasi, timestamp saja tidak cukup
+func (a *AuditLog) CheckChainContent() error {
+	expected := a.ActorPIIHash
+	if a.ActorAnonymizedAt != nil {
+		expected = a.anonymizedActorHash()
+	}
+	if a.actorDataHash() != expected {
+		return ErrPIIHashMismatch
+	}
+
+	expected = a.DataPIIHash
+	if a.DataRedactedAt != nil {
+		expected = piiHash("", "null", "null", "null")
+	}
+	if a.snapshotDataHash() != expected {
+		return ErrPIIHashMismatch
+	}
+	return nil
+}
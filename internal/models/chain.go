@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChainLink is embedded in log records that form a tamper-evident hash chain.
+// Hash = sha256(prev_hash + "\n" + canonical JSON dari ChainContent record)
+type ChainLink struct {
+	ChainSeq *int64 `json:"chain_seq,omitempty" gorm:"uniqueIndex"`
+	PrevHash string `json:"prev_hash,omitempty" gorm:"type:varchar(64)"`
+	Hash     string `json:"hash,omitempty" gorm:"type:varchar(64)"`
+}
+
+// Link returns the chain fields of the record
+func (l *ChainLink) Link() *ChainLink {
+	return l
+}
+
+// ChainCheckpoint is a signed snapshot of a chain head. Signature adalah Ed25519 atas
+// "<chain>:<seq>:<hash>:<created_at RFC3339Nano UTC>"
+type ChainCheckpoint struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Chain     string    `json:"chain" gorm:"type:varchar(50);not null;index:idx_chain_checkpoints_chain_seq,priority:1"`
+	Seq       int64     `json:"seq" gorm:"not null;index:idx_chain_checkpoints_chain_seq,priority:2"`
+	Hash      string    `json:"hash" gorm:"type:varchar(64);not null"`
+	KeyID     string    `json:"key_id" gorm:"type:varchar(16);not null"`
+	Signature string    `json:"signature" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// chainTime formats a timestamp for hashing, in UTC with the microsecond precision Postgres stores
+func chainTime(t time.Time) string {
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+// chainJSON decodes a jsonb value so hashing does not depend on how Postgres formats it
+func chainJSON(j JSON) interface{} {
+	if len(j) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(j, &value); err != nil {
+		return string(j)
+	}
+	return value
+}
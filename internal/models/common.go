@@ -11,3 +11,16 @@ type PaginatedResponse struct {
 	Data       interface{} `json:"data"`
 	Pagination Pagination  `json:"pagination"`
 }
+
+// CursorPagination dipakai untuk keyset pagination, total hanya diisi jika with_total=true
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type CursorPaginatedResponse struct {
+	Data       interface{}      `json:"data"`
+	Pagination CursorPagination `json:"pagination"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+// RoleAPIDeny melarang role mengakses API, selalu menang atas grant di map_role_api
+type RoleAPIDeny struct {
+	RoleID    uuid.UUID `json:"role_id" gorm:"type:uuid;primaryKey"`
+	APIID     uuid.UUID `json:"api_id" gorm:"type:uuid;primaryKey"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Role Role `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	API  API  `json:"api,omitempty" gorm:"foreignKey:APIID"`
+}
+
+func (RoleAPIDeny) TableName() string {
+	return "map_role_api_deny"
+}
+
+// RoleMenuDeny menyembunyikan menu dari role, selalu menang atas grant di map_role_menu
+type RoleMenuDeny struct {
+	RoleID    uuid.UUID `json:"role_id" gorm:"type:uuid;primaryKey"`
+	MenuID    uuid.UUID `json:"menu_id" gorm:"type:uuid;primaryKey"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Role Role `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	Menu Menu `json:"menu,omitempty" gorm:"foreignKey:MenuID"`
+}
+
+func (RoleMenuDeny) TableName() string {
+	return "map_role_menu_deny"
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DocumentType string
+type DocumentStatus string
+
+const (
+	DocumentTypeKTP  DocumentType = "KTP"
+	DocumentTypeSIM  DocumentType = "SIM"
+	DocumentTypeSTNK DocumentType = "STNK"
+)
+
+const (
+	DocumentStatusPending  DocumentStatus = "PENDING"
+	DocumentStatusApproved DocumentStatus = "APPROVED"
+	DocumentStatusRejected DocumentStatus = "REJECTED"
+)
+
+// Document menyimpan metadata file KYC driver, isi file ada di storage
+type Document struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type        DocumentType   `json:"type" gorm:"type:varchar(20);not null"`
+	StorageKey  string         `json:"-" gorm:"not null;unique"`
+	FileName    string         `json:"file_name"`
+	ContentType string         `json:"content_type" gorm:"type:varchar(100)"`
+	Size        int64          `json:"size"`
+	Checksum    string         `json:"checksum" gorm:"type:varchar(64)"` // sha256 hex
+	Status      DocumentStatus `json:"status" gorm:"type:varchar(20);default:'PENDING';index"`
+	UploadedBy  *uuid.UUID     `json:"uploaded_by,omitempty" gorm:"type:uuid"`
+	ReviewedBy  *uuid.UUID     `json:"reviewed_by,omitempty" gorm:"type:uuid"`
+	ReviewedAt  *time.Time     `json:"reviewed_at,omitempty"`
+	ReviewNote  string         `json:"review_note,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// IsValidDocumentType returns true for supported KYC document types
+func IsValidDocumentType(t DocumentType) bool {
+	switch t {
+	case DocumentTypeKTP, DocumentTypeSIM, DocumentTypeSTNK:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type VerificationStatus string
+type VehicleType string
+
+const (
+	VerificationPending  VerificationStatus = "PENDING"
+	VerificationVerified VerificationStatus = "VERIFIED"
+	VerificationRejected VerificationStatus = "REJECTED"
+)
+
+const (
+	VehicleTypeMotorcycle VehicleType = "MOTORCYCLE"
+	VehicleTypeCar        VehicleType = "CAR"
+)
+
+// verificationTransitions lists allowed verification status transitions.
+// Profile yang diubah setelah verified/rejected kembali ke PENDING untuk direview ulang
+var verificationTransitions = map[VerificationStatus][]VerificationStatus{
+	VerificationPending:  {VerificationVerified, VerificationRejected},
+	VerificationVerified: {VerificationPending, VerificationRejected},
+	VerificationRejected: {VerificationPending},
+}
+
+// CanTransition returns true if verification status may change from one value to another
+func (from VerificationStatus) CanTransition(to VerificationStatus) bool {
+	for _, allowed := range verificationTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// DriverProfile menyimpan data SIM dan kendaraan untuk user bertipe DRIVER
+type DriverProfile struct {
+	ID                 uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID             uuid.UUID          `json:"user_id" gorm:"type:uuid;not null;uniqueIndex"`
+	LicenseNumber      string             `json:"license_number" gorm:"type:varchar(20);not null"`
+	LicenseExpiry      time.Time          `json:"license_expiry" gorm:"type:date;not null"`
+	VehiclePlate       string             `json:"vehicle_plate" gorm:"type:varchar(15);not null;uniqueIndex"`
+	VehicleType        VehicleType        `json:"vehicle_type" gorm:"type:varchar(20);not null"`
+	VehicleBrand       string             `json:"vehicle_brand,omitempty"`
+	VerificationStatus VerificationStatus `json:"verification_status" gorm:"type:varchar(20);default:'PENDING';index"`
+	VerifiedBy         *uuid.UUID         `json:"verified_by,omitempty" gorm:"type:uuid"`
+	VerifiedAt         *time.Time         `json:"verified_at,omitempty"`
+	RejectionReason    string             `json:"rejection_reason,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	User               User               `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// IsLicenseValidAt returns true if the driver license has not expired at the given time
+func (p DriverProfile) IsLicenseValidAt(t time.Time) bool {
+	expiry := time.Date(p.LicenseExpiry.Year(), p.LicenseExpiry.Month(), p.LicenseExpiry.Day(), 23, 59, 59, 0, t.Location())
+	return !t.After(expiry)
+}
+
+var (
+	ErrInvalidPlate         = errors.New("invalid vehicle plate, expected format like 'B 1234 ABC'")
+	ErrInvalidLicenseNumber = errors.New("invalid license number, expected 12 to 14 digits")
+	ErrInvalidVehicleType   = errors.New("invalid vehicle type, use MOTORCYCLE or CAR")
+)
+
+// Plat nomor Indonesia: kode wilayah 1-2 huruf, nomor 1-4 digit, seri 0-3 huruf
+var platePattern = regexp.MustCompile(`^([A-Z]{1,2})\s*([0-9]{1,4})\s*([A-Z]{0,3})$`)
+
+// Nomor SIM: 12 digit (format baru) atau 14 digit (format lama), pemisah diabaikan
+var licensePattern = regexp.MustCompile(`^[0-9]{12,14}$`)
+
+// NormalizePlate validates an Indonesian vehicle plate and returns it as "B 1234 ABC"
+func NormalizePlate(plate string) (string, error) {
+	match := platePattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(plate)))
+	if match == nil {
+		return "", ErrInvalidPlate
+	}
+	if match[3] == "" {
+		return match[1] + " " + match[2], nil
+	}
+	return match[1] + " " + match[2] + " " + match[3], nil
+}
+
+// NormalizeLicenseNumber validates an Indonesian SIM number and strips separators
+func NormalizeLicenseNumber(number string) (string, error) {
+	digits := strings.NewReplacer(" ", "", "-", "", ".", "").Replace(strings.TrimSpace(number))
+	if !licensePattern.MatchString(digits) {
+		return "", ErrInvalidLicenseNumber
+	}
+	return digits, nil
+}
+
+// ParseVehicleType validates vehicle type case-insensitively
+func ParseVehicleType(value string) (VehicleType, error) {
+	switch VehicleType(strings.ToUpper(strings.TrimSpace(value))) {
+	case VehicleTypeMotorcycle:
+		return VehicleTypeMotorcycle, nil
+	case VehicleTypeCar:
+		return VehicleTypeCar, nil
+	}
+	return "", ErrInvalidVehicleType
+}
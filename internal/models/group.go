@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group adalah tim operasional (misalnya driver per kota). Role yang di-assign ke group
+// berlaku untuk semua anggota, digabung dengan role yang di-assign langsung ke user
+type Group struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Name        string    `json:"name" gorm:"unique;not null"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GroupMember adalah join table map_group_user
+type GroupMember struct {
+	GroupID   uuid.UUID  `json:"group_id" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;primaryKey;index"`
+	AddedBy   *uuid.UUID `json:"added_by,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	Group Group `json:"-" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	User  User  `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (GroupMember) TableName() string {
+	return "map_group_user"
+}
+
+// GroupRole adalah join table map_group_role
+type GroupRole struct {
+	GroupID   uuid.UUID  `json:"group_id" gorm:"type:uuid;primaryKey"`
+	RoleID    uuid.UUID  `json:"role_id" gorm:"type:uuid;primaryKey;index"`
+	GrantedBy *uuid.UUID `json:"granted_by,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	Group Group `json:"-" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	Role  Role  `json:"-" gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE"`
+}
+
+func (GroupRole) TableName() string {
+	return "map_group_role"
+}
@@ -1,22 +1,37 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
+
 	"github.com/google/uuid"
 )
 
 type LoginLog struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4();index:idx_login_logs_login_time_id,priority:2"`
 	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
 	Username  string    `json:"username" gorm:"not null"`
 	Email     string    `json:"email"`
 	IPAddress string    `json:"ip_address"`
 	UserAgent string    `json:"user_agent"`
-	LoginTime time.Time `json:"login_time" gorm:"default:CURRENT_TIMESTAMP"`
-	Status    string    `json:"status" gorm:"type:varchar(20);default:'SUCCESS'"` // SUCCESS, FAILED
+	LoginTime time.Time `json:"login_time" gorm:"default:CURRENT_TIMESTAMP;index:idx_login_logs_login_time_id,priority:1;index:idx_login_logs_status_login_time,priority:2"` // index untuk keyset pagination
+	Status    string    `json:"status" gorm:"type:varchar(20);default:'SUCCESS';index:idx_login_logs_status_login_time,priority:1"` // SUCCESS, FAILED; index untuk analytics
 	Message   string    `json:"message,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Data pribadi (username, email, IP, user agent) di-hash terpisah dengan salt acak supaya
+	// anonimisasi bisa menghapus salt tanpa memutus hash chain
+	PIIHash      string     `json:"-" gorm:"type:varchar(64)"`
+	PIISalt      string     `json:"-" gorm:"type:varchar(32)"`
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+	// Hash data pribadi setelah anonimisasi, ditetapkan saat log di-chain. Log dengan
+	// anonymized_at hanya valid jika isinya persis hasil anonimisasi
+	AnonymizedHash string `json:"-" gorm:"type:varchar(64)"`
+	ChainLink
 	
 	// Relationship
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -25,4 +40,76 @@ type LoginLog struct {
 const (
 	LoginStatusSuccess = "SUCCESS"
 	LoginStatusFailed  = "FAILED"
-)
\ No newline at end of file
+)
+
+// AnonymizedUsername is the username left on a user and its login logs after anonymization
+func AnonymizedUsername(userID uuid.UUID) string {
+	return fmt.Sprintf("deleted-%s", userID)
+}
+
+// ErrPIIHashMismatch means the personal data of a log no longer matches its hash
+var ErrPIIHashMismatch = errors.New("personal data does not match pii_hash")
+
+func (l *LoginLog) personalDataHash() string {
+	return piiHash(l.PIISalt, l.Username, l.Email, l.IPAddress, l.UserAgent)
+}
+
+// anonymizedDataHash is the hash of the personal data as AnonymizeUser leaves it:
+// tanpa salt, username diganti dan field lain dikosongkan
+func (l *LoginLog) anonymizedDataHash() string {
+	return piiHash("", AnonymizedUsername(l.UserID), "", "", "")
+}
+
+func piiHash(fields ...string) string {
+	sum := sha256.New()
+	for _, field := range fields {
+		sum.Write([]byte(field))
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// ChainRecordID identifies the login log in chain verification reports
+func (l *LoginLog) ChainRecordID() string {
+	return l.ID.String()
+}
+
+// ChainSeal fixes timestamps and hashes the personal data before the log is chained
+func (l *LoginLog) ChainSeal(now time.Time) []string {
+	if l.LoginTime.IsZero() {
+		l.LoginTime = now
+	}
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = now
+	}
+	l.LoginTime = l.LoginTime.Truncate(time.Microsecond)
+	l.CreatedAt = l.CreatedAt.Truncate(time.Microsecond)
+
+	if l.PIIHash == "" {
+		l.PIISalt = newPIISalt()
+		l.PIIHash = l.personalDataHash()
+	}
+	if l.AnonymizedHash == "" {
+		l.AnonymizedHash = l.anonymizedDataHash()
+	}
+	return []string{"pii_hash", "pii_salt", "anonymized_hash"}
+}
+
+// ChainContent returns the hashed fields of the login log
+func (l *LoginLog) ChainContent() []interface{} {
+	return []interface{}{l.ID, l.UserID, chainTime(l.LoginTime), l.Status, l.Message, chainTime(l.CreatedAt), l.PIIHash, l.AnonymizedHash}
+}
+
+// CheckChainContent verifies the personal data against pii_hash. Salt log yang sudah
+// dianonimisasi sudah dihapus, jadi datanya dicek terhadap anonymized_hash: anonymized_at
+// saja tidak cukup untuk melewati pengecekan
+func (l *LoginLog) CheckChainContent() error {
+	expected := l.PIIHash
+	if l.AnonymizedAt != nil {
+		expected = l.AnonymizedHash
+	}
+	if l.personalDataHash() != expected {
+		return ErrPIIHashMismatch
+	}
+	return nil
+}
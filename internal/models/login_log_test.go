@@ -0,0 +1,76 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func sealedLoginLog() LoginLog {
+	log := LoginLog{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Username:  "driver01",
+		Email:     "driver01@example.com",
+		IPAddress: "10.0.0.7",
+		UserAgent: "sjek-app/1.0",
+		Status:    LoginStatusSuccess,
+	}
+	log.ChainSeal(time.Now())
+	return log
+}
+
+// anonymize mirrors jobs.AnonymizeUser
+func anonymize(log *LoginLog) {
+	now := time.Now()
+	log.Username = AnonymizedUsername(log.UserID)
+	log.Email = ""
+	log.IPAddress = ""
+	log.UserAgent = ""
+	log.PIISalt = ""
+	log.AnonymizedAt = &now
+}
+
+func TestLoginLogCheckChainContent(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		tamper  func(log *LoginLog)
+		wantErr bool
+	}{
+		{"untouched", func(*LoginLog) {}, false},
+		{"anonymized", anonymize, false},
+		{"username changed", func(log *LoginLog) { log.Username = "someone-else" }, true},
+		{"ip changed", func(log *LoginLog) { log.IPAddress = "192.168.1.1" }, true},
+		{"anonymized_at set on real data", func(log *LoginLog) { log.AnonymizedAt = &now }, true},
+		{"anonymized_at set with forged data", func(log *LoginLog) {
+			log.AnonymizedAt = &now
+			log.PIISalt = ""
+			log.Username = "someone-else"
+		}, true},
+		{"anonymized then ip written", func(log *LoginLog) {
+			anonymize(log)
+			log.IPAddress = "192.168.1.1"
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := sealedLoginLog()
+			content := log.ChainContent()
+			tt.tamper(&log)
+
+			err := log.CheckChainContent()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckChainContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Anonimisasi tidak mengubah isi yang di-hash ke chain
+			if !reflect.DeepEqual(log.ChainContent(), content) {
+				t.Error("chain content changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+const (
+	PasswordTokenInvite = "INVITE"
+	PasswordTokenReset  = "RESET"
+)
+
+// PasswordToken adalah token sekali pakai untuk undangan dan reset password.
+// Hanya hash token yang disimpan.
+type PasswordToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash string     `json:"-" gorm:"type:varchar(64);not null;unique"`
+	Purpose   string     `json:"purpose" gorm:"type:varchar(20);not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
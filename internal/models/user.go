@@ -3,6 +3,7 @@ package models
 import (
     "time"
     "github.com/google/uuid"
+    "gorm.io/gorm"
 )
 
 type UserType string
@@ -14,20 +15,25 @@ const (
 )
 
 const (
-    UserStatusActive   UserStatus = "ACTIVE"
-    UserStatusInactive UserStatus = "INACTIVE"
+    UserStatusActive    UserStatus = "ACTIVE"
+    UserStatusInactive  UserStatus = "INACTIVE"
+    UserStatusSuspended UserStatus = "SUSPENDED"
 )
 
 type User struct {
-    ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-    Username       string     `json:"username" gorm:"unique;not null"`
-    Email          string     `json:"email" gorm:"type:varchar(1500);unique"`
-    Password       string     `json:"password,omitempty" gorm:"not null"`
-    Type           UserType   `json:"type" gorm:"type:varchar(20);default:'DRIVER'"`
-    Status         UserStatus `json:"status" gorm:"type:varchar(20);default:'ACTIVE'"`
-    ActivatedDate  *time.Time `json:"activated_date,omitempty"`
-    InactiveDate   *time.Time `json:"inactive_date,omitempty"`
-    CreatedAt      time.Time  `json:"created_at"`
-    UpdatedAt      time.Time  `json:"updated_at"`
-    Roles          []Role     `json:"roles,omitempty" gorm:"many2many:map_user_role;"`
+    ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+    Username       string         `json:"username" gorm:"unique;not null"`
+    Email          string         `json:"email" gorm:"type:varchar(1500);unique"`
+    Password       string         `json:"password,omitempty" gorm:"not null"`
+    Type           UserType       `json:"type" gorm:"type:varchar(20);default:'DRIVER'"`
+    Status         UserStatus     `json:"status" gorm:"type:varchar(20);default:'ACTIVE'"`
+    ActivatedDate  *time.Time     `json:"activated_date,omitempty"`
+    InactiveDate   *time.Time     `json:"inactive_date,omitempty"`
+    SuspendedUntil *time.Time     `json:"suspended_until,omitempty"`
+    SuspendReason  string         `json:"suspend_reason,omitempty"`
+    CreatedAt      time.Time      `json:"created_at"`
+    UpdatedAt      time.Time      `json:"updated_at"`
+    DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" format:"date-time"`
+    AnonymizedAt   *time.Time     `json:"anonymized_at,omitempty"`
+    Roles          []Role         `json:"roles,omitempty" gorm:"many2many:map_user_role;"`
 }
\ No newline at end of file
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+// UserRole adalah join table map_user_role dengan masa berlaku assignment
+type UserRole struct {
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;primaryKey"`
+	RoleID     uuid.UUID  `json:"role_id" gorm:"type:uuid;primaryKey"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" gorm:"index"`
+	Reason     string     `json:"reason,omitempty"`
+	GrantedBy  *uuid.UUID `json:"granted_by,omitempty" gorm:"type:uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Relationships
+	Role Role `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+}
+
+func (UserRole) TableName() string {
+	return "map_user_role"
+}
+
+// IsActiveAt returns true if the assignment is valid at the given time
+func (ur UserRole) IsActiveAt(t time.Time) bool {
+	if ur.ValidFrom != nil && ur.ValidFrom.After(t) {
+		return false
+	}
+	if ur.ValidUntil != nil && !ur.ValidUntil.After(t) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+// userStatusTransitions lists allowed status transitions
+var userStatusTransitions = map[UserStatus][]UserStatus{
+	UserStatusActive:    {UserStatusInactive, UserStatusSuspended},
+	UserStatusInactive:  {UserStatusActive},
+	UserStatusSuspended: {UserStatusActive, UserStatusInactive},
+}
+
+// CanTransition returns true if status may change from one value to another
+func (from UserStatus) CanTransition(to UserStatus) bool {
+	for _, allowed := range userStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UserStatusHistory mencatat setiap perubahan status user
+type UserStatusHistory struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	FromStatus UserStatus `json:"from_status" gorm:"type:varchar(20)"`
+	ToStatus   UserStatus `json:"to_status" gorm:"type:varchar(20);not null"`
+	Reason     string     `json:"reason,omitempty"`
+	Until      *time.Time `json:"until,omitempty"`
+	ChangedBy  *uuid.UUID `json:"changed_by,omitempty" gorm:"type:uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StringList is a list of strings stored as a jsonb array
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+// WebhookSubscription adalah endpoint yang menerima event webhook. EventTypes kosong berarti semua event
+type WebhookSubscription struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Name       string     `json:"name" gorm:"not null"`
+	URL        string     `json:"url" gorm:"type:varchar(2048);not null"`
+	Secret     string     `json:"-" gorm:"not null"` // kunci HMAC, hanya ditampilkan saat dibuat atau dirotasi
+	EventTypes StringList `json:"event_types" gorm:"type:jsonb;not null;default:'[]'" swaggertype:"array,string"`
+	IsActive   bool       `json:"is_active" gorm:"default:true"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Matches reports whether the subscription wants events of the given type
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent adalah outbox: ditulis dalam transaksi yang sama dengan perubahan datanya,
+// lalu disebar ke subscription oleh dispatcher (DispatchedAt terisi setelahnya)
+type WebhookEvent struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Type         string     `json:"type" gorm:"type:varchar(50);not null;index"`
+	Payload      JSON       `json:"payload" gorm:"type:jsonb;not null" swaggertype:"object"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"index"`
+	DispatchedAt *time.Time `json:"dispatched_at,omitempty" gorm:"index"`
+}
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryDead      = "DEAD" // retry habis, lihat dead-letter
+)
+
+// WebhookDelivery is one event sent to one subscription, retried until it succeeds or is dead
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	EventID        uuid.UUID  `json:"event_id" gorm:"type:uuid;not null;index"`
+	SubscriptionID uuid.UUID  `json:"subscription_id" gorm:"type:uuid;not null;index"`
+	EventType      string     `json:"event_type" gorm:"type:varchar(50);not null"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'PENDING';index:idx_webhook_deliveries_status_next,priority:1"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" gorm:"index:idx_webhook_deliveries_status_next,priority:2"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationship
+	Event *WebhookEvent `json:"event,omitempty" gorm:"foreignKey:EventID"`
+}
+
+// WebhookAttempt is the log of one HTTP request made for a delivery
+type WebhookAttempt struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	DeliveryID   uuid.UUID `json:"delivery_id" gorm:"type:uuid;not null;index"`
+	Attempt      int       `json:"attempt"`
+	StatusCode   *int      `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DurationMs   float64   `json:"duration_ms"`
+	ResponseBody string    `json:"response_body,omitempty"` // dipotong, lihat webhook.maxResponseBody
+	CreatedAt    time.Time `json:"created_at"`
+}
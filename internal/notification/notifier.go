@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Message is a notification addressed to a single user
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notification messages to users
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// LogNotifier only writes messages to the application log.
+// Dipakai jika SMTP belum dikonfigurasi.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(msg Message) error {
+	slog.Info("Notification", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// SMTPNotifier sends messages as plain text email
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (n SMTPNotifier) Send(msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("notification recipient is empty")
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + n.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(n.Host+":"+n.Port, auth, n.From, []string{msg.To}, []byte(body))
+}
+
+// FromEnv returns SMTPNotifier when SMTP_HOST is set, otherwise LogNotifier
+func FromEnv() Notifier {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogNotifier{}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	return SMTPNotifier{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+}
@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"sjek/internal/models"
+)
+
+// Subject berisi atribut user yang sedang melakukan request
+type Subject struct {
+	UserID string
+	Type   models.UserType
+	Roles  []string
+}
+
+// Resource berisi atribut resource yang sedang diakses
+type Resource struct {
+	Type       string
+	ID         string
+	OwnerID    string
+	Status     string
+	Attributes map[string]string
+}
+
+// Target menentukan apakah sebuah policy berlaku untuk subject
+type Target func(sub Subject) bool
+
+// Condition adalah syarat yang harus dipenuhi agar akses diizinkan
+type Condition func(sub Subject, res Resource) bool
+
+// Policy membatasi akses ke resource berdasarkan atribut subject dan resource.
+// Policy hanya dievaluasi jika Target cocok (nil berarti berlaku untuk semua subject).
+// HideResource membuat penolakan dilaporkan sebagai "not found" supaya keberadaan
+// resource milik orang lain tidak bocor.
+type Policy struct {
+	Name         string
+	Description  string
+	Target       Target
+	Condition    Condition
+	HideResource bool
+}
+
+// Decision adalah hasil evaluasi policy
+type Decision struct {
+	Allowed      bool   `json:"allowed"`
+	Policy       string `json:"policy,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	HideResource bool   `json:"-"`
+}
+
+// AppliesTo checks whether the policy targets the given subject
+func (p Policy) AppliesTo(sub Subject) bool {
+	return p.Target == nil || p.Target(sub)
+}
+
+// Evaluate runs all policies against subject and resource.
+// Access is denied by the first applicable policy whose condition does not hold.
+func Evaluate(policies []Policy, sub Subject, res Resource) Decision {
+	for _, p := range policies {
+		if !p.AppliesTo(sub) {
+			continue
+		}
+		if p.Condition != nil && !p.Condition(sub, res) {
+			return Decision{Allowed: false, Policy: p.Name, Reason: p.Description, HideResource: p.HideResource}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// HasRole returns true if subject has the given role
+func (s Subject) HasRole(role string) bool {
+	for _, r := range s.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets
+
+// SubjectType matches subjects with one of the given user types
+func SubjectType(types ...models.UserType) Target {
+	return func(sub Subject) bool {
+		for _, t := range types {
+			if sub.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithoutRole matches subjects that do not have any of the given roles
+func WithoutRole(roles ...string) Target {
+	return func(sub Subject) bool {
+		for _, role := range roles {
+			if sub.HasRole(role) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Conditions
+
+// IsOwner holds when the subject owns the resource
+func IsOwner(sub Subject, res Resource) bool {
+	return sub.UserID != "" && sub.UserID == res.OwnerID
+}
+
+// StatusIn holds when the resource status is one of the given values
+func StatusIn(statuses ...string) Condition {
+	return func(sub Subject, res Resource) bool {
+		for _, status := range statuses {
+			if res.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AttributeEquals holds when a resource attribute has the given value
+func AttributeEquals(key, value string) Condition {
+	return func(sub Subject, res Resource) bool {
+		return res.Attributes[key] == value
+	}
+}
+
+// All holds when every condition holds
+func All(conditions ...Condition) Condition {
+	return func(sub Subject, res Resource) bool {
+		for _, cond := range conditions {
+			if !cond(sub, res) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any holds when at least one condition holds
+func Any(conditions ...Condition) Condition {
+	return func(sub Subject, res Resource) bool {
+		for _, cond := range conditions {
+			if cond(sub, res) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not negates a condition
+func Not(condition Condition) Condition {
+	return func(sub Subject, res Resource) bool {
+		return !condition(sub, res)
+	}
+}
+
+// Built-in policies
+
+// DriverOwnUser: driver hanya boleh mengakses data user miliknya sendiri
+var DriverOwnUser = Policy{
+	Name:        "driver-own-user",
+	Description: "Drivers may only access their own user record",
+	Target:      SubjectType(models.UserTypeDriver),
+	Condition:   IsOwner,
+}
+
+// DriverOwnDocument: driver hanya boleh mengakses dokumen miliknya sendiri
+var DriverOwnDocument = Policy{
+	Name:        "driver-own-document",
+	Description: "Drivers may only access their own documents",
+	Target:      SubjectType(models.UserTypeDriver),
+	Condition:   IsOwner,
+}
+
+// OwnToken: user hanya boleh mengelola token miliknya sendiri. Token orang lain
+// diperlakukan seolah tidak ada
+var OwnToken = Policy{
+	Name:         "own-token",
+	Description:  "Users may only manage their own tokens",
+	Condition:    IsOwner,
+	HideResource: true,
+}
@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"testing"
+
+	"sjek/internal/models"
+)
+
+const (
+	driverID = "6f1c2a4e-0b7d-4d0e-9a53-1f7a3c2b9e10"
+	otherID  = "a2d9e8f1-5c3b-4e7a-8b21-9c4d6e0f1a23"
+)
+
+func TestIsOwner(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		ownerID string
+		want    bool
+	}{
+		{"same user", driverID, driverID, true},
+		{"other user", driverID, otherID, false},
+		{"empty subject", "", "", false},
+		{"empty owner", driverID, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsOwner(Subject{UserID: tt.userID}, Resource{OwnerID: tt.ownerID})
+			if got != tt.want {
+				t.Errorf("IsOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriverOwnUser(t *testing.T) {
+	tests := []struct {
+		name string
+		sub  Subject
+		res  Resource
+		want bool
+	}{
+		{"driver reads own record", Subject{UserID: driverID, Type: models.UserTypeDriver}, Resource{OwnerID: driverID}, true},
+		{"driver reads other record", Subject{UserID: driverID, Type: models.UserTypeDriver}, Resource{OwnerID: otherID}, false},
+		{"admin reads other record", Subject{UserID: driverID, Type: models.UserTypeAdmin}, Resource{OwnerID: otherID}, true},
+		{"subject without type", Subject{UserID: driverID}, Resource{OwnerID: otherID}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate([]Policy{DriverOwnUser}, tt.sub, tt.res)
+			if decision.Allowed != tt.want {
+				t.Fatalf("Allowed = %v, want %v", decision.Allowed, tt.want)
+			}
+			if !decision.Allowed && decision.Policy != DriverOwnUser.Name {
+				t.Errorf("Policy = %q, want %q", decision.Policy, DriverOwnUser.Name)
+			}
+			if decision.HideResource {
+				t.Errorf("HideResource = true, want false")
+			}
+		})
+	}
+}
+
+func TestOwnTokenHidesResource(t *testing.T) {
+	sub := Subject{UserID: driverID, Type: models.UserTypeAdmin}
+
+	if decision := Evaluate([]Policy{OwnToken}, sub, Resource{OwnerID: driverID}); !decision.Allowed {
+		t.Fatalf("own token denied: %+v", decision)
+	}
+
+	decision := Evaluate([]Policy{OwnToken}, sub, Resource{OwnerID: otherID})
+	if decision.Allowed {
+		t.Fatal("other user's token allowed")
+	}
+	if !decision.HideResource {
+		t.Error("HideResource = false, want true")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	deny := func(name string) Policy {
+		return Policy{Name: name, Condition: func(Subject, Resource) bool { return false }}
+	}
+	allow := Policy{Name: "allow", Condition: func(Subject, Resource) bool { return true }}
+	driversOnly := func(p Policy) Policy {
+		p.Target = SubjectType(models.UserTypeDriver)
+		return p
+	}
+
+	driver := Subject{UserID: driverID, Type: models.UserTypeDriver}
+	admin := Subject{UserID: driverID, Type: models.UserTypeAdmin}
+
+	tests := []struct {
+		name       string
+		policies   []Policy
+		sub        Subject
+		wantAllow  bool
+		wantPolicy string
+	}{
+		{"no policies", nil, driver, true, ""},
+		{"nil condition", []Policy{{Name: "empty"}}, driver, true, ""},
+		{"all allow", []Policy{allow, allow}, driver, true, ""},
+		{"first denial wins", []Policy{allow, deny("first"), deny("second")}, driver, false, "first"},
+		{"target does not match", []Policy{driversOnly(deny("drivers"))}, admin, true, ""},
+		{"target matches", []Policy{driversOnly(deny("drivers"))}, driver, false, "drivers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Evaluate(tt.policies, tt.sub, Resource{})
+			if decision.Allowed != tt.wantAllow {
+				t.Fatalf("Allowed = %v, want %v", decision.Allowed, tt.wantAllow)
+			}
+			if decision.Policy != tt.wantPolicy {
+				t.Errorf("Policy = %q, want %q", decision.Policy, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestConditions(t *testing.T) {
+	sub := Subject{UserID: driverID, Roles: []string{"dispatcher"}}
+	res := Resource{OwnerID: driverID, Status: "ACTIVE", Attributes: map[string]string{"type": "DRIVER"}}
+	never := func(Subject, Resource) bool { return false }
+
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"status in", StatusIn("PENDING", "ACTIVE"), true},
+		{"status not in", StatusIn("SUSPENDED"), false},
+		{"attribute equals", AttributeEquals("type", "DRIVER"), true},
+		{"attribute differs", AttributeEquals("type", "ADMIN"), false},
+		{"missing attribute", AttributeEquals("region", ""), true},
+		{"all hold", All(IsOwner, StatusIn("ACTIVE")), true},
+		{"all with one failing", All(IsOwner, never), false},
+		{"any with one holding", Any(never, IsOwner), true},
+		{"any none holding", Any(never, StatusIn("DELETED")), false},
+		{"not", Not(IsOwner), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond(sub, res); got != tt.want {
+				t.Errorf("condition = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	sub := Subject{Type: models.UserTypeDriver, Roles: []string{"dispatcher"}}
+
+	tests := []struct {
+		name   string
+		target Target
+		want   bool
+	}{
+		{"subject type matches", SubjectType(models.UserTypeAdmin, models.UserTypeDriver), true},
+		{"subject type differs", SubjectType(models.UserTypeAdmin), false},
+		{"without role", WithoutRole("admin"), true},
+		{"has excluded role", WithoutRole("admin", "dispatcher"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target(sub); got != tt.want {
+				t.Errorf("target = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
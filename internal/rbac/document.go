@@ -0,0 +1,187 @@
+package rbac
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DocumentVersion is the current version of the RBAC document format
+const DocumentVersion = 1
+
+// Document describes the complete RBAC state: roles, APIs, menus and their mappings
+type Document struct {
+	Version int        `json:"version" yaml:"version"`
+	Roles   []RoleSpec `json:"roles" yaml:"roles"`
+	APIs    []APISpec  `json:"apis" yaml:"apis"`
+	Menus   []MenuSpec `json:"menus" yaml:"menus"`
+}
+
+type RoleSpec struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// APISpec is identified by method and path
+type APISpec struct {
+	Method      string   `json:"method" yaml:"method"`
+	Path        string   `json:"path" yaml:"path"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Roles       []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	DenyRoles   []string `json:"deny_roles,omitempty" yaml:"deny_roles,omitempty"`
+}
+
+// MenuSpec is identified by path, parent refers to the path of the parent menu
+type MenuSpec struct {
+	Name        string   `json:"name" yaml:"name"`
+	Path        string   `json:"path" yaml:"path"`
+	Parent      string   `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Icon        string   `json:"icon,omitempty" yaml:"icon,omitempty"`
+	Sequence    int      `json:"sequence" yaml:"sequence"`
+	IsActive    *bool    `json:"is_active,omitempty" yaml:"is_active,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Roles       []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	DenyRoles   []string `json:"deny_roles,omitempty" yaml:"deny_roles,omitempty"`
+}
+
+// Key returns the unique key of an API spec
+func (a APISpec) Key() string {
+	return apiKey(a.Method, a.Path)
+}
+
+// Active returns is_active value, menu aktif jika tidak diisi
+func (m MenuSpec) Active() bool {
+	return m.IsActive == nil || *m.IsActive
+}
+
+// httpMethods are the verbs an API entry may use
+var httpMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+func apiKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// Validate checks the document for duplicates, unknown HTTP methods and dangling references.
+// API methods are normalized to uppercase
+func (d *Document) Validate() error {
+	if d.Version != 0 && d.Version != DocumentVersion {
+		return fmt.Errorf("unsupported document version %d", d.Version)
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range d.Roles {
+		if role.Name == "" {
+			return fmt.Errorf("role name is required")
+		}
+		if roles[role.Name] {
+			return fmt.Errorf("duplicate role %q", role.Name)
+		}
+		roles[role.Name] = true
+	}
+
+	checkRoles := func(owner string, names []string) error {
+		for _, name := range names {
+			if !roles[name] {
+				return fmt.Errorf("%s references unknown role %q", owner, name)
+			}
+		}
+		return nil
+	}
+
+	apis := make(map[string]bool)
+	for i := range d.APIs {
+		// Method disimpan uppercase supaya cocok dengan APIAccessMiddleware
+		d.APIs[i].Method = strings.ToUpper(strings.TrimSpace(d.APIs[i].Method))
+		api := d.APIs[i]
+		if api.Method == "" || api.Path == "" {
+			return fmt.Errorf("api method and path are required")
+		}
+		if !httpMethods[api.Method] {
+			return fmt.Errorf("api %q has unknown method %q", api.Path, api.Method)
+		}
+		key := api.Key()
+		if apis[key] {
+			return fmt.Errorf("duplicate api %q", key)
+		}
+		apis[key] = true
+		if err := checkRoles("api "+key, api.Roles); err != nil {
+			return err
+		}
+		if err := checkRoles("api "+key, api.DenyRoles); err != nil {
+			return err
+		}
+	}
+
+	parents := make(map[string]string)
+	for _, menu := range d.Menus {
+		if menu.Name == "" || menu.Path == "" {
+			return fmt.Errorf("menu name and path are required")
+		}
+		if _, exists := parents[menu.Path]; exists {
+			return fmt.Errorf("duplicate menu %q", menu.Path)
+		}
+		parents[menu.Path] = menu.Parent
+		if err := checkRoles("menu "+menu.Path, menu.Roles); err != nil {
+			return err
+		}
+		if err := checkRoles("menu "+menu.Path, menu.DenyRoles); err != nil {
+			return err
+		}
+	}
+
+	for path, parent := range parents {
+		if parent == "" {
+			continue
+		}
+		if _, exists := parents[parent]; !exists {
+			return fmt.Errorf("menu %q references unknown parent %q", path, parent)
+		}
+
+		// Cegah circular reference
+		seen := map[string]bool{path: true}
+		for current := parent; current != ""; current = parents[current] {
+			if seen[current] {
+				return fmt.Errorf("circular parent reference at menu %q", path)
+			}
+			seen[current] = true
+		}
+	}
+
+	return nil
+}
+
+// menusParentFirst orders menus so every parent comes before its children
+func (d *Document) menusParentFirst() []MenuSpec {
+	depth := make(map[string]int)
+	parents := make(map[string]string)
+	for _, menu := range d.Menus {
+		parents[menu.Path] = menu.Parent
+	}
+
+	var depthOf func(path string) int
+	depthOf = func(path string) int {
+		if parents[path] == "" {
+			return 0
+		}
+		if value, ok := depth[path]; ok {
+			return value
+		}
+		depth[path] = depthOf(parents[path]) + 1
+		return depth[path]
+	}
+
+	ordered := make([]MenuSpec, len(d.Menus))
+	copy(ordered, d.Menus)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return depthOf(ordered[i].Path) < depthOf(ordered[j].Path)
+	})
+	return ordered
+}
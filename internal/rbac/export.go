@@ -0,0 +1,118 @@
+package rbac
+
+import (
+	"sort"
+
+	"sjek/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// state is the current RBAC state loaded from database
+type state struct {
+	roles      []models.Role
+	apis       []models.API
+	menus      []models.Menu
+	apiDenies  []models.RoleAPIDeny
+	menuDenies []models.RoleMenuDeny
+	roleNames  map[uuid.UUID]string
+	menuPaths  map[uuid.UUID]string
+}
+
+func loadState(db *gorm.DB) (*state, error) {
+	s := &state{
+		roleNames: make(map[uuid.UUID]string),
+		menuPaths: make(map[uuid.UUID]string),
+	}
+
+	if err := db.Order("name ASC").Find(&s.roles).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Preload("Roles").Order("path ASC, method ASC").Find(&s.apis).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Preload("Roles").Order("path ASC").Find(&s.menus).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&s.apiDenies).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&s.menuDenies).Error; err != nil {
+		return nil, err
+	}
+
+	for _, role := range s.roles {
+		s.roleNames[role.ID] = role.Name
+	}
+	for _, menu := range s.menus {
+		s.menuPaths[menu.ID] = menu.Path
+	}
+
+	return s, nil
+}
+
+// Export builds a document describing the complete RBAC state
+func Export(db *gorm.DB) (*Document, error) {
+	s, err := loadState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Version: DocumentVersion}
+
+	for _, role := range s.roles {
+		doc.Roles = append(doc.Roles, RoleSpec{Name: role.Name})
+	}
+
+	apiDenies := make(map[uuid.UUID][]string)
+	for _, deny := range s.apiDenies {
+		apiDenies[deny.APIID] = append(apiDenies[deny.APIID], s.roleNames[deny.RoleID])
+	}
+	for _, api := range s.apis {
+		doc.APIs = append(doc.APIs, APISpec{
+			Method:      api.Method,
+			Path:        api.Path,
+			Description: api.Description,
+			Roles:       sortedRoleNames(api.Roles),
+			DenyRoles:   sortedStrings(apiDenies[api.ID]),
+		})
+	}
+
+	menuDenies := make(map[uuid.UUID][]string)
+	for _, deny := range s.menuDenies {
+		menuDenies[deny.MenuID] = append(menuDenies[deny.MenuID], s.roleNames[deny.RoleID])
+	}
+	for _, menu := range s.menus {
+		isActive := menu.IsActive
+		spec := MenuSpec{
+			Name:        menu.Name,
+			Path:        menu.Path,
+			Icon:        menu.Icon,
+			Sequence:    menu.Sequence,
+			IsActive:    &isActive,
+			Description: menu.Description,
+			Roles:       sortedRoleNames(menu.Roles),
+			DenyRoles:   sortedStrings(menuDenies[menu.ID]),
+		}
+		if menu.ParentID != nil {
+			spec.Parent = s.menuPaths[*menu.ParentID]
+		}
+		doc.Menus = append(doc.Menus, spec)
+	}
+
+	return doc, nil
+}
+
+func sortedRoleNames(roles []models.Role) []string {
+	var names []string
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return sortedStrings(names)
+}
+
+func sortedStrings(values []string) []string {
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,522 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sjek/internal/models"
+	"sjek/internal/webhook"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+const (
+	KindRole      = "role"
+	KindAPI       = "api"
+	KindMenu      = "menu"
+	KindAPIGrant  = "api_grant"
+	KindAPIDeny   = "api_deny"
+	KindMenuGrant = "menu_grant"
+	KindMenuDeny  = "menu_deny"
+)
+
+// Change is a single difference between the document and the database
+type Change struct {
+	Action  string `json:"action"`
+	Kind    string `json:"kind"`
+	Key     string `json:"key"`
+	Details string `json:"details,omitempty"`
+}
+
+// Diff lists all changes needed to bring the database in line with the document
+type Diff struct {
+	Changes []Change       `json:"changes"`
+	Summary map[string]int `json:"summary"`
+}
+
+func (d *Diff) add(action, kind, key, details string) {
+	d.Changes = append(d.Changes, Change{Action: action, Kind: kind, Key: key, Details: details})
+	d.Summary[action]++
+}
+
+// Plan computes the diff without touching the database
+func Plan(db *gorm.DB, doc *Document) (*Diff, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return sync(db, doc, false)
+}
+
+// Apply computes the diff and applies it in a single transaction.
+// onApplied (boleh nil) dijalankan di transaksi yang sama, misalnya untuk audit log
+func Apply(db *gorm.DB, doc *Document, onApplied func(tx *gorm.DB, diff *Diff) error) (*Diff, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	var diff *Diff
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if diff, err = sync(tx, doc, true); err != nil || onApplied == nil {
+			return err
+		}
+		return onApplied(tx, diff)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// syncer holds lookup tables while walking the document
+type syncer struct {
+	db      *gorm.DB
+	apply   bool
+	diff    *Diff
+	current *state
+	roleIDs map[string]uuid.UUID
+	apiIDs  map[string]uuid.UUID
+	menuIDs map[string]uuid.UUID
+}
+
+func sync(db *gorm.DB, doc *Document, apply bool) (*Diff, error) {
+	current, err := loadState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &syncer{
+		db:      db,
+		apply:   apply,
+		diff:    &Diff{Changes: []Change{}, Summary: map[string]int{}},
+		current: current,
+		roleIDs: make(map[string]uuid.UUID),
+		apiIDs:  make(map[string]uuid.UUID),
+		menuIDs: make(map[string]uuid.UUID),
+	}
+
+	// Urutan: buat/ubah entitas, sinkronkan mapping, lalu hapus entitas yang tidak ada di dokumen
+	steps := []func(*Document) error{
+		s.syncRoles,
+		s.syncAPIs,
+		s.syncMenus,
+		s.syncAPIMappings,
+		s.syncMenuMappings,
+		s.deleteMenus,
+		s.deleteAPIs,
+		s.deleteRoles,
+	}
+	for _, step := range steps {
+		if err := step(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.diff, nil
+}
+
+func (s *syncer) syncRoles(doc *Document) error {
+	for _, role := range s.current.roles {
+		s.roleIDs[role.Name] = role.ID
+	}
+
+	for _, spec := range doc.Roles {
+		if _, exists := s.roleIDs[spec.Name]; exists {
+			continue
+		}
+
+		role := models.Role{ID: uuid.New(), Name: spec.Name}
+		s.roleIDs[spec.Name] = role.ID
+		s.diff.add(ActionCreate, KindRole, spec.Name, "")
+		if s.apply {
+			if err := s.db.Create(&role).Error; err != nil {
+				return fmt.Errorf("failed to create role %q: %v", spec.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncAPIs(doc *Document) error {
+	existing := make(map[string]models.API)
+	for _, api := range s.current.apis {
+		existing[apiKey(api.Method, api.Path)] = api
+		s.apiIDs[apiKey(api.Method, api.Path)] = api.ID
+	}
+
+	for _, spec := range doc.APIs {
+		key := spec.Key()
+		api, exists := existing[key]
+		if !exists {
+			api = models.API{ID: uuid.New(), Method: spec.Method, Path: spec.Path, Description: spec.Description}
+			s.apiIDs[key] = api.ID
+			s.diff.add(ActionCreate, KindAPI, key, "")
+			if s.apply {
+				if err := s.db.Create(&api).Error; err != nil {
+					return fmt.Errorf("failed to create api %q: %v", key, err)
+				}
+			}
+			continue
+		}
+
+		if api.Description != spec.Description {
+			s.diff.add(ActionUpdate, KindAPI, key, fmt.Sprintf("description: %q -> %q", api.Description, spec.Description))
+			if s.apply {
+				if err := s.db.Model(&models.API{}).Where("id = ?", api.ID).Update("description", spec.Description).Error; err != nil {
+					return fmt.Errorf("failed to update api %q: %v", key, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) syncMenus(doc *Document) error {
+	existing := make(map[string]models.Menu)
+	for _, menu := range s.current.menus {
+		existing[menu.Path] = menu
+		s.menuIDs[menu.Path] = menu.ID
+	}
+
+	// Parent diproses lebih dulu agar ID parent baru sudah tersedia
+	for _, spec := range doc.menusParentFirst() {
+		var parentID *uuid.UUID
+		if spec.Parent != "" {
+			id := s.menuIDs[spec.Parent]
+			parentID = &id
+		}
+
+		menu, exists := existing[spec.Path]
+		if !exists {
+			menu = models.Menu{
+				ID:          uuid.New(),
+				Name:        spec.Name,
+				Path:        spec.Path,
+				Icon:        spec.Icon,
+				ParentID:    parentID,
+				Sequence:    spec.Sequence,
+				IsActive:    spec.Active(),
+				Description: spec.Description,
+			}
+			s.menuIDs[spec.Path] = menu.ID
+			s.diff.add(ActionCreate, KindMenu, spec.Path, "")
+			if s.apply {
+				// Select semua field agar is_active=false tidak diganti default
+				if err := s.db.Select("*").Create(&menu).Error; err != nil {
+					return fmt.Errorf("failed to create menu %q: %v", spec.Path, err)
+				}
+			}
+			continue
+		}
+
+		changes := menuChanges(menu, spec, s.current.menuPaths)
+		if len(changes) == 0 {
+			continue
+		}
+
+		s.diff.add(ActionUpdate, KindMenu, spec.Path, strings.Join(changes, "; "))
+		if s.apply {
+			updates := map[string]interface{}{
+				"name":        spec.Name,
+				"icon":        spec.Icon,
+				"parent_id":   parentID,
+				"sequence":    spec.Sequence,
+				"is_active":   spec.Active(),
+				"description": spec.Description,
+			}
+			if err := s.db.Model(&models.Menu{}).Where("id = ?", menu.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update menu %q: %v", spec.Path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func menuChanges(menu models.Menu, spec MenuSpec, menuPaths map[uuid.UUID]string) []string {
+	var changes []string
+	if menu.Name != spec.Name {
+		changes = append(changes, fmt.Sprintf("name: %q -> %q", menu.Name, spec.Name))
+	}
+	if menu.Icon != spec.Icon {
+		changes = append(changes, fmt.Sprintf("icon: %q -> %q", menu.Icon, spec.Icon))
+	}
+	currentParent := ""
+	if menu.ParentID != nil {
+		currentParent = menuPaths[*menu.ParentID]
+	}
+	if currentParent != spec.Parent {
+		changes = append(changes, fmt.Sprintf("parent: %q -> %q", currentParent, spec.Parent))
+	}
+	if menu.Sequence != spec.Sequence {
+		changes = append(changes, fmt.Sprintf("sequence: %d -> %d", menu.Sequence, spec.Sequence))
+	}
+	if menu.IsActive != spec.Active() {
+		changes = append(changes, fmt.Sprintf("is_active: %t -> %t", menu.IsActive, spec.Active()))
+	}
+	if menu.Description != spec.Description {
+		changes = append(changes, fmt.Sprintf("description: %q -> %q", menu.Description, spec.Description))
+	}
+	return changes
+}
+
+// pair is a (resource key, role name) mapping
+type pair struct {
+	key  string
+	role string
+}
+
+func (p pair) String() string {
+	return p.key + " -> " + p.role
+}
+
+func (s *syncer) syncAPIMappings(doc *Document) error {
+	currentGrants := make(map[pair]bool)
+	for _, api := range s.current.apis {
+		for _, role := range api.Roles {
+			currentGrants[pair{apiKey(api.Method, api.Path), role.Name}] = true
+		}
+	}
+	apiKeys := make(map[uuid.UUID]string)
+	for _, api := range s.current.apis {
+		apiKeys[api.ID] = apiKey(api.Method, api.Path)
+	}
+	currentDenies := make(map[pair]bool)
+	for _, deny := range s.current.apiDenies {
+		currentDenies[pair{apiKeys[deny.APIID], s.current.roleNames[deny.RoleID]}] = true
+	}
+
+	desiredGrants := make(map[pair]bool)
+	desiredDenies := make(map[pair]bool)
+	for _, spec := range doc.APIs {
+		for _, role := range spec.Roles {
+			desiredGrants[pair{spec.Key(), role}] = true
+		}
+		for _, role := range spec.DenyRoles {
+			desiredDenies[pair{spec.Key(), role}] = true
+		}
+	}
+
+	err := s.syncPairs(KindAPIGrant, currentGrants, desiredGrants, func(p pair, create bool) error {
+		if create {
+			return s.db.Exec("INSERT INTO map_role_api (role_id, api_id) VALUES (?, ?)", s.roleIDs[p.role], s.apiIDs[p.key]).Error
+		}
+		return s.db.Exec("DELETE FROM map_role_api WHERE role_id = ? AND api_id = ?", s.roleIDs[p.role], s.apiIDs[p.key]).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.syncPairs(KindAPIDeny, currentDenies, desiredDenies, func(p pair, create bool) error {
+		if create {
+			return s.db.Create(&models.RoleAPIDeny{RoleID: s.roleIDs[p.role], APIID: s.apiIDs[p.key]}).Error
+		}
+		return s.db.Delete(&models.RoleAPIDeny{}, "role_id = ? AND api_id = ?", s.roleIDs[p.role], s.apiIDs[p.key]).Error
+	})
+}
+
+func (s *syncer) syncMenuMappings(doc *Document) error {
+	currentGrants := make(map[pair]bool)
+	for _, menu := range s.current.menus {
+		for _, role := range menu.Roles {
+			currentGrants[pair{menu.Path, role.Name}] = true
+		}
+	}
+	currentDenies := make(map[pair]bool)
+	for _, deny := range s.current.menuDenies {
+		currentDenies[pair{s.current.menuPaths[deny.MenuID], s.current.roleNames[deny.RoleID]}] = true
+	}
+
+	desiredGrants := make(map[pair]bool)
+	desiredDenies := make(map[pair]bool)
+	for _, spec := range doc.Menus {
+		for _, role := range spec.Roles {
+			desiredGrants[pair{spec.Path, role}] = true
+		}
+		for _, role := range spec.DenyRoles {
+			desiredDenies[pair{spec.Path, role}] = true
+		}
+	}
+
+	err := s.syncPairs(KindMenuGrant, currentGrants, desiredGrants, func(p pair, create bool) error {
+		if create {
+			return s.db.Exec("INSERT INTO map_role_menu (role_id, menu_id) VALUES (?, ?)", s.roleIDs[p.role], s.menuIDs[p.key]).Error
+		}
+		return s.db.Exec("DELETE FROM map_role_menu WHERE role_id = ? AND menu_id = ?", s.roleIDs[p.role], s.menuIDs[p.key]).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.syncPairs(KindMenuDeny, currentDenies, desiredDenies, func(p pair, create bool) error {
+		if create {
+			return s.db.Create(&models.RoleMenuDeny{RoleID: s.roleIDs[p.role], MenuID: s.menuIDs[p.key]}).Error
+		}
+		return s.db.Delete(&models.RoleMenuDeny{}, "role_id = ? AND menu_id = ?", s.roleIDs[p.role], s.menuIDs[p.key]).Error
+	})
+}
+
+// syncPairs records and applies removed mappings first, then added mappings
+func (s *syncer) syncPairs(kind string, current, desired map[pair]bool, exec func(p pair, create bool) error) error {
+	for _, p := range sortedPairs(current) {
+		if desired[p] {
+			continue
+		}
+		s.diff.add(ActionDelete, kind, p.String(), "")
+		if s.apply {
+			if err := exec(p, false); err != nil {
+				return fmt.Errorf("failed to delete %s %q: %v", kind, p.String(), err)
+			}
+		}
+	}
+
+	for _, p := range sortedPairs(desired) {
+		if current[p] {
+			continue
+		}
+		s.diff.add(ActionCreate, kind, p.String(), "")
+		if s.apply {
+			if err := exec(p, true); err != nil {
+				return fmt.Errorf("failed to create %s %q: %v", kind, p.String(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortedPairs(set map[pair]bool) []pair {
+	pairs := make([]pair, 0, len(set))
+	for p := range set {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].String() < pairs[j].String()
+	})
+	return pairs
+}
+
+func (s *syncer) deleteMenus(doc *Document) error {
+	desired := make(map[string]bool)
+	for _, spec := range doc.Menus {
+		desired[spec.Path] = true
+	}
+
+	// Hapus child lebih dulu
+	depth := func(menu models.Menu) int {
+		d := 0
+		for current := menu.ParentID; current != nil && d < len(s.current.menus); d++ {
+			var parent *uuid.UUID
+			for _, m := range s.current.menus {
+				if m.ID == *current {
+					parent = m.ParentID
+					break
+				}
+			}
+			current = parent
+		}
+		return d
+	}
+	menus := make([]models.Menu, len(s.current.menus))
+	copy(menus, s.current.menus)
+	sort.SliceStable(menus, func(i, j int) bool {
+		return depth(menus[i]) > depth(menus[j])
+	})
+
+	for _, menu := range menus {
+		if desired[menu.Path] {
+			continue
+		}
+		s.diff.add(ActionDelete, KindMenu, menu.Path, "")
+		if s.apply {
+			if err := s.db.Delete(&models.Menu{}, "id = ?", menu.ID).Error; err != nil {
+				return fmt.Errorf("failed to delete menu %q: %v", menu.Path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) deleteAPIs(doc *Document) error {
+	desired := make(map[string]bool)
+	for _, spec := range doc.APIs {
+		desired[spec.Key()] = true
+	}
+
+	for _, api := range s.current.apis {
+		key := apiKey(api.Method, api.Path)
+		if desired[key] {
+			continue
+		}
+		s.diff.add(ActionDelete, KindAPI, key, "")
+		if s.apply {
+			if err := s.db.Delete(&models.API{}, "id = ?", api.ID).Error; err != nil {
+				return fmt.Errorf("failed to delete api %q: %v", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *syncer) deleteRoles(doc *Document) error {
+	desired := make(map[string]bool)
+	for _, spec := range doc.Roles {
+		desired[spec.Name] = true
+	}
+
+	for _, role := range s.current.roles {
+		if desired[role.Name] {
+			continue
+		}
+
+		var userCount, groupCount int64
+		if err := s.db.Model(&models.UserRole{}).Where("role_id = ?", role.ID).Count(&userCount).Error; err != nil {
+			return err
+		}
+		if err := s.db.Model(&models.GroupRole{}).Where("role_id = ?", role.ID).Count(&groupCount).Error; err != nil {
+			return err
+		}
+
+		var removed []string
+		if userCount > 0 {
+			removed = append(removed, fmt.Sprintf("%d user(s)", userCount))
+		}
+		if groupCount > 0 {
+			removed = append(removed, fmt.Sprintf("%d group(s)", groupCount))
+		}
+		details := ""
+		if len(removed) > 0 {
+			details = "removes role from " + strings.Join(removed, " and ")
+		}
+		s.diff.add(ActionDelete, KindRole, role.Name, details)
+		if s.apply {
+			if err := webhook.EnqueueRoleRemoval(s.db, role, "Role removed by RBAC import"); err != nil {
+				return fmt.Errorf("failed to enqueue webhooks for role %q: %v", role.Name, err)
+			}
+			if err := s.db.Delete(&models.UserRole{}, "role_id = ?", role.ID).Error; err != nil {
+				return fmt.Errorf("failed to delete assignments of role %q: %v", role.Name, err)
+			}
+			if err := s.db.Delete(&models.GroupRole{}, "role_id = ?", role.ID).Error; err != nil {
+				return fmt.Errorf("failed to delete group assignments of role %q: %v", role.Name, err)
+			}
+			if err := s.db.Delete(&models.Role{}, "id = ?", role.ID).Error; err != nil {
+				return fmt.Errorf("failed to delete role %q: %v", role.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,10 +2,17 @@ package routes
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"sjek/internal/accesslog"
+	"sjek/internal/events"
 	"sjek/internal/handlers"
+	"sjek/internal/hashchain"
 	"sjek/internal/middleware"
 	"sjek/internal/models"
+	"sjek/internal/notification"
+	"sjek/internal/policy"
+	"sjek/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -13,53 +20,119 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
-	router := gin.Default()
+func SetupRouter(db *gorm.DB, notifier notification.Notifier, store storage.Storage, signer storage.URLSigner, chainSigner hashchain.Signer, accessLogs *accesslog.Writer, eventBus *events.Bus) *gin.Engine {
+	// Output debug gin (daftar route) ikut lewat slog
+	gin.DefaultWriter = slog.NewLogLogger(slog.Default().Handler(), slog.LevelDebug).Writer()
+	gin.DefaultErrorWriter = slog.NewLogLogger(slog.Default().Handler(), slog.LevelError).Writer()
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Recovery())
+	if accessLogs != nil {
+		router.Use(middleware.AccessLog(accessLogs))
+	}
+
+	// Middleware tiap route dicatat untuk /access/explain
+	access := middleware.NewRouteAccess()
 
 	// Swagger route
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Public routes
-	setupPublicRoutes(router)
+	setupPublicRoutes(router, notifier)
+	access.Mark(router.Routes(), middleware.RoutePublic)
+
+	// Self-service routes, cukup login tanpa grant per route
+	self := router.Group("/me")
+	self.Use(middleware.AuthMiddleware())
+	setupMeRoutes(self, store, signer)
+	access.Mark(router.Routes(), middleware.RouteAuthenticated)
 
 	// Protected routes
 	protected := router.Group("/")
 	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.DriverVerificationMiddleware(db))
 	protected.Use(middleware.APIAccessMiddleware(db))
 
-	setupUserRoutes(protected)
+	setupUserRoutes(protected, db, access, notifier)
 	setupRoleRoutes(protected)
 	setupRoleUserMappingRoutes(protected)
+	setupGroupRoutes(protected)
 	setupAPIRoutes(protected, db)
 	setupAPIRoleMappingRoutes(protected, db)
 	setupLoginLogRoutes(protected)
-	setupTokenRoutes(protected)
+	setupAuditLogRoutes(protected)
+	setupIntegrityRoutes(protected, db, chainSigner)
+	setupAccessLogRoutes(protected, accessLogs)
+	setupSecurityEventRoutes(protected, eventBus)
+	setupWebhookRoutes(protected)
+	setupTokenRoutes(protected, db, access)
 	setupMenuRoutes(protected) // Tambahkan ini
+	setupRBACRoutes(protected, db)
+	setupAccessRoutes(protected, db, router, access)
+	setupDocumentRoutes(protected, router, db, access, store, signer)
+	access.Mark(router.Routes(), middleware.RouteProtected)
 
 	// Insert API endpoints ke database
 	if err := insertAPIEndpoints(db, router); err != nil {
-		log.Printf("Warning: gagal insert API endpoints: %v", err)
+		slog.Warn("Gagal insert API endpoints", "error", err)
 	}
 
 	return router
 }
 
 // setupPublicRoutes configures public routes that don't require authentication
-func setupPublicRoutes(router *gin.Engine) {
+func setupPublicRoutes(router *gin.Engine, notifier notification.Notifier) {
 	router.POST("/register/admin", handlers.RegisterAdmin)
 	router.POST("/register/driver", handlers.RegisterDriver)
 	router.POST("/login", handlers.Login)
+	router.POST("/password/forgot", handlers.ForgotPassword(notifier))
+	router.POST("/password/reset", handlers.ResetPassword)
+}
+
+// setupMeRoutes configures current-user routes
+func setupMeRoutes(rg *gin.RouterGroup, store storage.Storage, signer storage.URLSigner) {
+	rg.GET("", handlers.GetMe)
+	rg.PUT("", handlers.UpdateMe)
+	rg.POST("/password", handlers.ChangeMyPassword)
+	rg.GET("/permissions", handlers.GetMyPermissions)
+	rg.GET("/driver-profile", handlers.GetMyDriverProfile)
+	rg.PUT("/driver-profile", handlers.SaveMyDriverProfile)
+	rg.GET("/documents", handlers.GetMyDocuments(signer))
+	rg.POST("/documents", handlers.UploadMyDocument(store, signer))
 }
 
 // setupUserRoutes configures user management routes
-func setupUserRoutes(rg *gin.RouterGroup) {
+func setupUserRoutes(rg *gin.RouterGroup, db *gorm.DB, access *middleware.RouteAccess, notifier notification.Notifier) {
+	ownUser := middleware.RoutePolicy{Loader: middleware.UserResource(db), Policies: []policy.Policy{policy.DriverOwnUser}}
+
 	users := rg.Group("/users")
 	{
 		users.GET("/", handlers.GetUsers)
-		users.GET("/:id", handlers.GetUser)
-		users.PUT("/:id", handlers.UpdateUser)
+		users.GET("/export", handlers.ExportUsers)
+		users.POST("/import", handlers.ImportUsers(db, notifier))
+		access.Handle(users, http.MethodGet, "/:id", db, ownUser, handlers.GetUser)
+		access.Handle(users, http.MethodPut, "/:id", db, ownUser, handlers.UpdateUser)
+		access.Handle(users, http.MethodPatch, "/:id", db, ownUser, handlers.PatchUser)
 		users.DELETE("/:id", handlers.DeleteUser)
+
+		// User status lifecycle
+		users.POST("/:id/activate", handlers.ActivateUser)
+		users.POST("/:id/deactivate", handlers.DeactivateUser)
+		users.POST("/:id/suspend", handlers.SuspendUser)
+		users.POST("/:id/reactivate", handlers.ReactivateUser)
+		users.GET("/:id/status-history", handlers.GetUserStatusHistory)
+		users.POST("/:id/restore", handlers.RestoreUser)
+
+		// Driver profile dan verifikasi
+		access.Handle(users, http.MethodGet, "/:id/driver-profile", db, ownUser, handlers.GetDriverProfile)
+		access.Handle(users, http.MethodPost, "/:id/driver-profile", db, ownUser, handlers.CreateDriverProfile)
+		access.Handle(users, http.MethodPut, "/:id/driver-profile", db, ownUser, handlers.UpdateDriverProfile)
+		users.DELETE("/:id/driver-profile", handlers.DeleteDriverProfile)
+		users.POST("/:id/driver-profile/verify", handlers.VerifyDriverProfile)
+		users.POST("/:id/driver-profile/reject", handlers.RejectDriverProfile)
 	}
+
+	rg.GET("/driver-profiles", handlers.GetDriverProfiles)
 }
 
 // setupRoleRoutes configures role management routes
@@ -70,6 +143,7 @@ func setupRoleRoutes(rg *gin.RouterGroup) {
 		roles.GET("/", handlers.GetRoles)
 		roles.GET("/:id", handlers.GetRole)
 		roles.PUT("/:id", handlers.UpdateRole)
+		roles.PATCH("/:id", handlers.PatchRole)
 		roles.DELETE("/:id", handlers.DeleteRole)
 	}
 }
@@ -80,6 +154,26 @@ func setupRoleUserMappingRoutes(rg *gin.RouterGroup) {
 	{
 		mappings.POST("/roles/:role_id/users/:user_id", handlers.AssignRoleToUser)
 		mappings.DELETE("/roles/:role_id/users/:user_id", handlers.RemoveRoleFromUser)
+		mappings.GET("/users/:user_id", handlers.GetUserRoleAssignments)
+	}
+}
+
+// setupGroupRoutes configures group (team) management routes
+func setupGroupRoutes(rg *gin.RouterGroup) {
+	groups := rg.Group("/groups")
+	{
+		groups.POST("/", handlers.CreateGroup)
+		groups.GET("/", handlers.GetGroups)
+		groups.GET("/:id", handlers.GetGroup)
+		groups.PUT("/:id", handlers.UpdateGroup)
+		groups.DELETE("/:id", handlers.DeleteGroup)
+
+		// Anggota dan role group
+		groups.GET("/:id/members", handlers.GetGroupMembers)
+		groups.POST("/:id/members", handlers.AddGroupMembers)
+		groups.DELETE("/:id/members/:user_id", handlers.RemoveGroupMember)
+		groups.POST("/:id/roles/:role_id", handlers.AssignRoleToGroup)
+		groups.DELETE("/:id/roles/:role_id", handlers.RemoveRoleFromGroup)
 	}
 }
 
@@ -91,6 +185,7 @@ func setupAPIRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 		apis.GET("/", handlers.GetAPIs(db))
 		apis.GET("/:id", handlers.GetAPI(db))
 		apis.PUT("/:id", handlers.UpdateAPI(db))
+		apis.PATCH("/:id", handlers.PatchAPI(db))
 		apis.DELETE("/:id", handlers.DeleteAPI(db))
 	}
 }
@@ -101,6 +196,9 @@ func setupAPIRoleMappingRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	{
 		mappings.POST("/apis/:api_id/roles/:role_id", handlers.AssignAPIToRole(db))
 		mappings.DELETE("/apis/:api_id/roles/:role_id", handlers.RemoveAPIFromRole(db))
+		mappings.GET("/denies", handlers.GetAPIDenyRules(db))
+		mappings.POST("/apis/:api_id/roles/:role_id/deny", handlers.DenyAPIForRole(db))
+		mappings.DELETE("/apis/:api_id/roles/:role_id/deny", handlers.RemoveAPIDenyFromRole(db))
 	}
 }
 
@@ -123,7 +221,7 @@ func insertAPIEndpoints(db *gorm.DB, router *gin.Engine) error {
 			if err := db.Create(&api).Error; err != nil {
 				return fmt.Errorf("gagal membuat API %s %s: %v", api.Method, api.Path, err)
 			}
-			log.Printf("API berhasil dibuat: %s %s", api.Method, api.Path)
+			slog.Info("API berhasil dibuat", "method", api.Method, "path", api.Path)
 		}
 	}
 
@@ -135,20 +233,84 @@ func setupLoginLogRoutes(rg *gin.RouterGroup) {
 	loginLogs := rg.Group("/login-logs")
 	{
 		loginLogs.GET("/", handlers.GetLoginLogs)
+		loginLogs.GET("/export", handlers.ExportLoginLogs)
+		loginLogs.GET("/analytics/trends", handlers.GetLoginTrends)
+		loginLogs.GET("/analytics/top-ips", handlers.GetLoginTopIPs)
+		loginLogs.GET("/analytics/top-usernames", handlers.GetLoginTopUsernames)
+		loginLogs.GET("/analytics/failures", handlers.GetLoginFailures)
 		loginLogs.GET("/:id", handlers.GetLoginLog)
 	}
 }
 
+// setupAuditLogRoutes configures audit trail routes
+func setupAuditLogRoutes(rg *gin.RouterGroup) {
+	auditLogs := rg.Group("/audit-logs")
+	{
+		auditLogs.GET("/", handlers.GetAuditLogs)
+		auditLogs.GET("/export", handlers.ExportAuditLogs)
+		auditLogs.GET("/:id", handlers.GetAuditLog)
+	}
+}
+
+// setupIntegrityRoutes configures hash chain verification routes for audit and login logs
+func setupIntegrityRoutes(rg *gin.RouterGroup, db *gorm.DB, signer hashchain.Signer) {
+	integrity := rg.Group("/integrity")
+	{
+		integrity.GET("/verify", handlers.VerifyLogIntegrity(db, signer))
+		integrity.GET("/checkpoints", handlers.GetChainCheckpoints(db, signer))
+		integrity.POST("/checkpoints", handlers.CreateChainCheckpoints(db, signer))
+	}
+}
+
+// setupAccessLogRoutes configures HTTP access log query and aggregation routes
+func setupAccessLogRoutes(rg *gin.RouterGroup, writer *accesslog.Writer) {
+	accessLogs := rg.Group("/access-logs")
+	{
+		accessLogs.GET("/", handlers.GetAccessLogs)
+		accessLogs.GET("/stats", handlers.GetAccessLogStats(writer))
+		accessLogs.GET("/timeseries", handlers.GetAccessLogTimeseries)
+	}
+}
+
+// setupSecurityEventRoutes configures the real-time security event stream
+func setupSecurityEventRoutes(rg *gin.RouterGroup, bus *events.Bus) {
+	securityEvents := rg.Group("/security-events")
+	{
+		securityEvents.GET("/stream", handlers.StreamSecurityEvents(bus))
+	}
+}
+
+// setupWebhookRoutes configures webhook subscription and delivery log routes
+func setupWebhookRoutes(rg *gin.RouterGroup) {
+	webhooks := rg.Group("/webhooks")
+	{
+		webhooks.GET("/event-types", handlers.GetWebhookEventTypes)
+		webhooks.GET("/dead-letters", handlers.GetWebhookDeadLetters)
+		webhooks.GET("/deliveries/:id", handlers.GetWebhookDelivery)
+		webhooks.POST("/deliveries/:id/retry", handlers.RetryWebhookDelivery)
+
+		webhooks.POST("/", handlers.CreateWebhook)
+		webhooks.GET("/", handlers.GetWebhooks)
+		webhooks.GET("/:id", handlers.GetWebhook)
+		webhooks.PUT("/:id", handlers.UpdateWebhook)
+		webhooks.DELETE("/:id", handlers.DeleteWebhook)
+		webhooks.POST("/:id/rotate-secret", handlers.RotateWebhookSecret)
+		webhooks.GET("/:id/deliveries", handlers.GetWebhookDeliveries)
+	}
+}
+
 // setupTokenRoutes configures token management routes
-func setupTokenRoutes(rg *gin.RouterGroup) {
+func setupTokenRoutes(rg *gin.RouterGroup, db *gorm.DB, access *middleware.RouteAccess) {
 	// Logout route
 	rg.POST("/logout", handlers.Logout)
 	
 	// Token management routes
+	ownToken := middleware.RoutePolicy{Loader: middleware.TokenResource(db), Policies: []policy.Policy{policy.OwnToken}}
 	tokens := rg.Group("/tokens")
 	{
 		tokens.GET("/", handlers.GetUserTokens)
-		tokens.DELETE("/:id", handlers.RevokeToken)
+		tokens.GET("/export", handlers.ExportTokens)
+		access.Handle(tokens, http.MethodDelete, "/:id", db, ownToken, handlers.RevokeToken)
 		tokens.POST("/revoke-all", handlers.RevokeAllTokens)
 	}
 }
@@ -163,6 +325,7 @@ func setupMenuRoutes(rg *gin.RouterGroup) {
 		menus.GET("/user", handlers.GetUserMenus) // Get menus for current user
 		menus.GET("/:id", handlers.GetMenu)
 		menus.PUT("/:id", handlers.UpdateMenu)
+		menus.PATCH("/:id", handlers.PatchMenu)
 		menus.DELETE("/:id", handlers.DeleteMenu)
 	}
 
@@ -171,5 +334,48 @@ func setupMenuRoutes(rg *gin.RouterGroup) {
 	{
 		menuAssignments.POST("/menus/:menu_id/roles/:role_id", handlers.AssignRoleToMenu)
 		menuAssignments.DELETE("/menus/:menu_id/roles/:role_id", handlers.RemoveRoleFromMenu)
+		menuAssignments.GET("/denies", handlers.GetMenuDenyRules)
+		menuAssignments.POST("/menus/:menu_id/roles/:role_id/deny", handlers.DenyMenuForRole)
+		menuAssignments.DELETE("/menus/:menu_id/roles/:role_id/deny", handlers.RemoveMenuDenyFromRole)
 	}
 }
+
+// setupRBACRoutes configures RBAC configuration import/export routes
+func setupRBACRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+	rbacConfig := rg.Group("/rbac")
+	{
+		rbacConfig.GET("/export", handlers.ExportRBAC(db))
+		rbacConfig.POST("/import", handlers.ImportRBAC(db))
+	}
+}
+
+// setupAccessRoutes configures access troubleshooting routes
+func setupAccessRoutes(rg *gin.RouterGroup, db *gorm.DB, router *gin.Engine, routeAccess *middleware.RouteAccess) {
+	access := rg.Group("/access")
+	{
+		access.GET("/explain", handlers.ExplainAccess(db, router, routeAccess))
+	}
+}
+
+// setupDocumentRoutes configures KYC document routes.
+// Download memakai signed URL sehingga didaftarkan di router tanpa AuthMiddleware
+func setupDocumentRoutes(rg *gin.RouterGroup, router *gin.Engine, db *gorm.DB, access *middleware.RouteAccess, store storage.Storage, signer storage.URLSigner) {
+	ownUser := middleware.RoutePolicy{Loader: middleware.UserResource(db), Policies: []policy.Policy{policy.DriverOwnUser}}
+	ownDocument := middleware.RoutePolicy{Loader: middleware.DocumentResource(db), Policies: []policy.Policy{policy.DriverOwnDocument}}
+
+	users := rg.Group("/users")
+	{
+		access.Handle(users, http.MethodGet, "/:id/documents", db, ownUser, handlers.GetUserDocuments(signer))
+		access.Handle(users, http.MethodPost, "/:id/documents", db, ownUser, handlers.UploadDocument(store, signer))
+	}
+
+	documents := rg.Group("/documents")
+	{
+		access.Handle(documents, http.MethodGet, "/:id", db, ownDocument, handlers.GetDocument(signer))
+		access.Handle(documents, http.MethodDelete, "/:id", db, ownDocument, handlers.DeleteDocument(store))
+		documents.POST("/:id/review", handlers.ReviewDocument)
+	}
+
+	router.GET("/documents/:id/download", handlers.DownloadDocument(store, signer))
+	access.Set(http.MethodGet, "/documents/:id/download", middleware.RoutePublic)
+}
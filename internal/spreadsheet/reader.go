@@ -0,0 +1,101 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// FormatFromFilename detects the spreadsheet format from file extension
+func FormatFromFilename(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %q, use .csv or .xlsx", filepath.Ext(filename))
+	}
+}
+
+// ReadRows reads all rows of a CSV file or the first sheet of an XLSX file
+func ReadRows(format string, r io.Reader) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		return reader.ReadAll()
+	case FormatXLSX:
+		file, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("workbook has no sheets")
+		}
+		return file.GetRows(sheets[0])
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Table is a set of rows addressed by header name
+type Table struct {
+	columns map[string]int
+	Rows    [][]string
+}
+
+// NewTable uses the first row as header, header names are case insensitive
+func NewTable(rows [][]string) (*Table, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	table := &Table{columns: make(map[string]int), Rows: rows[1:]}
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			table.columns[name] = i
+		}
+	}
+
+	return table, nil
+}
+
+// HasColumn returns true if the header contains the column
+func (t *Table) HasColumn(name string) bool {
+	_, ok := t.columns[name]
+	return ok
+}
+
+// Value returns trimmed cell value of a row, empty if the column or cell is missing
+func (t *Table) Value(row []string, name string) string {
+	index, ok := t.columns[name]
+	if !ok || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
+
+// IsEmptyRow returns true if every cell of the row is blank
+func IsEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
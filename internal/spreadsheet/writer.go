@@ -0,0 +1,139 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// csvFlushEvery controls how often buffered CSV rows are flushed to the client
+const csvFlushEvery = 500
+
+// escapeFormula prefixes values that a spreadsheet application would evaluate as a formula
+// with a single quote, supaya isian user seperti "=HYPERLINK(...)" tampil sebagai teks biasa
+func escapeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
+
+// Writer writes rows one by one without keeping the whole result in memory
+type Writer interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NewWriter creates a streaming writer for the format
+func NewWriter(format string, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		file := excelize.NewFile()
+		if err := file.SetSheetName("Sheet1", sheet); err != nil {
+			return nil, err
+		}
+		stream, err := file.NewStreamWriter(sheet)
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxWriter{file: file, stream: stream, out: w, row: 1}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	count  int
+}
+
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		case *time.Time:
+			if v != nil {
+				record[i] = v.Format(time.RFC3339)
+			}
+		case string:
+			record[i] = escapeFormula(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	if err := w.writer.Write(record); err != nil {
+		return err
+	}
+
+	w.count++
+	if w.count%csvFlushEvery == 0 {
+		w.writer.Flush()
+		return w.writer.Error()
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// xlsxWriter memakai StreamWriter excelize yang menyimpan row ke temporary file
+type xlsxWriter struct {
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	out    io.Writer
+	row    int
+}
+
+func (w *xlsxWriter) WriteRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	w.row++
+
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case *time.Time:
+			// excelize tidak mengenali pointer time, ubah ke value atau cell kosong
+			if v != nil {
+				row[i] = *v
+			}
+		case string:
+			row[i] = escapeFormula(v)
+		default:
+			row[i] = value
+		}
+	}
+	return w.stream.SetRow(cell, row)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.Write(w.out)
+}
@@ -0,0 +1,85 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"driver01", "driver01"},
+		{"budi@example.com", "budi@example.com"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+62812", "'+62812"},
+		{"-1+1", "'-1+1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeFormula(tt.value); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVWriterEscapesStrings(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &buf, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRow([]interface{}{"=cmd|' /C calc'!A0", -5, "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"'=cmd|' /C calc'!A0", "-5", "ok"}
+	for i, value := range want {
+		if records[0][i] != value {
+			t.Errorf("column %d = %q, want %q", i, records[0][i], value)
+		}
+	}
+}
+
+func TestXLSXWriterEscapesStrings(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatXLSX, &buf, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRow([]interface{}{"@SUM(A1)", "driver01"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0][0] != "'@SUM(A1)" || rows[0][1] != "driver01" {
+		t.Errorf("row = %q, want escaped formula", rows[0])
+	}
+}
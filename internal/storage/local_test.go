@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(filepath.Join(t.TempDir(), "documents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "users/42/ktp.jpg"
+	if err := store.Put(ctx, key, strings.NewReader("first"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := readObject(t, store, key); got != "first" {
+		t.Fatalf("Get = %q, want %q", got, "first")
+	}
+
+	// Put dengan key yang sama menimpa isi lama
+	if err := store.Put(ctx, key, strings.NewReader("second"), 6, "image/jpeg"); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+	if got := readObject(t, store, key); got != "second" {
+		t.Fatalf("Get after overwrite = %q, want %q", got, "second")
+	}
+
+	// Tidak ada file sementara yang tertinggal
+	entries, err := os.ReadDir(filepath.Join(store.Root, "users", "42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want 1", len(entries))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); err != ErrNotFound {
+		t.Fatalf("Get after delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete missing object: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "/", "../secret", "users/../../secret"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil || err == ErrNotFound {
+			t.Errorf("Get(%q) error = %v, want invalid key", key, err)
+		}
+	}
+}
+
+func readObject(t *testing.T, store Storage, key string) string {
+	t.Helper()
+	reader, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// URLSigner membuat dan memverifikasi signature untuk download URL yang bisa kadaluarsa
+type URLSigner struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+func (s URLSigner) signature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	fmt.Fprintf(mac, "%s:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns expiry unix timestamp and signature for the given object id
+func (s URLSigner) Sign(id string) (int64, string) {
+	expires := time.Now().Add(s.TTL).Unix()
+	return expires, s.signature(id, expires)
+}
+
+// Verify checks the signature and that the URL has not expired.
+// Signer tanpa secret tidak pernah menerima URL
+func (s URLSigner) Verify(id, expires, signature string) bool {
+	if len(s.Secret) == 0 {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signature(id, expiresAt)))
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestURLSignerRoundTrip(t *testing.T) {
+	signer := URLSigner{Secret: []byte("document-secret"), TTL: time.Minute}
+
+	expires, signature := signer.Sign("doc-1")
+	if !signer.Verify("doc-1", strconv.FormatInt(expires, 10), signature) {
+		t.Fatal("valid signature rejected")
+	}
+}
+
+func TestURLSignerExpired(t *testing.T) {
+	signer := URLSigner{Secret: []byte("document-secret"), TTL: -time.Minute}
+
+	expires, signature := signer.Sign("doc-1")
+	if signer.Verify("doc-1", strconv.FormatInt(expires, 10), signature) {
+		t.Fatal("expired signature accepted")
+	}
+}
+
+func TestURLSignerTampered(t *testing.T) {
+	signer := URLSigner{Secret: []byte("document-secret"), TTL: time.Minute}
+	expires, signature := signer.Sign("doc-1")
+	expiresParam := strconv.FormatInt(expires, 10)
+
+	// Ganti karakter pertama dengan hex digit lain
+	modified := "0" + signature[1:]
+	if signature[0] == '0' {
+		modified = "1" + signature[1:]
+	}
+
+	tests := []struct {
+		name      string
+		signer    URLSigner
+		id        string
+		expires   string
+		signature string
+	}{
+		{"other document", signer, "doc-2", expiresParam, signature},
+		{"extended expiry", signer, "doc-1", strconv.FormatInt(expires+3600, 10), signature},
+		{"invalid expiry", signer, "doc-1", "tomorrow", signature},
+		{"modified signature", signer, "doc-1", expiresParam, modified},
+		{"empty signature", signer, "doc-1", expiresParam, ""},
+		{"other secret", URLSigner{Secret: []byte("other-secret")}, "doc-1", expiresParam, signature},
+		{"empty secret", URLSigner{}, "doc-1", expiresParam, URLSigner{}.signature("doc-1", expires)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.signer.Verify(tt.id, tt.expires, tt.signature) {
+				t.Error("tampered URL accepted")
+			}
+		})
+	}
+}
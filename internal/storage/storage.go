@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned when an object does not exist in the storage
+var ErrNotFound = errors.New("object not found")
+
+// Storage menyimpan file berdasarkan key, implementasi: LocalStorage dan S3Storage
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv returns S3Storage when STORAGE_DRIVER=s3, otherwise LocalStorage
+func FromEnv() (Storage, error) {
+	if strings.ToLower(os.Getenv("STORAGE_DRIVER")) == "s3" {
+		return NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    os.Getenv("S3_USE_SSL") == "true",
+		})
+	}
+
+	root := os.Getenv("STORAGE_LOCAL_PATH")
+	if root == "" {
+		root = "storage"
+	}
+	return NewLocalStorage(root)
+}
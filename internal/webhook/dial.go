@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned when a webhook target is or resolves to an address that is
+// not reachable from the public internet, misalnya loopback, jaringan privat atau link-local
+var ErrNonPublicAddress = errors.New("webhook target is not a public address")
+
+// nonPublicPrefixes are ranges that netip does not classify as private but are still internal
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, termasuk broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, bisa menunjuk ke alamat IPv4 internal
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+}
+
+// PublicAddr reports whether ip may be used as a webhook target
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckTarget rejects URLs whose host is localhost or a non-public IP literal. Hostname lain
+// baru bisa diperiksa saat dial, lihat dialControl
+func CheckTarget(target *url.URL) error {
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrNonPublicAddress
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !PublicAddr(ip) {
+		return ErrNonPublicAddress
+	}
+	return nil
+}
+
+// dialControl runs after DNS resolution for every connection the dispatcher opens, sehingga
+// hostname yang resolve (atau di-rebind) ke alamat internal tetap ditolak
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, ip)
+	}
+	return nil
+}
+
+// newTransport creates the transport used for deliveries. Proxy dari environment tidak dipakai
+// karena koneksi ke proxy akan ikut diperiksa dialControl
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
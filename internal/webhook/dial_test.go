@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::1", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := PublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("PublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/events", true},
+		{"https://93.184.216.34/events", true},
+		{"http://localhost:8080/", false},
+		{"http://LOCALHOST./", false},
+		{"http://api.localhost/", false},
+		{"http://127.0.0.1/", false},
+		{"http://[::1]:9000/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := CheckTarget(target); (err == nil) != tt.allowed {
+				t.Errorf("CheckTarget(%s) = %v, allowed want %v", tt.url, err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestTransportRejectsLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached loopback server")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport()}
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("Get error = %v, want ErrNonPublicAddress", err)
+	}
+}
@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sjek/internal/env"
+	"sjek/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxResponseBody is how much of the subscriber response is kept in the attempt log
+const maxResponseBody = 1024
+
+// Config mengatur pengiriman webhook
+type Config struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	RetryBase    time.Duration // jeda sebelum retry pertama, lalu berlipat dua
+	RetryMax     time.Duration
+	BatchSize    int
+	Workers      int
+}
+
+// ConfigFromEnv reads WEBHOOK_POLL_INTERVAL, WEBHOOK_TIMEOUT, WEBHOOK_MAX_ATTEMPTS,
+// WEBHOOK_RETRY_BASE, WEBHOOK_RETRY_MAX and WEBHOOK_WORKERS
+func ConfigFromEnv() Config {
+	return Config{
+		PollInterval: env.Duration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		Timeout:      env.Duration("WEBHOOK_TIMEOUT", 10*time.Second),
+		MaxAttempts:  env.Int("WEBHOOK_MAX_ATTEMPTS", 8),
+		RetryBase:    env.Duration("WEBHOOK_RETRY_BASE", 30*time.Second),
+		RetryMax:     env.Duration("WEBHOOK_RETRY_MAX", 6*time.Hour),
+		BatchSize:    100,
+		Workers:      env.Int("WEBHOOK_WORKERS", 4),
+	}
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts:
+// RetryBase * 2^(attempts-1), maksimal RetryMax, dengan jitter ±10%
+func (c Config) Backoff(attempts int) time.Duration {
+	delay := c.RetryBase
+	for i := 1; i < attempts && delay < c.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > c.RetryMax {
+		delay = c.RetryMax
+	}
+	jitter := time.Duration((rand.Float64()*0.2 - 0.1) * float64(delay))
+	return delay + jitter
+}
+
+// Dispatcher fans outbox events out to subscriptions and delivers them. Beberapa replica
+// boleh berjalan bersamaan; row diklaim dengan FOR UPDATE SKIP LOCKED
+type Dispatcher struct {
+	db     *gorm.DB
+	config Config
+	client *http.Client
+}
+
+// NewDispatcher creates a dispatcher for db
+func NewDispatcher(db *gorm.DB, config Config) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: newTransport(),
+			// Redirect tidak diikuti, endpoint harus menjawab langsung
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Start runs the dispatcher loop in the background
+func (d *Dispatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(d.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.FanOut(); err != nil {
+				slog.Error("Failed to dispatch webhook events", "error", err)
+			}
+			if err := d.DeliverDue(); err != nil {
+				slog.Error("Failed to deliver webhooks", "error", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// FanOut creates a delivery for every active subscription matching each undispatched event
+func (d *Dispatcher) FanOut() error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var pending []models.WebhookEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL").Order("created_at").Limit(d.config.BatchSize).Find(&pending).Error
+		if err != nil || len(pending) == 0 {
+			return err
+		}
+
+		var subscriptions []models.WebhookSubscription
+		if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var deliveries []models.WebhookDelivery
+		ids := make([]interface{}, 0, len(pending))
+		for _, event := range pending {
+			ids = append(ids, event.ID)
+			for _, subscription := range subscriptions {
+				if !subscription.Matches(event.Type) {
+					continue
+				}
+				deliveries = append(deliveries, models.WebhookDelivery{
+					EventID:        event.ID,
+					SubscriptionID: subscription.ID,
+					EventType:      event.Type,
+					Status:         models.WebhookDeliveryPending,
+					NextAttemptAt:  &now,
+				})
+			}
+		}
+
+		if len(deliveries) > 0 {
+			if err := tx.CreateInBatches(deliveries, d.config.BatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.WebhookEvent{}).Where("id IN ?", ids).Update("dispatched_at", now).Error
+	})
+}
+
+// DeliverDue sends the deliveries whose next attempt is due
+func (d *Dispatcher) DeliverDue() error {
+	// Klaim delivery dengan memundurkan next_attempt_at (lease) supaya replica lain tidak
+	// mengirim delivery yang sama selama request berjalan
+	var due []models.WebhookDelivery
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_attempt_at").Limit(d.config.BatchSize).Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]interface{}, len(due))
+		for i, delivery := range due {
+			ids[i] = delivery.ID
+		}
+		lease := time.Now().Add(2 * d.config.Timeout)
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", lease).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(d.config.Workers, 1))
+	for _, delivery := range due {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(delivery models.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := d.deliver(delivery); err != nil {
+				slog.Error("Failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliver makes one attempt and records the outcome
+func (d *Dispatcher) deliver(delivery models.WebhookDelivery) error {
+	attempt := models.WebhookAttempt{DeliveryID: delivery.ID, Attempt: delivery.Attempts + 1}
+	start := time.Now()
+
+	var subscription models.WebhookSubscription
+	var event models.WebhookEvent
+	err := d.db.First(&subscription, "id = ?", delivery.SubscriptionID).Error
+	if err == nil && !subscription.IsActive {
+		err = fmt.Errorf("subscription is inactive")
+	}
+	if err == nil {
+		err = d.db.First(&event, "id = ?", delivery.EventID).Error
+	}
+
+	succeeded := false
+	dead := false
+	if err != nil {
+		// Subscription dihapus atau dinonaktifkan: langsung ke dead-letter, bisa di-retry manual
+		attempt.Error = err.Error()
+		dead = true
+	} else {
+		statusCode, body, err := d.send(subscription, delivery, event)
+		if statusCode != 0 {
+			attempt.StatusCode = &statusCode
+		}
+		attempt.ResponseBody = body
+		switch {
+		case err != nil:
+			attempt.Error = err.Error()
+		case statusCode < 200 || statusCode >= 300:
+			attempt.Error = fmt.Sprintf("unexpected status %d", statusCode)
+		default:
+			succeeded = true
+		}
+	}
+	attempt.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	updates := map[string]interface{}{
+		"attempts":         attempt.Attempt,
+		"last_status_code": attempt.StatusCode,
+		"last_error":       attempt.Error,
+	}
+	now := time.Now()
+	switch {
+	case succeeded:
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = now
+		updates["next_attempt_at"] = nil
+	case dead || attempt.Attempt >= d.config.MaxAttempts:
+		updates["status"] = models.WebhookDeliveryDead
+		updates["next_attempt_at"] = nil
+		slog.Warn("Webhook delivery moved to dead-letter", "delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "error", attempt.Error)
+	default:
+		updates["next_attempt_at"] = now.Add(d.config.Backoff(attempt.Attempt))
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+	})
+}
+
+// send posts the signed event and returns the status code and the start of the response body
+func (d *Dispatcher) send(subscription models.WebhookSubscription, delivery models.WebhookDelivery, event models.WebhookEvent) (int, string, error) {
+	body := []byte(event.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sjek-webhook/1.0")
+	req.Header.Set(HeaderEventID, event.ID.String())
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	// Response disimpan di kolom text, jadi harus UTF-8 valid tanpa byte NUL
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(response), ""), "\x00", "")
+	return resp.StatusCode, text, nil
+}
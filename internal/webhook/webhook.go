@@ -0,0 +1,256 @@
+// Package webhook delivers user, role and session events to subscribed HTTP endpoints.
+//
+// Enqueue menulis event ke outbox (webhook_events) di transaksi yang sama dengan perubahan
+// datanya, sehingga event tidak hilang walaupun proses mati sebelum dikirim. Dispatcher
+// menyebar event ke subscription yang cocok lalu mengirimnya dengan retry dan back-off.
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"sjek/internal/database"
+	"sjek/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	UserCreated     = "user.created"
+	UserUpdated     = "user.updated"
+	UserActivated   = "user.activated"
+	UserDeactivated = "user.deactivated"
+	UserSuspended   = "user.suspended"
+	UserDeleted     = "user.deleted"
+	UserRestored    = "user.restored"
+	RoleAssigned    = "role.assigned"
+	RoleUnassigned  = "role.unassigned"
+	SessionStarted  = "session.started"
+	SessionRevoked  = "session.revoked"
+)
+
+// EventTypes lists every event type a subscription can filter on
+var EventTypes = []string{
+	UserCreated, UserUpdated, UserActivated, UserDeactivated, UserSuspended, UserDeleted, UserRestored,
+	RoleAssigned, RoleUnassigned, SessionStarted, SessionRevoked,
+}
+
+// ValidEventType reports whether t is a known event type
+func ValidEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusEventType returns the event type for a user status change
+func StatusEventType(status models.UserStatus) string {
+	switch status {
+	case models.UserStatusActive:
+		return UserActivated
+	case models.UserStatusSuspended:
+		return UserSuspended
+	default:
+		return UserDeactivated
+	}
+}
+
+// Envelope is the JSON body sent to subscribers
+type Envelope struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data" swaggertype:"object"`
+}
+
+// User is the user snapshot in webhook payloads, tanpa password dan data sensitif lain
+type User struct {
+	ID       uuid.UUID         `json:"id"`
+	Username string            `json:"username"`
+	Email    string            `json:"email"`
+	Type     models.UserType   `json:"type"`
+	Status   models.UserStatus `json:"status"`
+}
+
+// Role identifies a role in webhook payloads
+type Role struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// StatusChange is the payload of user.activated, user.deactivated and user.suspended
+type StatusChange struct {
+	User
+	FromStatus models.UserStatus `json:"from_status"`
+	Reason     string            `json:"reason,omitempty"`
+	Until      *time.Time        `json:"until,omitempty"`
+}
+
+// RoleChange is the payload of role.assigned and role.unassigned
+type RoleChange struct {
+	User       User       `json:"user"`
+	Role       Role       `json:"role"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// Session describes a login session (token) in webhook payloads
+type Session struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Username  string     `json:"username,omitempty"`
+	TokenID   *uuid.UUID `json:"token_id,omitempty"`
+	IPAddress string     `json:"ip_address,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	Reason    string     `json:"reason,omitempty"` // session.revoked: logout, revoked atau revoked_all
+	Count     int64      `json:"count,omitempty"`  // jumlah token yang dicabut untuk revoked_all
+}
+
+// UserData builds the payload snapshot of user
+func UserData(user models.User) User {
+	return User{ID: user.ID, Username: user.Username, Email: user.Email, Type: user.Type, Status: user.Status}
+}
+
+// RoleData builds the payload snapshot of role
+func RoleData(role models.Role) Role {
+	return Role{ID: role.ID, Name: role.Name}
+}
+
+// Enqueue writes an event to the outbox. tx harus transaksi yang sama dengan perubahan
+// datanya supaya event hanya ada jika perubahan tersimpan
+func Enqueue(tx *gorm.DB, eventType string, data interface{}) error {
+	event := models.WebhookEvent{ID: uuid.New(), Type: eventType, CreatedAt: time.Now()}
+	payload, err := json.Marshal(Envelope{ID: event.ID, Type: eventType, CreatedAt: event.CreatedAt, Data: data})
+	if err != nil {
+		return err
+	}
+	event.Payload = payload
+	return tx.Create(&event).Error
+}
+
+// EnqueueRoleRemoval enqueues role.unassigned for every user that has role, langsung maupun
+// lewat group. Dipanggil sebelum assignment dihapus, misalnya saat role dihapus
+func EnqueueRoleRemoval(tx *gorm.DB, role models.Role, reason string) error {
+	var users []models.User
+	if err := tx.Scopes(database.RoleHolders(tx, role.ID, time.Now())).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		change := RoleChange{User: UserData(user), Role: RoleData(role), Reason: reason}
+		if err := Enqueue(tx, RoleUnassigned, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RoleSnapshot holds the effective roles (direct plus group, see database.ActiveRoles) of a
+// set of users. Diambil sebelum assignment atau keanggotaan group diubah, lalu dibandingkan
+// dengan kondisi sesudahnya oleh EnqueueRoleChanges
+type RoleSnapshot struct {
+	userIDs []uuid.UUID
+	roles   map[uuid.UUID]map[uuid.UUID]bool
+}
+
+// SnapshotRoles loads the roles the users have right now
+func SnapshotRoles(tx *gorm.DB, userIDs []uuid.UUID) (*RoleSnapshot, error) {
+	pairs, err := database.ActiveRoleIDs(tx, userIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &RoleSnapshot{userIDs: userIDs, roles: make(map[uuid.UUID]map[uuid.UUID]bool, len(userIDs))}
+	for _, pair := range pairs {
+		if snapshot.roles[pair.UserID] == nil {
+			snapshot.roles[pair.UserID] = make(map[uuid.UUID]bool)
+		}
+		snapshot.roles[pair.UserID][pair.RoleID] = true
+	}
+	return snapshot, nil
+}
+
+// Has reports whether the user had the role when the snapshot was taken
+func (s *RoleSnapshot) Has(userID, roleID uuid.UUID) bool {
+	return s.roles[userID][roleID]
+}
+
+// EnqueueRoleChanges compares before with the roles the same users have now and enqueues
+// role.assigned or role.unassigned for every role gained or lost. Role yang tetap dimiliki
+// lewat jalur lain (assignment langsung atau group lain) tidak menghasilkan event
+func EnqueueRoleChanges(tx *gorm.DB, before *RoleSnapshot, reason string) error {
+	after, err := SnapshotRoles(tx, before.userIDs)
+	if err != nil {
+		return err
+	}
+
+	type change struct {
+		eventType      string
+		userID, roleID uuid.UUID
+	}
+	var changes []change
+	userIDs := make(map[uuid.UUID]bool)
+	roleIDs := make(map[uuid.UUID]bool)
+	add := func(eventType string, userID, roleID uuid.UUID) {
+		changes = append(changes, change{eventType, userID, roleID})
+		userIDs[userID] = true
+		roleIDs[roleID] = true
+	}
+
+	for _, userID := range before.userIDs {
+		for roleID := range before.roles[userID] {
+			if !after.Has(userID, roleID) {
+				add(RoleUnassigned, userID, roleID)
+			}
+		}
+		for roleID := range after.roles[userID] {
+			if !before.Has(userID, roleID) {
+				add(RoleAssigned, userID, roleID)
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	if err := tx.Where("id IN ?", mapKeys(userIDs)).Find(&users).Error; err != nil {
+		return err
+	}
+	var roles []models.Role
+	if err := tx.Where("id IN ?", mapKeys(roleIDs)).Find(&roles).Error; err != nil {
+		return err
+	}
+	usersByID := make(map[uuid.UUID]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+	rolesByID := make(map[uuid.UUID]models.Role, len(roles))
+	for _, role := range roles {
+		rolesByID[role.ID] = role
+	}
+
+	for _, ch := range changes {
+		user, userFound := usersByID[ch.userID]
+		role, roleFound := rolesByID[ch.roleID]
+		// User yang sudah dihapus tidak lagi mendapat event role
+		if !userFound || !roleFound {
+			continue
+		}
+		if err := Enqueue(tx, ch.eventType, RoleChange{User: UserData(user), Role: RoleData(role), Reason: reason}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapKeys(set map[uuid.UUID]bool) []uuid.UUID {
+	keys := make([]uuid.UUID, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
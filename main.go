@@ -14,20 +14,40 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	_ "sjek/docs" // Import swagger docs
+	"sjek/internal/accesslog"
 	"sjek/internal/database"
+	"sjek/internal/env"
+	"sjek/internal/events"
+	"sjek/internal/hashchain"
+	"sjek/internal/jobs"
+	"sjek/internal/logger"
+	"sjek/internal/notification"
 	"sjek/internal/routes"
+	"sjek/internal/storage"
+	"sjek/internal/webhook"
+	"syscall"
+	"time"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"gorm.io/gorm"
 )
 
+// setupLogging configures slog (lihat package logger untuk LOG_LEVEL dan LOG_FORMAT)
+// to write to both console and a rotated file
 func setupLogging() {
 	// Create logs directory if not exists
 	logsDir := "logs"
 	if err := os.MkdirAll(logsDir, 0755); err != nil {
-		log.Printf("Failed to create logs directory: %v", err)
+		logger.Setup(os.Stdout)
+		slog.Error("Failed to create logs directory, logging to console only", "error", err)
 		return
 	}
 
@@ -43,12 +63,61 @@ func setupLogging() {
 
 	// Set log output to both file and console
 	multiWriter := io.MultiWriter(os.Stdout, logRotator)
-	log.SetOutput(multiWriter)
-	
-	// Set log format
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	
-	log.Println("Logging initialized with 30MB rotation limit")
+	logger.Setup(multiWriter)
+
+	slog.Info("Logging initialized with 30MB rotation limit", "file", logRotator.Filename)
+}
+
+// chainSigningSecret returns CHAIN_SIGNING_KEY. Wajib diisi dan tidak boleh sama dengan
+// secret lain, karena siapa pun yang tahu key bisa menandatangani checkpoint palsu
+func chainSigningSecret() []byte {
+	secret := os.Getenv("CHAIN_SIGNING_KEY")
+	if secret == "" {
+		logger.Fatal("CHAIN_SIGNING_KEY is required to sign hash chain checkpoints")
+	}
+	if secret == os.Getenv("JWT_SECRET") || secret == os.Getenv("DOCUMENT_URL_SECRET") {
+		logger.Fatal("CHAIN_SIGNING_KEY must differ from JWT_SECRET and DOCUMENT_URL_SECRET")
+	}
+	return []byte(secret)
+}
+
+// verifyChains implements "sjek verify-chain [chain]": walks the log hash chains,
+// prints the reports as JSON and exits 1 when a chain is broken
+func verifyChains(db *gorm.DB, signer hashchain.Signer, args []string) {
+	chains := hashchain.Chains
+	if len(args) > 0 {
+		chain, ok := hashchain.Find(args[0])
+		if !ok {
+			logger.Fatal("Unknown chain, use audit_logs or login_logs", "chain", args[0])
+		}
+		chains = []hashchain.Chain{chain}
+	}
+
+	valid := true
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	for _, chain := range chains {
+		report, err := chain.Verify(db, signer)
+		if err != nil {
+			logger.Fatal("Failed to verify chain", "chain", chain.Name, "error", err)
+		}
+		valid = valid && report.Valid
+		encoder.Encode(report)
+	}
+
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+// documentURLSecret returns DOCUMENT_URL_SECRET. Wajib diisi, tanpa secret signed URL
+// download dokumen bisa dipalsukan siapa saja
+func documentURLSecret() []byte {
+	secret := os.Getenv("DOCUMENT_URL_SECRET")
+	if secret == "" {
+		logger.Fatal("DOCUMENT_URL_SECRET is required to sign document download URLs")
+	}
+	return []byte(secret)
 }
 
 func main() {
@@ -58,15 +127,67 @@ func main() {
 	// Initialize database connection
 	db, err := database.InitDB()
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		logger.Fatal("Failed to initialize database", "error", err)
+	}
+
+	// Key untuk checkpoint hash chain, juga dipakai oleh CLI verify-chain
+	chainSigner := hashchain.NewSigner(chainSigningSecret())
+
+	if len(os.Args) > 1 && os.Args[1] == "verify-chain" {
+		verifyChains(db, chainSigner, os.Args[2:])
+		return
+	}
+
+	// Initialize document storage
+	store, err := storage.FromEnv()
+	if err != nil {
+		logger.Fatal("Failed to initialize storage", "error", err)
 	}
+	signer := storage.URLSigner{Secret: documentURLSecret(), TTL: env.Duration("DOCUMENT_URL_TTL", 15*time.Minute)}
+
+	// Start background jobs
+	notifier := notification.FromEnv()
+	jobs.StartRoleAssignmentCleanup(db, notifier, env.Duration("ROLE_CLEANUP_INTERVAL", 15*time.Minute))
+	jobs.StartUserPurge(db, store, env.Duration("USER_RETENTION_PERIOD", 30*24*time.Hour), env.Duration("USER_PURGE_INTERVAL", 24*time.Hour))
+	jobs.StartChainCheckpoints(db, chainSigner, env.Duration("CHAIN_CHECKPOINT_INTERVAL", time.Hour))
+	jobs.StartAccessLogMaintenance(db, env.Duration("ACCESS_LOG_RETENTION", 90*24*time.Hour), env.Duration("ACCESS_LOG_MAINTENANCE_INTERVAL", 6*time.Hour))
+	webhook.NewDispatcher(db, webhook.ConfigFromEnv()).Start()
+
+	// Access log ditulis async dalam batch, lihat package accesslog untuk konfigurasi
+	accessLogs := accesslog.NewWriter(db, accesslog.ConfigFromEnv())
+
+	// Security event dari semua replica diterima lewat LISTEN/NOTIFY lalu disebar ke stream SSE
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	eventBus := events.NewBus()
+	go events.Listen(listenCtx, db, eventBus)
 
 	// Setup router
-	router := routes.SetupRouter(db)
+	router := routes.SetupRouter(db, notifier, store, signer, chainSigner, accessLogs, eventBus)
 
 	// Start server
-	log.Println("Starting server on :8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":8080", Handler: router}
+	// Stream SSE yang terbuka diakhiri supaya Shutdown tidak menunggu sampai timeout
+	server.RegisterOnShutdown(eventBus.Close)
+	go func() {
+		slog.Info("Starting server", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", "error", err)
+		}
+	}()
+
+	// Graceful shutdown supaya request yang berjalan selesai dan access log sempat di-flush
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	slog.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), env.Duration("SHUTDOWN_TIMEOUT", 15*time.Second))
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Server shutdown failed", "error", err)
+	}
+	if err := accessLogs.Close(ctx); err != nil {
+		slog.Error("Failed to flush access logs", "error", err)
 	}
 }